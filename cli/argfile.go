@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"os"
+	"strings"
+)
+
+// expandArgFiles replaces each "@path" argument with the arguments listed in
+// the file at path, one per line. Blank lines and lines starting with "#" are
+// ignored. Arguments after "--" are not expanded, and neither are arguments
+// read from files.
+func expandArgFiles(args []string) ([]string, error) {
+	var expanded []string
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...), nil
+		}
+		path, ok := strings.CutPrefix(arg, "@")
+		if !ok || path == "" {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			expanded = append(expanded, line)
+		}
+	}
+	return expanded, nil
+}
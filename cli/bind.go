@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Bind defines a flag for each field of the struct pointed to by target that
+// has a flag tag naming the flag. The usage tag sets the flag's usage string,
+// and the env tag names an environment variable used if the command's Vars
+// has no entry for the flag. The field's current value is the flag's default.
+//
+//	type config struct {
+//		Addr    string        `flag:"addr" env:"APP_ADDR" usage:"listen address"`
+//		Timeout time.Duration `flag:"timeout" usage:"request timeout"`
+//		Tags    []string      `flag:"tag" usage:"repeatable tag"`
+//	}
+//
+// Fields may be strings, bools, ints, uints, floats, durations, string slices
+// (see [StringsVar]), string maps (see [MapVar]), or any type whose pointer
+// implements [flag.Value]. Like the flag package, Bind panics on programming
+// errors: if target is not a pointer to a struct, or if a tagged field has an
+// unsupported type.
+func Bind(flags *flag.FlagSet, target any) {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("cli: Bind target must be a pointer to a struct, got %T", target))
+	}
+	rv = rv.Elem()
+	typ := rv.Type()
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		name, ok := field.Tag.Lookup("flag")
+		if !ok || name == "-" {
+			continue
+		}
+		if !field.IsExported() {
+			panic(fmt.Sprintf("cli: Bind field %s is unexported", field.Name))
+		}
+		usage := field.Tag.Get("usage")
+		value := bindValue(flags, rv.Field(i), name, usage)
+		if value == nil {
+			panic(fmt.Sprintf("cli: Bind field %s has unsupported type %s", field.Name, field.Type))
+		}
+		if env := field.Tag.Get("env"); env != "" {
+			f := flags.Lookup(name)
+			f.Value = wrapEnvValue(f.Value, env)
+		}
+	}
+}
+
+// bindValue defines a flag for the field v and returns its value, or nil if
+// the field's type is unsupported.
+func bindValue(flags *flag.FlagSet, v reflect.Value, name, usage string) flag.Value {
+	switch p := v.Addr().Interface().(type) {
+	case flag.Value:
+		flags.Var(p, name, usage)
+	case *string:
+		flags.StringVar(p, name, *p, usage)
+	case *bool:
+		flags.BoolVar(p, name, *p, usage)
+	case *int:
+		flags.IntVar(p, name, *p, usage)
+	case *int64:
+		flags.Int64Var(p, name, *p, usage)
+	case *uint:
+		flags.UintVar(p, name, *p, usage)
+	case *uint64:
+		flags.Uint64Var(p, name, *p, usage)
+	case *float64:
+		flags.Float64Var(p, name, *p, usage)
+	case *time.Duration:
+		flags.DurationVar(p, name, *p, usage)
+	case *[]string:
+		StringsVar(flags, p, name, *p, usage)
+	case *map[string]string:
+		MapVar(flags, p, name, *p, usage)
+	default:
+		return nil
+	}
+	return flags.Lookup(name).Value
+}
+
+// An envValue is a flag value bound to an environment variable by [Bind].
+type envValue struct {
+	flag.Value
+	env string
+}
+
+func (v *envValue) EnvVar() string     { return v.env }
+func (v *envValue) Unwrap() flag.Value { return v.Value }
+
+// An envBoolValue is an envValue for a boolean flag.
+type envBoolValue struct {
+	envValue
+}
+
+func (v *envBoolValue) IsBoolFlag() bool { return true }
+
+func wrapEnvValue(v flag.Value, env string) flag.Value {
+	if bv, ok := v.(boolFlag); ok && bv.IsBoolFlag() {
+		return &envBoolValue{envValue{v, env}}
+	}
+	return &envValue{v, env}
+}
+
+// flagEnvVar returns the environment variable bound to f by [Bind], if any.
+func flagEnvVar(f *flag.Flag) (string, bool) {
+	ev, ok := f.Value.(interface{ EnvVar() string })
+	if !ok {
+		return "", false
+	}
+	return ev.EnvVar(), true
+}
+
+// unquoteUsage is [flag.UnquoteUsage] for flags whose values may be wrapped
+// by [Bind].
+func unquoteUsage(f *flag.Flag) (name, usage string) {
+	if uv, ok := f.Value.(interface{ Unwrap() flag.Value }); ok {
+		unwrapped := *f
+		unwrapped.Value = uv.Unwrap()
+		return flag.UnquoteUsage(&unwrapped)
+	}
+	return flag.UnquoteUsage(f)
+}
@@ -0,0 +1,84 @@
+package cli_test
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/x/cli"
+	"github.com/jonathonwebb/x/cli/clitest"
+)
+
+type bindTarget struct {
+	Addr    string            `flag:"addr" env:"APP_ADDR" usage:"listen address"`
+	Debug   bool              `flag:"debug" env:"APP_DEBUG"`
+	Workers int               `flag:"workers" usage:"number of workers"`
+	Timeout time.Duration     `flag:"timeout"`
+	Tags    []string          `flag:"tag"`
+	Labels  map[string]string `flag:"label"`
+	Ignored string
+}
+
+func bindCommand() *cli.Command[*bindTarget, any] {
+	return &cli.Command[*bindTarget, any]{
+		Name:  "serve",
+		Flags: func(flags *flag.FlagSet, target *bindTarget) { cli.Bind(flags, target) },
+		Action: func(ctx context.Context, env *cli.Env[any], target *bindTarget) cli.ExitStatus {
+			return cli.ExitSuccess
+		},
+	}
+}
+
+func TestBind(t *testing.T) {
+	env := cli.Env[any]{
+		Args: []string{"serve", "-workers", "4", "-tag", "a,b", "-label", "k=v", "-timeout", "5s"},
+		Vars: map[string]string{"APP_ADDR": ":9090", "APP_DEBUG": "true"},
+	}
+	res := clitest.Execute(t, bindCommand(), env, &bindTarget{Addr: ":8080", Ignored: "kept"})
+	clitest.RequireStatus(t, res, cli.ExitSuccess)
+
+	want := &bindTarget{
+		Addr:    ":9090",
+		Debug:   true,
+		Workers: 4,
+		Timeout: 5 * time.Second,
+		Tags:    []string{"a", "b"},
+		Labels:  map[string]string{"k": "v"},
+		Ignored: "kept",
+	}
+	if diff := cmp.Diff(want, res.Target); diff != "" {
+		t.Errorf("target mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBind_Help(t *testing.T) {
+	res := clitest.Run(t, bindCommand(), &bindTarget{Addr: ":8080"}, "serve", "-h")
+	clitest.RequireStatus(t, res, cli.ExitSuccess)
+	clitest.RequireStdoutContains(t, res, "  -addr string\n    \tlisten address (default \":8080\") [$APP_ADDR]\n")
+	clitest.RequireStdoutContains(t, res, "  -debug\n    \t[$APP_DEBUG]\n")
+	clitest.RequireStdoutContains(t, res, "  -workers int\n    \tnumber of workers\n")
+}
+
+func TestBind_Panics(t *testing.T) {
+	tests := []struct {
+		name   string
+		target any
+	}{
+		{name: "not_a_pointer", target: bindTarget{}},
+		{name: "unsupported_type", target: &struct {
+			C chan int `flag:"c"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Bind did not panic")
+				}
+			}()
+			cli.Bind(flag.NewFlagSet("test", flag.ContinueOnError), tt.target)
+		})
+	}
+}
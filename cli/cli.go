@@ -8,19 +8,56 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"runtime/debug"
 	"strings"
+	"syscall"
 	"text/template"
+	"time"
 )
 
 // An Env represents the execution environment for a [Command].
 //
 // M is the type of custom metadata that will be available to Command actions.
 type Env[M any] struct {
-	Err  io.Writer         // error output stream
-	Out  io.Writer         // standard output stream
-	Args []string          // command-line arguments
-	Vars map[string]string // environment variables
-	Meta M                 // custom metadata
+	In     io.Reader         // standard input stream
+	Err    io.Writer         // error output stream
+	Out    io.Writer         // standard output stream
+	Args   []string          // command-line arguments
+	Vars   map[string]string // environment variables
+	Meta   M                 // custom metadata
+	Output OutputFormat      // format used by [Env.Emit], plain if empty
+	Path   string            // names of the executing command and its parents, separated by spaces
+	DryRun bool              // report actions instead of performing them, set by the -dry-run flag
+	Debug  bool              // print stack traces of panics recovered by commands with RecoverPanics
+
+	// Translate, if set, translates the printf formats of messages written by
+	// the framework, such as "unknown command", so they can be localized. A
+	// translated format must have the same verbs. Errors from the flag package
+	// and from flag values are not translated.
+	Translate func(format string) string
+}
+
+// tr returns the translation of the message format.
+func (e Env[M]) tr(format string) string {
+	if e.Translate == nil {
+		return format
+	}
+	return e.Translate(format)
+}
+
+type envKey struct{}
+
+// WithEnv returns a copy of ctx that carries env. [Command.Execute] passes such
+// a context to hooks and actions.
+func WithEnv[M any](ctx context.Context, env *Env[M]) context.Context {
+	return context.WithValue(ctx, envKey{}, env)
+}
+
+// EnvFromContext returns the Env carried by ctx, if any.
+func EnvFromContext[M any](ctx context.Context) (*Env[M], bool) {
+	env, ok := ctx.Value(envKey{}).(*Env[M])
+	return env, ok
 }
 
 // Printf formats and writes a message to the standard output stream.
@@ -67,8 +104,8 @@ func (e Env[M]) getVar(name string) string {
 
 // DefaultEnv returns an [Env] using the current process's environment.
 //
-// The returned Env will use the [os.Stderr] and [os.Stdout] streams, [os.Args],
-// and environment variables from [os.Environ].
+// The returned Env will use the [os.Stdin], [os.Stderr] and [os.Stdout]
+// streams, [os.Args], and environment variables from [os.Environ].
 func DefaultEnv[M any](meta M) Env[M] {
 	vars := make(map[string]string)
 	for _, v := range os.Environ() {
@@ -76,6 +113,7 @@ func DefaultEnv[M any](meta M) Env[M] {
 		vars[key] = value
 	}
 	return Env[M]{
+		In:   os.Stdin,
 		Err:  os.Stderr,
 		Out:  os.Stdout,
 		Args: os.Args,
@@ -84,6 +122,17 @@ func DefaultEnv[M any](meta M) Env[M] {
 	}
 }
 
+// Run executes root with the [DefaultEnv] for meta, then exits the process
+// with the resulting status. The context passed to commands is canceled when
+// the process receives SIGINT or SIGTERM.
+func Run[T any, M any](root *Command[T, M], meta M, target T) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	env := DefaultEnv(meta)
+	status := root.Execute(ctx, &env, target)
+	stop()
+	os.Exit(int(status))
+}
+
 // An ExitStatus is the result of command execution.
 type ExitStatus int
 
@@ -91,31 +140,73 @@ const (
 	ExitSuccess ExitStatus = 0 // execution succeeded
 	ExitFailure ExitStatus = 1 // execution failed due to an error
 	ExitUsage   ExitStatus = 2 // execution failed due to invalid user input
+	ExitPanic   ExitStatus = 3 // execution failed due to a recovered panic
 )
 
-var (
-	errMissingCommand = errors.New("missing command")
-	errUnknownCommand = errors.New("unknown command")
-)
+// An ExitCoder is an error that determines the exit status of a command whose
+// RunE action returns it.
+type ExitCoder interface {
+	error
+	ExitStatus() ExitStatus
+}
+
+// An ExitError is an error with an explicit exit status.
+type ExitError struct {
+	Status ExitStatus
+	Err    error
+}
+
+func (e *ExitError) Error() string          { return e.Err.Error() }
+func (e *ExitError) Unwrap() error          { return e.Err }
+func (e *ExitError) ExitStatus() ExitStatus { return e.Status }
 
 // A Command represents a CLI command.
 //
 // T is the type of the target value for configuration storage.
 // M is the type of the metadata provided by the execution [Env].
 type Command[T any, M any] struct {
-	Name        string                                                      // name used to invoke the command.
-	Usage       string                                                      // short usage text
-	Help        string                                                      // long help text
-	Flags       func(flags *flag.FlagSet, target T)                         // function for defining flags
-	Vars        map[string]string                                           // map of flag names -> environment variables
-	Action      func(ctx context.Context, env *Env[M], target T) ExitStatus // command action
-	Subcommands []*Command[T, M]                                            // command subcommands
+	Name            string                                                      // name used to invoke the command.
+	Usage           string                                                      // short usage text, generated if empty
+	Help            string                                                      // long help text, generated from flags and subcommands if empty
+	Flags           func(flags *flag.FlagSet, target T)                         // function for defining flags
+	Vars            map[string]string                                           // map of flag names -> environment variables
+	VarPrefix       string                                                      // prefix of environment variables derived from names of flags not in Vars, inherited by subcommands
+	Aliases         map[string]string                                           // map of flag aliases, such as short names -> flag names
+	Version         string                                                      // version text, enables the -version flag and version subcommand if set
+	Category        string                                                      // heading the command is listed under in its parent's generated help
+	Hidden          bool                                                        // omit the command from generated help
+	Deprecated      string                                                      // deprecation message, warned about on use if set
+	DeprecatedFlags map[string]string                                           // map of deprecated flag names -> deprecation messages
+	Action          func(ctx context.Context, env *Env[M], target T) ExitStatus // command action
+	RunE            func(ctx context.Context, env *Env[M], target T) error      // command action returning an error, used if Action is nil
+	Validate        func(env *Env[M], target T) error                           // hook validating flags after they are resolved, errors are usage errors
+	Before          func(ctx context.Context, env *Env[M], target T) error      // hook run before the action or subcommand
+	After           func(ctx context.Context, env *Env[M], target T) error      // hook run after the action or subcommand, even if it failed
+	Interspersed    bool                                                        // parse flags after positional arguments, up to a subcommand name or "--"
+	ArgFiles        bool                                                        // expand "@path" arguments, including those of subcommands, into the lines of the file at path
+	ConfigFlag      string                                                      // name of the flag holding a config file path, enables config file values if set
+	DecodeConfig    func(r io.Reader) (map[string]string, error)                // config file decoder, [DecodeJSONConfig] if nil
+	RecoverPanics   bool                                                        // recover panics in hooks, actions and subcommands, exiting with [ExitPanic]
+	TimeoutFlag     bool                                                        // define a -timeout flag limiting the context passed to hooks and actions, inherited by subcommands
+	DryRunFlag      bool                                                        // define a -dry-run flag setting [Env.DryRun], inherited by subcommands
+	OutputFlag      bool                                                        // define an -output flag selecting the [Env.Emit] format, inherited by subcommands
+	Funcs           template.FuncMap                                            // functions for Usage, Help and Vars templates, inherited by subcommands
+	Subcommands     []*Command[T, M]                                            // command subcommands
+}
 
-	vars map[string]string
-	fs   *flag.FlagSet
+// An execution holds the state of a single call to [Command.Execute], so that
+// executing a command does not modify it.
+type execution[T any, M any] struct {
+	*Command[T, M]
+	parent      *execution[T, M]
+	vars        map[string]string
+	fs          *flag.FlagSet
+	showVersion bool
+	config      map[string]string
+	timeout     time.Duration
 }
 
-func (c *Command[T, M]) flagSet() *flag.FlagSet {
+func (c *execution[T, M]) flagSet() *flag.FlagSet {
 	if c.fs == nil {
 		c.fs = flag.NewFlagSet(c.Name, flag.ContinueOnError)
 		c.fs.Usage = func() { /* no-op */ }
@@ -124,7 +215,7 @@ func (c *Command[T, M]) flagSet() *flag.FlagSet {
 	return c.fs
 }
 
-func (c *Command[T, M]) getFlagVar(flagName string) (string, bool) {
+func (c *execution[T, M]) getFlagVar(flagName string) (string, bool) {
 	vars := c.vars
 	if vars == nil {
 		return "", false
@@ -150,44 +241,168 @@ type boolFlag interface {
 	IsBoolFlag() bool
 }
 
-// Execute parses command-line arguments from the environment, then either calls
-// the command's action or defers to the specified subcommand's Execute method.
-func (c *Command[T, M]) Execute(ctx context.Context, env *Env[M], target T) ExitStatus {
-	usage, err := env.ExecMetaTmpl(c.Usage)
-	if err != nil {
-		env.Errorf("error executing usage template: %v\n", err)
-		return ExitFailure
+// setup resolves the command's environment variables and defines its flags,
+// then returns its usage and help text.
+func (c *execution[T, M]) setup(env *Env[M], target T) (usage, help string, err error) {
+	if c.Flags != nil {
+		c.Flags(c.flagSet(), target)
 	}
-
-	help, err := env.ExecMetaTmpl(c.Help)
-	if err != nil {
-		env.Errorf("error executing help template: %v\n", err)
-		return ExitFailure
+	if c.Version != "" && c.flagSet().Lookup("version") == nil {
+		c.flagSet().BoolVar(&c.showVersion, "version", false, env.tr("print version and exit"))
+	}
+	if c.inherits(func(cmd *Command[T, M]) bool { return cmd.OutputFlag }) {
+		defineOutputFlag(c.flagSet(), env)
+	}
+	if c.inherits(func(cmd *Command[T, M]) bool { return cmd.TimeoutFlag }) && c.flagSet().Lookup("timeout") == nil {
+		c.flagSet().DurationVar(&c.timeout, "timeout", 0, env.tr("time limit for the command, none if 0"))
+	}
+	if c.inherits(func(cmd *Command[T, M]) bool { return cmd.DryRunFlag }) && c.flagSet().Lookup("dry-run") == nil {
+		c.flagSet().BoolVar(&env.DryRun, "dry-run", env.DryRun, env.tr("show what would be done without doing it"))
+	}
+	for alias, name := range c.Aliases {
+		f := c.flagSet().Lookup(name)
+		if f == nil {
+			return "", "", fmt.Errorf("alias -%s for undefined flag -%s", alias, name)
+		}
+		if c.flagSet().Lookup(alias) == nil {
+			c.flagSet().Var(f.Value, alias, f.Usage)
+		}
 	}
 
 	c.vars = make(map[string]string)
 	if c.Vars != nil {
 		for k, v := range c.Vars {
-			newV, err := env.ExecMetaTmpl(v)
+			newV, err := c.execTmpl(env, v)
 			if err != nil {
-				env.Errorf("error executing template for var %s: %v\n", k, err)
-				return ExitFailure
+				return "", "", fmt.Errorf("error executing template for var %s: %v", k, err)
 			}
 			c.vars[k] = newV
 		}
 	}
+	c.flagSet().VisitAll(func(f *flag.Flag) {
+		if _, ok := c.vars[f.Name]; ok {
+			return
+		}
+		if _, ok := c.Aliases[f.Name]; ok {
+			return
+		}
+		if env, ok := flagEnvVar(f); ok {
+			c.vars[f.Name] = env
+		}
+	})
+	if prefix := c.varPrefix(); prefix != "" {
+		c.flagSet().VisitAll(func(f *flag.Flag) {
+			if _, ok := c.vars[f.Name]; ok {
+				return
+			}
+			if _, ok := c.Aliases[f.Name]; ok || f.Name == "version" {
+				return
+			}
+			c.vars[f.Name] = prefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(f.Name))
+		})
+	}
 
-	if c.Flags != nil {
-		c.Flags(c.flagSet(), target)
+	usage = c.defaultUsage(env)
+	if c.Usage != "" {
+		if usage, err = c.execTmpl(env, c.Usage); err != nil {
+			return "", "", fmt.Errorf("error executing usage template: %v", err)
+		}
+	}
+
+	help = c.defaultHelp(env)
+	if c.Help != "" {
+		if help, err = c.execTmpl(env, c.Help); err != nil {
+			return "", "", fmt.Errorf("error executing help template: %v", err)
+		}
+	}
+	return usage, help, nil
+}
+
+// HelpRequested reports whether args, the arguments following a command name,
+// contain a -h or -help flag before any "--" terminator.
+//
+// Execute uses it to show help even if other arguments or environment
+// variables are invalid. Because args are scanned without parsing them, a -h
+// after a positional argument requests help even for commands that are not
+// Interspersed, so positional arguments that are themselves -h or -help must
+// follow "--".
+func HelpRequested(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "--":
+			return false
+		case "-h", "-help", "--h", "--help":
+			return true
+		}
+	}
+	return false
+}
+
+// Execute parses command-line arguments from the environment, then either calls
+// the command's action or defers to the specified subcommand's Execute method.
+//
+// Flags set on the command line take precedence over environment variables,
+// which take precedence over values from the config file named by ConfigFlag
+// of the command or its nearest parent that has one.
+//
+// Once a command's flags are resolved, its Validate hook can reject them with a
+// usage error before any hooks, actions or subcommands run. Help and version
+// requests, whether by flag or by the built-in help and version commands, are
+// answered before Validate and the hooks run.
+//
+// The Before hooks of the command and its parents run in order before the
+// action, and their After hooks run in reverse order once it returns. An error
+// from a Before hook stops execution.
+//
+// Hooks and actions receive a context carrying env, see [EnvFromContext], and
+// env.Path is set to the path of the executing command.
+//
+// Execute does not modify the command, so it can be executed repeatedly, and
+// concurrently if its Flags function, hooks and actions are safe for concurrent
+// use.
+func (c *Command[T, M]) Execute(ctx context.Context, env *Env[M], target T) ExitStatus {
+	return (&execution[T, M]{Command: c}).execute(WithEnv(ctx, env), env, target)
+}
+
+func (c *execution[T, M]) execute(ctx context.Context, env *Env[M], target T) (status ExitStatus) {
+	if c.RecoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				env.Errorf(env.tr("panic: %v")+"\n", r)
+				if env.Debug {
+					env.Errorf("%s", debug.Stack())
+				}
+				status = ExitPanic
+			}
+		}()
+	}
+
+	usage, help, err := c.setup(env, target)
+	if err != nil {
+		env.Errorf("%v\n", err)
+		return ExitFailure
 	}
 
 	if len(env.Args) < 1 {
-		env.Errorf("no arguments provided\n")
+		env.Errorf("%s\n", env.tr("no arguments provided"))
 		return ExitFailure
 	}
 
-	if err := c.flagSet().Parse(env.Args[1:]); err != nil {
+	args := env.Args[1:]
+	if c.ArgFiles {
+		if args, err = expandArgFiles(args); err != nil {
+			env.Errorf("%s\n"+env.tr("error reading argument file: %v")+"\n", usage, err)
+			return ExitUsage
+		}
+	}
+
+	helpRequested := HelpRequested(args)
+	args, err = c.parse(args)
+	if err != nil {
 		if errors.Is(err, flag.ErrHelp) {
+			return c.printHelp(env, target, usage, help, c.subcommandPath(c.flagSet().Args()))
+		}
+		if helpRequested {
 			env.Printf("%s\n\n%s\n", usage, help)
 			return ExitSuccess
 		}
@@ -195,10 +410,7 @@ func (c *Command[T, M]) Execute(ctx context.Context, env *Env[M], target T) Exit
 		return ExitUsage
 	}
 
-	setByUser := make(map[string]bool)
-	c.flagSet().Visit(func(f *flag.Flag) {
-		setByUser[f.Name] = true
-	})
+	setByUser := c.setFlags()
 
 	var flagErr error
 	c.flagSet().VisitAll(func(f *flag.Flag) {
@@ -222,23 +434,88 @@ func (c *Command[T, M]) Execute(ctx context.Context, env *Env[M], target T) Exit
 		value := env.getVar(varName)
 		if err := c.flagSet().Set(f.Name, value); err != nil {
 			if fv, ok := f.Value.(boolFlag); ok && fv.IsBoolFlag() {
-				flagErr = fmt.Errorf("invalid boolean value %q for var $%s: %v", value, varName, err)
+				flagErr = fmt.Errorf(env.tr("invalid boolean value %q for var $%s: %v"), value, varName, err)
 			} else {
-				flagErr = fmt.Errorf("invalid value %q for var $%s: %v", value, varName, err)
+				flagErr = fmt.Errorf(env.tr("invalid value %q for var $%s: %v"), value, varName, err)
 			}
 		}
 	})
-	if flagErr != nil {
+	if flagErr != nil && !helpRequested {
 		env.Errorf("%s\n%v\n", usage, flagErr)
 		return ExitUsage
 	}
 
-	env.Args = c.flagSet().Args()
+	if err := c.applyConfig(env); err != nil && !helpRequested {
+		env.Errorf("%s\n%v\n", usage, err)
+		return ExitUsage
+	}
+
+	if c.Deprecated != "" {
+		env.Errorf(env.tr("warning: command %s is deprecated: %s")+"\n", c.Name, c.Deprecated)
+	}
+	c.flagSet().Visit(func(f *flag.Flag) {
+		if msg, ok := c.DeprecatedFlags[f.Name]; ok {
+			env.Errorf(env.tr("warning: flag -%s is deprecated: %s")+"\n", f.Name, msg)
+		}
+	})
+
+	env.Args = args
+	env.Path = c.path()
+
+	// Help was requested for the command or one of its subcommands, and the
+	// errors above were suppressed, so show it without validating the flags or
+	// running any hooks.
+	if helpRequested {
+		if len(args) > 0 {
+			if subCmd := c.findSubcommand(args[0]); subCmd != nil {
+				return (&execution[T, M]{Command: subCmd, parent: c}).execute(ctx, env, target)
+			}
+		}
+		return c.printHelp(env, target, usage, help, nil)
+	}
+
+	if c.showVersion {
+		return c.printVersion(env)
+	}
+	if len(args) > 0 && c.findSubcommand(args[0]) == nil {
+		if args[0] == "version" && c.Version != "" {
+			return c.printVersion(env)
+		}
+		if args[0] == "help" && c.hasHelpCommand() {
+			return c.printHelp(env, target, usage, help, args[1:])
+		}
+	}
+
+	if c.Validate != nil {
+		if err := c.Validate(env, target); err != nil {
+			env.Errorf("%s\n%v\n", usage, err)
+			return ExitUsage
+		}
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	if c.Before != nil {
+		if err := c.Before(ctx, env, target); err != nil {
+			return exitStatus(env, usage, err)
+		}
+	}
+	if c.After != nil {
+		defer func() {
+			if s := exitStatus(env, usage, c.After(ctx, env, target)); status == ExitSuccess {
+				status = s
+			}
+		}()
+	}
 
 	if len(env.Args) > 0 {
 		subCmd := c.findSubcommand(env.Args[0])
 		if subCmd != nil {
-			return subCmd.Execute(ctx, env, target)
+			return (&execution[T, M]{Command: subCmd, parent: c}).execute(ctx, env, target)
 		}
 	}
 
@@ -246,11 +523,251 @@ func (c *Command[T, M]) Execute(ctx context.Context, env *Env[M], target T) Exit
 		return c.Action(ctx, env, target)
 	}
 
+	if c.RunE != nil {
+		return exitStatus(env, usage, c.RunE(ctx, env, target))
+	}
+
 	if len(env.Args) == 0 {
-		env.Errorf("%s\n%v\n", usage, errMissingCommand)
+		env.Errorf("%s\n%s\n", usage, env.tr("missing command"))
 		return ExitUsage
 	}
 
-	env.Errorf("%s\n%v\n", usage, errUnknownCommand)
+	env.Errorf("%s\n%s\n", usage, env.tr("unknown command"))
 	return ExitUsage
 }
+
+// exitStatus reports err, if any, to env and maps it to an exit status. Errors
+// are failures unless they implement [ExitCoder]; usage errors are printed
+// after the usage text.
+func exitStatus[M any](env *Env[M], usage string, err error) ExitStatus {
+	if err == nil {
+		return ExitSuccess
+	}
+
+	status := ExitFailure
+	var ec ExitCoder
+	if errors.As(err, &ec) {
+		status = ec.ExitStatus()
+	}
+	switch status {
+	case ExitSuccess:
+	case ExitUsage:
+		env.Errorf("%s\n%v\n", usage, err)
+	default:
+		env.Errorf("%v\n", err)
+	}
+	return status
+}
+
+// varPrefix returns the VarPrefix of the command or its nearest parent that
+// has one.
+func (c *execution[T, M]) varPrefix() string {
+	for x := c; x != nil; x = x.parent {
+		if x.VarPrefix != "" {
+			return x.VarPrefix
+		}
+	}
+	return ""
+}
+
+// inherits reports whether f is true for the command or one of its parents.
+func (c *execution[T, M]) inherits(f func(cmd *Command[T, M]) bool) bool {
+	for x := c; x != nil; x = x.parent {
+		if f(x.Command) {
+			return true
+		}
+	}
+	return false
+}
+
+// setFlags returns the names of the flags that have been set, including flags
+// set through an alias.
+func (c *execution[T, M]) setFlags() map[string]bool {
+	set := make(map[string]bool)
+	c.flagSet().Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+		if name, ok := c.Aliases[f.Name]; ok {
+			set[name] = true
+		}
+	})
+	return set
+}
+
+// parse parses flags from args and returns the remaining positional arguments.
+// If the command is interspersed, parsing continues past positional arguments
+// until a subcommand name or "--".
+func (c *execution[T, M]) parse(args []string) ([]string, error) {
+	fs := c.flagSet()
+	if !c.Interspersed {
+		err := fs.Parse(args)
+		return fs.Args(), err
+	}
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if consumed := args[:len(args)-len(rest)]; len(consumed) > 0 && consumed[len(consumed)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 || c.isCommandName(rest[0]) {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// isCommandName reports whether name invokes a subcommand.
+func (c *Command[T, M]) isCommandName(name string) bool {
+	return c.findSubcommand(name) != nil ||
+		(name == "version" && c.hasVersionCommand()) ||
+		(name == "help" && c.hasHelpCommand())
+}
+
+// printVersion writes the command's version text to the standard output
+// stream.
+func (c *execution[T, M]) printVersion(env *Env[M]) ExitStatus {
+	version, err := c.execTmpl(env, c.Version)
+	if err != nil {
+		env.Errorf("error executing version template: %v\n", err)
+		return ExitFailure
+	}
+	env.Printf("%s\n", version)
+	return ExitSuccess
+}
+
+// printHelp writes the help text of the subcommand at path, relative to the
+// command, to the standard output stream.
+func (c *execution[T, M]) printHelp(env *Env[M], target T, usage, help string, path []string) ExitStatus {
+	cmd := c
+	for _, name := range path {
+		sub := cmd.findSubcommand(name)
+		if sub == nil {
+			env.Errorf("%s\n%s: %s\n", usage, env.tr("unknown command"), strings.Join(path, " "))
+			return ExitUsage
+		}
+		cmd = &execution[T, M]{Command: sub, parent: cmd}
+	}
+	if cmd != c {
+		var err error
+		if usage, help, err = cmd.setup(env, target); err != nil {
+			env.Errorf("%v\n", err)
+			return ExitFailure
+		}
+	}
+	env.Printf("%s\n\n%s\n", usage, help)
+	return ExitSuccess
+}
+
+// subcommandPath returns the longest prefix of args that names a chain of
+// nested subcommands.
+func (c *Command[T, M]) subcommandPath(args []string) []string {
+	cmd := c
+	for i, name := range args {
+		if cmd = cmd.findSubcommand(name); cmd == nil {
+			return args[:i]
+		}
+	}
+	return args
+}
+
+// hasHelpCommand reports whether the command handles the built-in help
+// subcommand.
+func (c *Command[T, M]) hasHelpCommand() bool {
+	return len(c.Subcommands) > 0 && c.findSubcommand("help") == nil
+}
+
+// path returns the names of the command's parents and the command, separated
+// by spaces.
+func (c *execution[T, M]) path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.path() + " " + c.Name
+}
+
+// hasVersionCommand reports whether the command handles the built-in version
+// subcommand.
+func (c *Command[T, M]) hasVersionCommand() bool {
+	return c.Version != "" && c.findSubcommand("version") == nil
+}
+
+// defaultUsage returns the usage text shown when Usage is empty.
+func (c *execution[T, M]) defaultUsage(env *Env[M]) string {
+	var b strings.Builder
+	b.WriteString(env.tr("usage:"))
+	b.WriteString(" " + c.path())
+	hasFlags := false
+	c.flagSet().VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		b.WriteString(" " + env.tr("[flags]"))
+	}
+	if len(c.Subcommands) > 0 || c.hasVersionCommand() {
+		if c.Action != nil || c.RunE != nil {
+			b.WriteString(" " + env.tr("[command]"))
+		} else {
+			b.WriteString(" " + env.tr("<command>"))
+		}
+	}
+	return b.String()
+}
+
+// defaultHelp returns the help text shown when Help is empty. It lists the
+// command's flags, with their defaults and environment variables, and its
+// subcommands.
+func (c *execution[T, M]) defaultHelp(env *Env[M]) string {
+	data := c.templateData()
+	var b strings.Builder
+	for i, f := range data.Flags {
+		if i == 0 {
+			b.WriteString(env.tr("Flags:"))
+		}
+		b.WriteString("\n  ")
+		for _, alias := range f.Aliases {
+			fmt.Fprintf(&b, "-%s, ", alias)
+		}
+		fmt.Fprintf(&b, "-%s", f.Name)
+		if f.Type != "" {
+			fmt.Fprintf(&b, " %s", f.Type)
+		}
+		var notes []string
+		if f.Usage != "" {
+			notes = append(notes, f.Usage)
+		}
+		if f.Default != "" {
+			notes = append(notes, fmt.Sprintf(env.tr("(default %q)"), f.Default))
+		}
+		if f.Var != "" {
+			notes = append(notes, "[$"+f.Var+"]")
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(&b, "\n    \t%s", strings.Join(notes, " "))
+		}
+	}
+
+	for _, category := range data.Categories {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		if category.Name == "" {
+			b.WriteString(env.tr("Commands:"))
+		} else {
+			b.WriteString(category.Name + ":")
+		}
+		for _, name := range category.Commands {
+			fmt.Fprintf(&b, "\n  %s", name)
+		}
+	}
+	return b.String()
+}
+
+func isZeroFlagValue(f *flag.Flag) bool {
+	switch f.DefValue {
+	case "", "0", "false", "[]", "0s":
+		return true
+	}
+	return false
+}
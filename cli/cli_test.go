@@ -3,11 +3,16 @@ package cli_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"text/template"
 
 	"github.com/jonathonwebb/x/cli"
 )
@@ -260,6 +265,191 @@ func TestCommand_Execute(t *testing.T) {
 		}
 	})
 
+	t.Run("generated_help", func(t *testing.T) {
+		cmd := testCommand(t)
+		cmd.Usage = ""
+		cmd.Help = ""
+		opts := testCommandOptions{args: []string{"foo", "-h"}}
+		res := executeTestCommand(t, cmd, opts)
+
+		want := "usage: foo [flags] [command]\n\n" +
+			"Flags:\n" +
+			"  -env string\n" +
+			"    \t(default \"prod\") [$FOO_ENV]\n" +
+			"  -verbose\n" +
+			"    \t[$FOO_VERBOSE]\n\n" +
+			"Commands:\n" +
+			"  bar\n" +
+			"  help\n"
+		if got, want := res.status, cli.ExitSuccess; got != want {
+			t.Errorf("with generated help: cmd.Execute()=%v, want %v", got, want)
+		}
+		if got := res.outbuf; got != want {
+			t.Errorf("with generated help: cmd.Execute() wrote output=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("generated_usage_on_error", func(t *testing.T) {
+		cmd := testCommand(t)
+		cmd.Usage = ""
+		cmd.Action = nil
+		opts := testCommandOptions{args: []string{"foo"}}
+		res := executeTestCommand(t, cmd, opts)
+
+		if got, want := res.errbuf, "usage: foo [flags] <command>\n"; !strings.Contains(got, want) {
+			t.Errorf("with generated usage: cmd.Execute() wrote error=%q, want contains %q", got, want)
+		}
+	})
+
+	t.Run("version", func(t *testing.T) {
+		for _, args := range [][]string{{"foo", "-version"}, {"foo", "version"}} {
+			cmd := testCommand(t)
+			cmd.Version = "foo 1.2.3"
+			res := executeTestCommand(t, cmd, testCommandOptions{args: args})
+
+			if got, want := res.status, cli.ExitSuccess; got != want {
+				t.Errorf("with args %v: cmd.Execute()=%v, want %v", args, got, want)
+			}
+			if got, want := res.outbuf, "foo 1.2.3\n"; got != want {
+				t.Errorf("with args %v: cmd.Execute() wrote output=%q, want %q", args, got, want)
+			}
+		}
+	})
+
+	t.Run("version_unset", func(t *testing.T) {
+		cmd := testCommand(t)
+		cmd.Action = nil
+		opts := testCommandOptions{args: []string{"foo", "version"}}
+		res := executeTestCommand(t, cmd, opts)
+
+		if got, want := res.status, cli.ExitUsage; got != want {
+			t.Errorf("without version: cmd.Execute()=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("hidden_and_deprecated", func(t *testing.T) {
+		cmd := testCommand(t)
+		cmd.Usage = ""
+		cmd.Help = ""
+		cmd.DeprecatedFlags = map[string]string{"verbose": "use -env=dev"}
+		cmd.Subcommands = append(cmd.Subcommands,
+			&cli.Command[*testTarget, testMeta]{Name: "secret", Hidden: true},
+			&cli.Command[*testTarget, testMeta]{Name: "old", Deprecated: "use bar"},
+		)
+		res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "-h"}})
+
+		want := "usage: foo [flags] [command]\n\n" +
+			"Flags:\n" +
+			"  -env string\n" +
+			"    \t(default \"prod\") [$FOO_ENV]\n\n" +
+			"Commands:\n" +
+			"  bar\n" +
+			"  help\n"
+		if got := res.outbuf; got != want {
+			t.Errorf("with hidden commands: cmd.Execute() wrote output=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("deprecated_warnings", func(t *testing.T) {
+		cmd := testCommand(t)
+		cmd.DeprecatedFlags = map[string]string{"verbose": "use -env=dev"}
+		cmd.Subcommands[0].Deprecated = "use foo"
+		opts := testCommandOptions{args: []string{"foo", "-verbose", "bar"}}
+		res := executeTestCommand(t, cmd, opts)
+
+		if got, want := res.status, cli.ExitSuccess; got != want {
+			t.Errorf("with deprecated usage: cmd.Execute()=%v, want %v", got, want)
+		}
+		want := "warning: flag -verbose is deprecated: use -env=dev\n" +
+			"warning: command bar is deprecated: use foo\n"
+		if got := res.errbuf; got != want {
+			t.Errorf("with deprecated usage: cmd.Execute() wrote error=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("help_command", func(t *testing.T) {
+		cmd := testCommand(t)
+		bar := cmd.Subcommands[0]
+		bar.Usage = ""
+		bar.Help = ""
+		bar.Subcommands = []*cli.Command[*testTarget, testMeta]{{Name: "baz"}}
+		opts := testCommandOptions{args: []string{"foo", "help", "bar"}}
+		res := executeTestCommand(t, cmd, opts)
+
+		want := "usage: foo bar [flags] [command]\n\n" +
+			"Flags:\n" +
+			"  -port uint\n" +
+			"    \t[$BAR_PORT]\n\n" +
+			"Commands:\n" +
+			"  baz\n" +
+			"  help\n"
+		if got, want := res.status, cli.ExitSuccess; got != want {
+			t.Errorf("with help command: cmd.Execute()=%v, want %v", got, want)
+		}
+		if got := res.outbuf; got != want {
+			t.Errorf("with help command: cmd.Execute() wrote output=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("help_command_skips_hooks", func(t *testing.T) {
+		for _, args := range [][]string{{"foo", "help", "bar"}, {"foo", "version"}} {
+			cmd := testCommand(t)
+			cmd.Version = "1.0.0"
+			cmd.Validate = func(*cli.Env[testMeta], *testTarget) error {
+				return errors.New("invalid env")
+			}
+			cmd.Before = func(context.Context, *cli.Env[testMeta], *testTarget) error {
+				return errors.New("before: cannot connect")
+			}
+			res := executeTestCommand(t, cmd, testCommandOptions{args: args})
+
+			if got, want := res.status, cli.ExitSuccess; got != want {
+				t.Errorf("%v: cmd.Execute()=%v, want %v (stderr: %q)", args, got, want, res.errbuf)
+			}
+			if res.outbuf == "" {
+				t.Errorf("%v: cmd.Execute() wrote no output", args)
+			}
+		}
+	})
+
+	t.Run("help_command_unknown", func(t *testing.T) {
+		cmd := testCommand(t)
+		opts := testCommandOptions{args: []string{"foo", "help", "qux"}}
+		res := executeTestCommand(t, cmd, opts)
+
+		if got, want := res.status, cli.ExitUsage; got != want {
+			t.Errorf("with unknown help path: cmd.Execute()=%v, want %v", got, want)
+		}
+		if got, want := res.errbuf, "unknown command: qux\n"; !strings.Contains(got, want) {
+			t.Errorf("with unknown help path: cmd.Execute() wrote error=%q, want contains %q", got, want)
+		}
+	})
+
+	t.Run("categories", func(t *testing.T) {
+		cmd := testCommand(t)
+		cmd.Help = ""
+		cmd.Flags = nil
+		cmd.Subcommands = append(cmd.Subcommands,
+			&cli.Command[*testTarget, testMeta]{Name: "migrate", Category: "Database commands"},
+			&cli.Command[*testTarget, testMeta]{Name: "users", Category: "Admin commands"},
+			&cli.Command[*testTarget, testMeta]{Name: "seed", Category: "Database commands"},
+		)
+		res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "-h"}})
+
+		want := fooUsage + "\n\n" +
+			"Commands:\n" +
+			"  bar\n" +
+			"  help\n\n" +
+			"Database commands:\n" +
+			"  migrate\n" +
+			"  seed\n\n" +
+			"Admin commands:\n" +
+			"  users\n"
+		if got := res.outbuf; got != want {
+			t.Errorf("with categories: cmd.Execute() wrote output=%q, want %q", got, want)
+		}
+	})
+
 	t.Run("valid_flag", func(t *testing.T) {
 		cmd := testCommand(t)
 		opts := testCommandOptions{args: []string{"foo", "-env=dev"}}
@@ -575,3 +765,670 @@ func ExampleCommand() {
 	// addr=localhost:8000
 	// status=0
 }
+
+func TestCommand_Execute_RunE(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus cli.ExitStatus
+		wantErr    string
+	}{
+		{
+			name:       "nil",
+			wantStatus: cli.ExitSuccess,
+		},
+		{
+			name:       "plain_error",
+			err:        errors.New("boom"),
+			wantStatus: cli.ExitFailure,
+			wantErr:    "boom\n",
+		},
+		{
+			name:       "usage_error",
+			err:        &cli.ExitError{Status: cli.ExitUsage, Err: errors.New("bad input")},
+			wantStatus: cli.ExitUsage,
+			wantErr:    fooUsage + "\nbad input\n",
+		},
+		{
+			name:       "wrapped_exit_coder",
+			err:        fmt.Errorf("wrapped: %w", &cli.ExitError{Status: 3, Err: errors.New("custom")}),
+			wantStatus: 3,
+			wantErr:    "wrapped: custom\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := testCommand(t)
+			cmd.Action = nil
+			cmd.RunE = func(ctx context.Context, env *cli.Env[testMeta], target *testTarget) error {
+				return tt.err
+			}
+			res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo"}})
+
+			if got, want := res.status, tt.wantStatus; got != want {
+				t.Errorf("with RunE: cmd.Execute()=%v, want %v", got, want)
+			}
+			if got, want := res.errbuf, tt.wantErr; got != want {
+				t.Errorf("with RunE: cmd.Execute() wrote error=%q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCommand_Execute_Hooks(t *testing.T) {
+	var calls []string
+	hook := func(name string, err error) func(context.Context, *cli.Env[testMeta], *testTarget) error {
+		return func(context.Context, *cli.Env[testMeta], *testTarget) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+
+	t.Run("order", func(t *testing.T) {
+		calls = nil
+		cmd := testCommand(t)
+		cmd.Before = hook("foo before", nil)
+		cmd.After = hook("foo after", nil)
+		bar := cmd.Subcommands[0]
+		bar.Before = hook("bar before", nil)
+		bar.After = hook("bar after", errors.New("cleanup failed"))
+		bar.Action = func(context.Context, *cli.Env[testMeta], *testTarget) cli.ExitStatus {
+			calls = append(calls, "bar action")
+			return cli.ExitSuccess
+		}
+		res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "bar"}})
+
+		want := []string{"foo before", "bar before", "bar action", "bar after", "foo after"}
+		if !slices.Equal(calls, want) {
+			t.Errorf("with hooks: calls=%v, want %v", calls, want)
+		}
+		if got, want := res.status, cli.ExitFailure; got != want {
+			t.Errorf("with failing After: cmd.Execute()=%v, want %v", got, want)
+		}
+		if got, want := res.errbuf, "cleanup failed\n"; got != want {
+			t.Errorf("with failing After: cmd.Execute() wrote error=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("before_error", func(t *testing.T) {
+		calls = nil
+		cmd := testCommand(t)
+		cmd.Before = hook("foo before", errors.New("setup failed"))
+		cmd.After = hook("foo after", nil)
+		res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "bar"}})
+
+		if want := []string{"foo before"}; !slices.Equal(calls, want) {
+			t.Errorf("with failing Before: calls=%v, want %v", calls, want)
+		}
+		if got, want := res.status, cli.ExitFailure; got != want {
+			t.Errorf("with failing Before: cmd.Execute()=%v, want %v", got, want)
+		}
+		if strings.Contains(res.outbuf, barOut) {
+			t.Errorf("with failing Before: subcommand ran")
+		}
+	})
+}
+
+func TestCommand_Execute_Templates(t *testing.T) {
+	cmd := testCommand(t)
+	cmd.Funcs = template.FuncMap{"upper": strings.ToUpper}
+	bar := cmd.Subcommands[0]
+	bar.Usage = "usage: {{upper cmd.Path}}"
+	bar.Help = "{{range cmd.Flags}}-{{.Name}} {{.Type}} ${{.Var}}{{end}}"
+	opts := testCommandOptions{args: []string{"foo", "bar", "-h"}}
+	res := executeTestCommand(t, cmd, opts)
+
+	if got, want := res.outbuf, "usage: FOO BAR\n\n-port uint $BAR_PORT\n"; got != want {
+		t.Errorf("with templates: cmd.Execute() wrote output=%q, want %q", got, want)
+	}
+}
+
+func TestCommand_Execute_Config(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"env": "dev", "verbose": true, "port": 8080, "other": "ignored"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := func(cmd *cli.Command[*testTarget, testMeta]) {
+		flags := cmd.Flags
+		cmd.Flags = func(fs *flag.FlagSet, target *testTarget) {
+			flags(fs, target)
+			fs.String("config", "", "")
+		}
+		cmd.Vars["config"] = "FOO_CONFIG"
+		cmd.ConfigFlag = "config"
+	}
+
+	tests := []struct {
+		name string
+		opts testCommandOptions
+		want testTarget
+	}{
+		{
+			name: "flag",
+			opts: testCommandOptions{args: []string{"foo", "-config", path, "bar"}},
+			want: testTarget{env: "dev", verbose: true, port: 8080},
+		},
+		{
+			name: "var",
+			opts: testCommandOptions{args: []string{"foo", "bar"}, vars: map[string]string{"FOO_CONFIG": path}},
+			want: testTarget{env: "dev", verbose: true, port: 8080},
+		},
+		{
+			name: "precedence",
+			opts: testCommandOptions{
+				args: []string{"foo", "-config", path, "-env", "test", "bar", "-port", "9090"},
+				vars: map[string]string{"FOO_VERBOSE": "false"},
+			},
+			want: testTarget{env: "test", verbose: false, port: 9090},
+		},
+		{
+			name: "no_config",
+			opts: testCommandOptions{args: []string{"foo", "bar"}},
+			want: testTarget{env: "prod"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := testCommand(t)
+			config(cmd)
+			res := executeTestCommand(t, cmd, tt.opts)
+
+			if got, want := res.status, cli.ExitSuccess; got != want {
+				t.Fatalf("with config: cmd.Execute()=%v, want %v (stderr: %q)", got, want, res.errbuf)
+			}
+			if got := *res.target; got != tt.want {
+				t.Errorf("with config: target=%+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid_value", func(t *testing.T) {
+		bad := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(bad, []byte(`{"verbose": "maybe"}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := testCommand(t)
+		config(cmd)
+		res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "-config", bad}})
+
+		if got, want := res.status, cli.ExitUsage; got != want {
+			t.Errorf("with invalid config: cmd.Execute()=%v, want %v", got, want)
+		}
+		if got, want := res.errbuf, `invalid value "maybe" for config verbose`; !strings.Contains(got, want) {
+			t.Errorf("with invalid config: cmd.Execute() wrote error=%q, want contains %q", got, want)
+		}
+	})
+}
+
+func TestCommand_Execute_ArgFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "args.txt")
+	if err := os.WriteFile(path, []byte("# generated\n-env\ndev\n\n  bar  \n-port=9090\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("expands", func(t *testing.T) {
+		cmd := testCommand(t)
+		cmd.ArgFiles = true
+		res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "-verbose", "@" + path}})
+
+		if got, want := res.status, cli.ExitSuccess; got != want {
+			t.Fatalf("with arg file: cmd.Execute()=%v, want %v (stderr: %q)", got, want, res.errbuf)
+		}
+		if got, want := *res.target, (testTarget{env: "dev", verbose: true, port: 9090}); got != want {
+			t.Errorf("with arg file: target=%+v, want %+v", got, want)
+		}
+		if got, want := res.outbuf, barOut; got != want {
+			t.Errorf("with arg file: cmd.Execute() wrote output=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cmd := testCommand(t)
+		res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "@" + path}})
+
+		if got, want := res.outbuf, fooOut; got != want {
+			t.Errorf("without arg files: cmd.Execute() wrote output=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		cmd := testCommand(t)
+		cmd.ArgFiles = true
+		res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "@" + path + ".missing"}})
+
+		if got, want := res.status, cli.ExitUsage; got != want {
+			t.Errorf("with missing arg file: cmd.Execute()=%v, want %v", got, want)
+		}
+		if got, want := res.errbuf, "error reading argument file"; !strings.Contains(got, want) {
+			t.Errorf("with missing arg file: cmd.Execute() wrote error=%q, want contains %q", got, want)
+		}
+	})
+}
+
+func TestCommand_Execute_Interspersed(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantArgs []string
+		wantEnv  string
+		wantOut  string
+	}{
+		{
+			name:     "flags_after_positionals",
+			args:     []string{"foo", "src", "-env", "dev", "dst", "-verbose"},
+			wantArgs: []string{"src", "dst"},
+			wantEnv:  "dev",
+			wantOut:  fooOut,
+		},
+		{
+			name:     "terminator",
+			args:     []string{"foo", "src", "--", "-env", "dev"},
+			wantArgs: []string{"src", "-env", "dev"},
+			wantEnv:  "prod",
+			wantOut:  fooOut,
+		},
+		{
+			name:     "subcommand",
+			args:     []string{"foo", "-env", "dev", "bar", "-port", "1"},
+			wantArgs: []string{},
+			wantEnv:  "dev",
+			wantOut:  barOut,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := testCommand(t)
+			cmd.Interspersed = true
+			res := executeTestCommand(t, cmd, testCommandOptions{args: tt.args})
+
+			if got, want := res.status, cli.ExitSuccess; got != want {
+				t.Fatalf("with interspersed flags: cmd.Execute()=%v, want %v (stderr: %q)", got, want, res.errbuf)
+			}
+			if got, want := res.outbuf, tt.wantOut; got != want {
+				t.Errorf("with interspersed flags: cmd.Execute() wrote output=%q, want %q", got, want)
+			}
+			if got, want := res.target.env, tt.wantEnv; got != want {
+				t.Errorf("with interspersed flags: env=%q, want %q", got, want)
+			}
+			if got, want := res.env.Args, tt.wantArgs; !slices.Equal(got, want) {
+				t.Errorf("with interspersed flags: args=%q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCommand_Execute_Aliases(t *testing.T) {
+	aliased := func() *cli.Command[*testTarget, testMeta] {
+		cmd := testCommand(t)
+		cmd.Aliases = map[string]string{"v": "verbose", "e": "env"}
+		return cmd
+	}
+
+	t.Run("alias", func(t *testing.T) {
+		res := executeTestCommand(t, aliased(), testCommandOptions{args: []string{"foo", "-v", "-e", "dev"}})
+
+		if got, want := *res.target, (testTarget{env: "dev", verbose: true}); got != want {
+			t.Errorf("with aliases: target=%+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("var_does_not_override_alias", func(t *testing.T) {
+		opts := testCommandOptions{
+			args: []string{"foo", "-e", "dev"},
+			vars: map[string]string{"FOO_ENV": "staging"},
+		}
+		res := executeTestCommand(t, aliased(), opts)
+
+		if got, want := res.target.env, "dev"; got != want {
+			t.Errorf("with alias and var: env=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		cmd := aliased()
+		cmd.Help = ""
+		res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "-h"}})
+
+		want := "Flags:\n" +
+			"  -e, -env string\n" +
+			"    \t(default \"prod\") [$FOO_ENV]\n" +
+			"  -v, -verbose\n" +
+			"    \t[$FOO_VERBOSE]\n\n"
+		if got := res.outbuf; !strings.Contains(got, want) {
+			t.Errorf("with aliases: cmd.Execute() wrote output=%q, want contains %q", got, want)
+		}
+	})
+
+	t.Run("undefined", func(t *testing.T) {
+		cmd := testCommand(t)
+		cmd.Aliases = map[string]string{"q": "quiet"}
+		res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo"}})
+
+		if got, want := res.status, cli.ExitFailure; got != want {
+			t.Errorf("with undefined alias: cmd.Execute()=%v, want %v", got, want)
+		}
+	})
+}
+
+func TestCommand_Execute_Reuse(t *testing.T) {
+	cmd := testCommand(t)
+	cmd.Action = func(ctx context.Context, env *cli.Env[testMeta], target *testTarget) cli.ExitStatus {
+		env.Printf("%s\n", target.env)
+		return cli.ExitSuccess
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			env := fmt.Sprintf("env%d", i)
+			res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "-env", env}})
+			if got, want := res.outbuf, env+"\n"; got != want {
+				t.Errorf("with reused command: cmd.Execute() wrote output=%q, want %q", got, want)
+			}
+		}()
+	}
+	wg.Wait()
+
+	res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo"}})
+	if got, want := res.outbuf, "prod\n"; got != want {
+		t.Errorf("with reused command: cmd.Execute() wrote output=%q, want %q", got, want)
+	}
+}
+
+func TestCommand_Execute_Validate(t *testing.T) {
+	validate := func(env *cli.Env[testMeta], target *testTarget) error {
+		if target.verbose && target.env == "prod" {
+			return errors.New("-verbose is not allowed with -env=prod")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStatus cli.ExitStatus
+		wantOut    string
+	}{
+		{name: "valid", args: []string{"foo", "-verbose", "-env", "dev", "bar"}, wantOut: barOut},
+		{name: "invalid", args: []string{"foo", "-verbose", "bar"}, wantStatus: cli.ExitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := testCommand(t)
+			cmd.Validate = validate
+			res := executeTestCommand(t, cmd, testCommandOptions{args: tt.args})
+
+			if got, want := res.status, tt.wantStatus; got != want {
+				t.Errorf("with Validate: cmd.Execute()=%v, want %v", got, want)
+			}
+			if got, want := res.outbuf, tt.wantOut; got != want {
+				t.Errorf("with Validate: cmd.Execute() wrote output=%q, want %q", got, want)
+			}
+			if tt.wantStatus == cli.ExitUsage {
+				want := fooUsage + "\n-verbose is not allowed with -env=prod\n"
+				if got := res.errbuf; got != want {
+					t.Errorf("with Validate: cmd.Execute() wrote error=%q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCommand_Execute_VarPrefix(t *testing.T) {
+	cmd := testCommand(t)
+	cmd.VarPrefix = "APP_"
+	cmd.Vars = map[string]string{"env": "ENVIRONMENT"}
+	cmd.Subcommands[0].Vars = nil
+	opts := testCommandOptions{
+		args: []string{"foo", "bar"},
+		vars: map[string]string{"ENVIRONMENT": "dev", "APP_VERBOSE": "true", "APP_PORT": "9090", "APP_ENV": "ignored"},
+	}
+	res := executeTestCommand(t, cmd, opts)
+
+	if got, want := *res.target, (testTarget{env: "dev", verbose: true, port: 9090}); got != want {
+		t.Errorf("with VarPrefix: target=%+v, want %+v", got, want)
+	}
+
+	cmd.Help = ""
+	res = executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "-h"}})
+	if got, want := res.outbuf, "[$APP_VERBOSE]"; !strings.Contains(got, want) {
+		t.Errorf("with VarPrefix: cmd.Execute() wrote output=%q, want contains %q", got, want)
+	}
+}
+
+func TestCommand_Execute_HelpRequested(t *testing.T) {
+	tests := []struct {
+		name string
+		opts testCommandOptions
+		want string
+	}{
+		{
+			name: "bad_flag_before_help",
+			opts: testCommandOptions{args: []string{"foo", "bar", "-badflag", "-h"}},
+			want: barUsage + "\n\n" + barHelp + "\n",
+		},
+		{
+			name: "help_before_subcommand",
+			opts: testCommandOptions{args: []string{"foo", "-h", "bar"}},
+			want: barUsage + "\n\n" + barHelp + "\n",
+		},
+		{
+			name: "help_before_unknown_subcommand",
+			opts: testCommandOptions{args: []string{"foo", "-h", "qux"}},
+			want: fooUsage + "\n\n" + fooHelp + "\n",
+		},
+		{
+			name: "invalid_parent_var",
+			opts: testCommandOptions{
+				args: []string{"foo", "bar", "-h"},
+				vars: map[string]string{"FOO_VERBOSE": "maybe"},
+			},
+			want: barUsage + "\n\n" + barHelp + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := executeTestCommand(t, testCommand(t), tt.opts)
+
+			if got, want := res.status, cli.ExitSuccess; got != want {
+				t.Errorf("with help requested: cmd.Execute()=%v, want %v (stderr: %q)", got, want, res.errbuf)
+			}
+			if got := res.outbuf; got != tt.want {
+				t.Errorf("with help requested: cmd.Execute() wrote output=%q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("help_after_positional", func(t *testing.T) {
+		cmd := testCommand(t)
+		bar := cmd.Subcommands[0]
+		ran := false
+		bar.Action = func(context.Context, *cli.Env[testMeta], *testTarget) cli.ExitStatus {
+			ran = true
+			return cli.ExitSuccess
+		}
+		bar.Validate = func(*cli.Env[testMeta], *testTarget) error {
+			return errors.New("port is required")
+		}
+
+		res := executeTestCommand(t, cmd, testCommandOptions{
+			args: []string{"foo", "bar", "pos", "-h"},
+			vars: map[string]string{"BAR_PORT": "notanumber"},
+		})
+		if got, want := res.status, cli.ExitSuccess; got != want {
+			t.Errorf("with help after positional: cmd.Execute()=%v, want %v (stderr: %q)", got, want, res.errbuf)
+		}
+		if ran {
+			t.Errorf("with help after positional: action ran, want help")
+		}
+		if got, want := res.outbuf, barUsage+"\n\n"+barHelp+"\n"; got != want {
+			t.Errorf("with help after positional: cmd.Execute() wrote output=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("help_skips_parent_hooks", func(t *testing.T) {
+		cmd := testCommand(t)
+		cmd.Validate = func(*cli.Env[testMeta], *testTarget) error {
+			return errors.New("invalid env")
+		}
+		cmd.Before = func(context.Context, *cli.Env[testMeta], *testTarget) error {
+			return errors.New("before: cannot connect")
+		}
+		cmd.After = func(context.Context, *cli.Env[testMeta], *testTarget) error {
+			return errors.New("after: cannot disconnect")
+		}
+
+		res := executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "bar", "-h"}})
+		if got, want := res.status, cli.ExitSuccess; got != want {
+			t.Errorf("with failing parent hooks: cmd.Execute()=%v, want %v (stderr: %q)", got, want, res.errbuf)
+		}
+		if got, want := res.outbuf, barUsage+"\n\n"+barHelp+"\n"; got != want {
+			t.Errorf("with failing parent hooks: cmd.Execute() wrote output=%q, want %q", got, want)
+		}
+	})
+
+	if cli.HelpRequested([]string{"--", "-h"}) {
+		t.Errorf("HelpRequested after --: got true, want false")
+	}
+}
+
+func TestCommand_Execute_Context(t *testing.T) {
+	cmd := testCommand(t)
+	var gotPath string
+	var fromCtx bool
+	cmd.Subcommands[0].Action = func(ctx context.Context, env *cli.Env[testMeta], target *testTarget) cli.ExitStatus {
+		ctxEnv, ok := cli.EnvFromContext[testMeta](ctx)
+		fromCtx = ok && ctxEnv == env
+		gotPath = env.Path
+		return cli.ExitSuccess
+	}
+	executeTestCommand(t, cmd, testCommandOptions{args: []string{"foo", "bar"}})
+
+	if !fromCtx {
+		t.Errorf("EnvFromContext did not return the executing env")
+	}
+	if want := "foo bar"; gotPath != want {
+		t.Errorf("env.Path=%q, want %q", gotPath, want)
+	}
+	if _, ok := cli.EnvFromContext[any](context.Background()); ok {
+		t.Errorf("EnvFromContext(context.Background()) returned ok")
+	}
+}
+
+func TestCommand_Execute_DryRun(t *testing.T) {
+	tests := []struct {
+		args []string
+		vars map[string]string
+		want bool
+	}{
+		{args: []string{"foo", "bar"}},
+		{args: []string{"foo", "-dry-run", "bar"}, want: true},
+		{args: []string{"foo", "bar", "-dry-run"}, want: true},
+		{args: []string{"foo", "bar"}, vars: map[string]string{"APP_DRY_RUN": "1"}, want: true},
+	}
+
+	for _, tt := range tests {
+		cmd := testCommand(t)
+		cmd.DryRunFlag = true
+		cmd.VarPrefix = "APP_"
+		res := executeTestCommand(t, cmd, testCommandOptions{args: tt.args, vars: tt.vars})
+
+		if got, want := res.status, cli.ExitSuccess; got != want {
+			t.Errorf("with args %v: cmd.Execute()=%v, want %v (stderr: %q)", tt.args, got, want, res.errbuf)
+		}
+		if got := res.env.DryRun; got != tt.want {
+			t.Errorf("with args %v: env.DryRun=%v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCommand_Execute_Timeout(t *testing.T) {
+	tests := []struct {
+		args         []string
+		wantDeadline bool
+	}{
+		{args: []string{"foo", "bar"}},
+		{args: []string{"foo", "-timeout", "1m", "bar"}, wantDeadline: true},
+		{args: []string{"foo", "bar", "-timeout=1m"}, wantDeadline: true},
+	}
+
+	for _, tt := range tests {
+		cmd := testCommand(t)
+		cmd.TimeoutFlag = true
+		var hasDeadline bool
+		cmd.Subcommands[0].Action = func(ctx context.Context, env *cli.Env[testMeta], target *testTarget) cli.ExitStatus {
+			_, hasDeadline = ctx.Deadline()
+			return cli.ExitSuccess
+		}
+		res := executeTestCommand(t, cmd, testCommandOptions{args: tt.args})
+
+		if got, want := res.status, cli.ExitSuccess; got != want {
+			t.Errorf("with args %v: cmd.Execute()=%v, want %v (stderr: %q)", tt.args, got, want, res.errbuf)
+		}
+		if hasDeadline != tt.wantDeadline {
+			t.Errorf("with args %v: context has deadline=%v, want %v", tt.args, hasDeadline, tt.wantDeadline)
+		}
+	}
+}
+
+func TestCommand_Execute_Translate(t *testing.T) {
+	catalog := map[string]string{
+		"usage:":          "uso:",
+		"[flags]":         "[opciones]",
+		"<command>":       "<comando>",
+		"unknown command": "comando desconocido",
+	}
+	cmd := testCommand(t)
+	cmd.Usage = ""
+	cmd.Action = nil
+	var errbuf strings.Builder
+	env := &cli.Env[testMeta]{
+		Err:  &errbuf,
+		Args: []string{"foo", "qux"},
+		Translate: func(format string) string {
+			if s, ok := catalog[format]; ok {
+				return s
+			}
+			return format
+		},
+	}
+	status := cmd.Execute(context.Background(), env, &testTarget{})
+
+	if got, want := status, cli.ExitUsage; got != want {
+		t.Errorf("with Translate: cmd.Execute()=%v, want %v", got, want)
+	}
+	if got, want := errbuf.String(), "uso: foo [opciones] <comando>\ncomando desconocido\n"; got != want {
+		t.Errorf("with Translate: cmd.Execute() wrote error=%q, want %q", got, want)
+	}
+}
+
+func TestCommand_Execute_RecoverPanics(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		cmd := testCommand(t)
+		cmd.RecoverPanics = true
+		cmd.Subcommands[0].Action = func(ctx context.Context, env *cli.Env[testMeta], target *testTarget) cli.ExitStatus {
+			panic("boom")
+		}
+		var errbuf strings.Builder
+		env := &cli.Env[testMeta]{Err: &errbuf, Args: []string{"foo", "bar"}, Debug: debug}
+		status := cmd.Execute(context.Background(), env, &testTarget{})
+
+		if got, want := status, cli.ExitPanic; got != want {
+			t.Errorf("with debug=%v: cmd.Execute()=%v, want %v", debug, got, want)
+		}
+		if got, want := errbuf.String(), "panic: boom\n"; !strings.HasPrefix(got, want) {
+			t.Errorf("with debug=%v: cmd.Execute() wrote error=%q, want prefix %q", debug, got, want)
+		}
+		if got := strings.Contains(errbuf.String(), "goroutine"); got != debug {
+			t.Errorf("with debug=%v: stack trace printed=%v", debug, got)
+		}
+	}
+}
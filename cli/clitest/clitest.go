@@ -0,0 +1,99 @@
+// Package clitest provides helpers for testing commands built with the cli
+// package.
+//
+// [Run] executes a command with the given arguments and captures its output:
+//
+//	res := clitest.Run(t, cmd, &config{}, "tool", "-v", "list")
+//	clitest.RequireStatus(t, res, cli.ExitSuccess)
+//	clitest.RequireStdoutContains(t, res, "item")
+package clitest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jonathonwebb/x/cli"
+)
+
+// A Result is the outcome of executing a command.
+type Result[T any] struct {
+	Status cli.ExitStatus
+	Stdout string
+	Stderr string
+	Args   []string // positional arguments left after parsing flags
+	Target T
+}
+
+// Run executes cmd with args, including the command name, and no environment
+// variables.
+func Run[T any, M any](t testing.TB, cmd *cli.Command[T, M], target T, args ...string) *Result[T] {
+	t.Helper()
+	return Execute(t, cmd, cli.Env[M]{Args: args}, target)
+}
+
+// Execute executes cmd in env, replacing its output streams with buffers
+// whose contents are returned in the Result. If env.In is nil, the command
+// reads an empty standard input stream.
+func Execute[T any, M any](t testing.TB, cmd *cli.Command[T, M], env cli.Env[M], target T) *Result[T] {
+	t.Helper()
+	if env.In == nil {
+		env.In = strings.NewReader("")
+	}
+	var stdout, stderr bytes.Buffer
+	env.Out = &stdout
+	env.Err = &stderr
+	status := cmd.Execute(t.Context(), &env, target)
+	return &Result[T]{
+		Status: status,
+		Stdout: stdout.String(),
+		Stderr: stderr.String(),
+		Args:   env.Args,
+		Target: target,
+	}
+}
+
+// RequireStatus fails the test immediately if the command did not exit with
+// status.
+func RequireStatus[T any](t testing.TB, r *Result[T], status cli.ExitStatus) {
+	t.Helper()
+	if r.Status != status {
+		t.Fatalf("exit status: want %d, got %d\nstderr: %q", status, r.Status, r.Stderr)
+	}
+}
+
+// RequireStdout fails the test immediately if the command's standard output
+// is not want.
+func RequireStdout[T any](t testing.TB, r *Result[T], want string) {
+	t.Helper()
+	if r.Stdout != want {
+		t.Fatalf("stdout mismatch\nwant: %q\ngot:  %q", want, r.Stdout)
+	}
+}
+
+// RequireStderr fails the test immediately if the command's error output is
+// not want.
+func RequireStderr[T any](t testing.TB, r *Result[T], want string) {
+	t.Helper()
+	if r.Stderr != want {
+		t.Fatalf("stderr mismatch\nwant: %q\ngot:  %q", want, r.Stderr)
+	}
+}
+
+// RequireStdoutContains fails the test immediately if the command's standard
+// output does not contain substr.
+func RequireStdoutContains[T any](t testing.TB, r *Result[T], substr string) {
+	t.Helper()
+	if !strings.Contains(r.Stdout, substr) {
+		t.Fatalf("stdout %q does not contain %q", r.Stdout, substr)
+	}
+}
+
+// RequireStderrContains fails the test immediately if the command's error
+// output does not contain substr.
+func RequireStderrContains[T any](t testing.TB, r *Result[T], substr string) {
+	t.Helper()
+	if !strings.Contains(r.Stderr, substr) {
+		t.Fatalf("stderr %q does not contain %q", r.Stderr, substr)
+	}
+}
@@ -0,0 +1,79 @@
+package clitest_test
+
+import (
+	"context"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jonathonwebb/x/cli"
+	"github.com/jonathonwebb/x/cli/clitest"
+)
+
+type config struct {
+	name string
+}
+
+func greetCommand() *cli.Command[*config, any] {
+	return &cli.Command[*config, any]{
+		Name: "greet",
+		Flags: func(flags *flag.FlagSet, c *config) {
+			flags.StringVar(&c.name, "name", "world", "")
+		},
+		Vars: map[string]string{"name": "GREET_NAME"},
+		Action: func(ctx context.Context, env *cli.Env[any], c *config) cli.ExitStatus {
+			if len(env.Args) > 0 {
+				env.Errorf("unexpected arguments: %v\n", env.Args)
+				return cli.ExitUsage
+			}
+			env.Printf("hello, %s\n", c.name)
+			return cli.ExitSuccess
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	res := clitest.Run(t, greetCommand(), &config{}, "greet", "-name", "gopher")
+	clitest.RequireStatus(t, res, cli.ExitSuccess)
+	clitest.RequireStdout(t, res, "hello, gopher\n")
+	clitest.RequireStderr(t, res, "")
+	if res.Target.name != "gopher" {
+		t.Errorf("target name: want %q, got %q", "gopher", res.Target.name)
+	}
+
+	res = clitest.Run(t, greetCommand(), &config{}, "greet", "extra")
+	clitest.RequireStatus(t, res, cli.ExitUsage)
+	clitest.RequireStderrContains(t, res, "unexpected arguments")
+}
+
+func TestExecute(t *testing.T) {
+	env := cli.Env[any]{
+		Args: []string{"greet"},
+		Vars: map[string]string{"GREET_NAME": "env"},
+	}
+	res := clitest.Execute(t, greetCommand(), env, &config{})
+	clitest.RequireStatus(t, res, cli.ExitSuccess)
+	clitest.RequireStdoutContains(t, res, "env")
+}
+
+func TestExecute_In(t *testing.T) {
+	cmd := &cli.Command[any, any]{
+		Name: "upper",
+		RunE: func(ctx context.Context, env *cli.Env[any], target any) error {
+			b, err := io.ReadAll(env.In)
+			if err != nil {
+				return err
+			}
+			env.Printf("%s", strings.ToUpper(string(b)))
+			return nil
+		},
+	}
+	env := cli.Env[any]{
+		In:   strings.NewReader("piped\n"),
+		Args: []string{"upper"},
+	}
+	res := clitest.Execute(t, cmd, env, nil)
+	clitest.RequireStatus(t, res, cli.ExitSuccess)
+	clitest.RequireStdout(t, res, "PIPED\n")
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jonathonwebb/x/internal/term"
+)
+
+// Successf formats and writes a success message to the standard output stream,
+// in green if colors are enabled for it.
+func (e Env[M]) Successf(format string, args ...any) {
+	e.colorf(e.Out, term.Green, format, args...)
+}
+
+// Warnf formats and writes a warning message to the error output stream, in
+// yellow if colors are enabled for it.
+func (e Env[M]) Warnf(format string, args ...any) {
+	e.colorf(e.Err, term.Yellow, format, args...)
+}
+
+// Failf formats and writes a failure message to the error output stream, in
+// red if colors are enabled for it.
+func (e Env[M]) Failf(format string, args ...any) {
+	e.colorf(e.Err, term.Red, format, args...)
+}
+
+func (e Env[M]) colorf(w io.Writer, color, format string, args ...any) {
+	if w == nil {
+		return
+	}
+	if !term.ColorEnabled(w, e.getVar) {
+		fmt.Fprintf(w, format, args...)
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	text := strings.TrimRight(msg, "\n")
+	fmt.Fprintf(w, "%s%s%s%s", color, text, term.Reset, msg[len(text):])
+}
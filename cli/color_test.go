@@ -0,0 +1,52 @@
+package cli_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jonathonwebb/x/cli"
+)
+
+func TestEnv_Colors(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantOut string
+		wantErr string
+	}{
+		{
+			name:    "not_a_terminal",
+			wantOut: "done\n",
+			wantErr: "careful\nbroken\n",
+		},
+		{
+			name:    "forced",
+			vars:    map[string]string{"CLICOLOR_FORCE": "1"},
+			wantOut: "\033[32mdone\033[0m\n",
+			wantErr: "\033[33mcareful\033[0m\n\033[31mbroken\033[0m\n",
+		},
+		{
+			name:    "no_color",
+			vars:    map[string]string{"CLICOLOR_FORCE": "1", "NO_COLOR": "1"},
+			wantOut: "done\n",
+			wantErr: "careful\nbroken\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut strings.Builder
+			env := cli.Env[any]{Out: &out, Err: &errOut, Vars: tt.vars}
+			env.Successf("%s\n", "done")
+			env.Warnf("careful\n")
+			env.Failf("broken\n")
+
+			if got := out.String(); got != tt.wantOut {
+				t.Errorf("stdout: want %q, got %q", tt.wantOut, got)
+			}
+			if got := errOut.String(); got != tt.wantErr {
+				t.Errorf("stderr: want %q, got %q", tt.wantErr, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DecodeJSONConfig decodes a JSON object of flag names to values, the default
+// config file format. Arrays are joined with commas, for flags such as
+// [StringsVar], and objects are joined as comma-separated key=value pairs, for
+// flags such as [MapVar].
+func DecodeJSONConfig(r io.Reader) (map[string]string, error) {
+	var raw map[string]any
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(raw))
+	for name, v := range raw {
+		s, err := configValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+		values[name] = s
+	}
+	return values, nil
+}
+
+func configValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number, bool:
+		return fmt.Sprint(v), nil
+	case []any:
+		elems := make([]string, len(v))
+		for i, elem := range v {
+			s, err := configValue(elem)
+			if err != nil {
+				return "", err
+			}
+			elems[i] = s
+		}
+		return strings.Join(elems, ","), nil
+	case map[string]any:
+		var pairs []string
+		for k, elem := range v {
+			s, err := configValue(elem)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, k+"="+s)
+		}
+		return strings.Join(pairs, ","), nil
+	}
+	return "", fmt.Errorf("unsupported type %T", v)
+}
+
+// loadConfig reads the config file named by the command's ConfigFlag, if it
+// is set.
+func (c *execution[T, M]) loadConfig(env *Env[M]) error {
+	c.config = nil
+	if c.ConfigFlag == "" {
+		return nil
+	}
+	f := c.flagSet().Lookup(c.ConfigFlag)
+	if f == nil || f.Value.String() == "" {
+		return nil
+	}
+
+	path := f.Value.String()
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf(env.tr("error reading config file: %v"), err)
+	}
+	defer file.Close()
+
+	decode := c.DecodeConfig
+	if decode == nil {
+		decode = DecodeJSONConfig
+	}
+	if c.config, err = decode(file); err != nil {
+		return fmt.Errorf(env.tr("error decoding config file %s: %v"), path, err)
+	}
+	return nil
+}
+
+// configValues returns the config file values of the command or, if it has
+// none, of its nearest parent.
+func (c *execution[T, M]) configValues() map[string]string {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.config != nil {
+			return cmd.config
+		}
+	}
+	return nil
+}
+
+// applyConfig sets flags that were not set on the command line or from
+// environment variables to their config file values. Config file entries for
+// unknown flags are ignored, since a config file is shared by a command and
+// its subcommands.
+func (c *execution[T, M]) applyConfig(env *Env[M]) error {
+	if err := c.loadConfig(env); err != nil {
+		return err
+	}
+	values := c.configValues()
+	if values == nil {
+		return nil
+	}
+
+	set := c.setFlags()
+	var err error
+	c.flagSet().VisitAll(func(f *flag.Flag) {
+		if _, isAlias := c.Aliases[f.Name]; isAlias {
+			return
+		}
+		value, ok := values[f.Name]
+		if err != nil || set[f.Name] || !ok {
+			return
+		}
+		if setErr := c.flagSet().Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf(env.tr("invalid value %q for config %s: %v"), value, f.Name, setErr)
+		}
+	})
+	return err
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// An OutputFormat selects how [Env.Emit] writes values.
+type OutputFormat string
+
+const (
+	OutputPlain OutputFormat = "plain" // values formatted with fmt, one slice element per line
+	OutputJSON  OutputFormat = "json"  // indented JSON
+	OutputTable OutputFormat = "table" // a table with a column per exported struct field
+)
+
+// outputFormats are the values accepted by the -output flag.
+var outputFormats = []string{string(OutputPlain), string(OutputJSON), string(OutputTable)}
+
+// defineOutputFlag defines the -output flag, storing its value in env.
+func defineOutputFlag[M any](flags *flag.FlagSet, env *Env[M]) {
+	if flags.Lookup("output") != nil {
+		return
+	}
+	if env.Output == "" {
+		env.Output = OutputPlain
+	}
+	flags.Var(&enumValue{p: (*string)(&env.Output), allowed: outputFormats}, "output", env.tr("output format:")+" "+strings.Join(outputFormats, ", "))
+}
+
+// Emit writes v to the standard output stream in the environment's Output
+// format.
+//
+// The table format expects a struct, or a slice of structs or struct pointers,
+// and writes a header row of the exported field names followed by a row per
+// element. Other values are written in the plain format.
+func (e Env[M]) Emit(v any) error {
+	if e.Out == nil {
+		return nil
+	}
+	switch e.Output {
+	case OutputJSON:
+		enc := json.NewEncoder(e.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case OutputTable:
+		if rows, ok := tableRows(v); ok {
+			return writeTable(e, rows)
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		for i := range rv.Len() {
+			if _, err := fmt.Fprintln(e.Out, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	_, err := fmt.Fprintln(e.Out, v)
+	return err
+}
+
+// tableRows returns the struct values in v, or false if v is not a struct or
+// a slice of structs.
+func tableRows(v any) ([]reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Struct {
+		return []reflect.Value{rv}, true
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	elem := rv.Type().Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	var rows []reflect.Value
+	for i := range rv.Len() {
+		row := reflect.Indirect(rv.Index(i))
+		if row.IsValid() {
+			rows = append(rows, row)
+		}
+	}
+	return rows, true
+}
+
+func writeTable[M any](e Env[M], rows []reflect.Value) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	var fields []int
+	var header []string
+	typ := rows[0].Type()
+	for i := range typ.NumField() {
+		if f := typ.Field(i); f.IsExported() {
+			fields = append(fields, i)
+			header = append(header, strings.ToUpper(f.Name))
+		}
+	}
+
+	tw := tabwriter.NewWriter(e.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(fields))
+		for i, field := range fields {
+			cells[i] = fmt.Sprint(row.Field(field).Interface())
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
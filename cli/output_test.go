@@ -0,0 +1,99 @@
+package cli_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jonathonwebb/x/cli"
+)
+
+type outputRow struct {
+	Name  string
+	Count int
+	note  string
+}
+
+func TestEnv_Emit(t *testing.T) {
+	rows := []*outputRow{{Name: "a", Count: 1, note: "x"}, {Name: "bb", Count: 22}}
+
+	tests := []struct {
+		name   string
+		output cli.OutputFormat
+		v      any
+		want   string
+	}{
+		{
+			name: "plain",
+			v:    []string{"a", "b"},
+			want: "a\nb\n",
+		},
+		{
+			name:   "json",
+			output: cli.OutputJSON,
+			v:      map[string]int{"n": 1},
+			want:   "{\n  \"n\": 1\n}\n",
+		},
+		{
+			name:   "table",
+			output: cli.OutputTable,
+			v:      rows,
+			want:   "NAME  COUNT\na     1\nbb    22\n",
+		},
+		{
+			name:   "table_fallback",
+			output: cli.OutputTable,
+			v:      42,
+			want:   "42\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			env := cli.Env[any]{Out: &b, Output: tt.output}
+			if err := env.Emit(tt.v); err != nil {
+				t.Fatalf("env.Emit()=%v, want nil", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("env.Emit() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommand_OutputFlag(t *testing.T) {
+	cmd := &cli.Command[any, any]{
+		Name:       "tool",
+		OutputFlag: true,
+		Subcommands: []*cli.Command[any, any]{{
+			Name: "list",
+			RunE: func(ctx context.Context, env *cli.Env[any], target any) error {
+				return env.Emit([]outputRow{{Name: "a", Count: 1}})
+			},
+		}},
+	}
+
+	tests := []struct {
+		args       []string
+		wantStatus cli.ExitStatus
+		want       string
+	}{
+		{args: []string{"tool", "list"}, want: "{a 1 }\n"},
+		{args: []string{"tool", "-output", "json", "list"}, want: "[\n  {\n    \"Name\": \"a\",\n    \"Count\": 1\n  }\n]\n"},
+		{args: []string{"tool", "list", "-output=table"}, want: "NAME  COUNT\na     1\n"},
+		{args: []string{"tool", "-output", "xml", "list"}, wantStatus: cli.ExitUsage},
+	}
+
+	for _, tt := range tests {
+		var b strings.Builder
+		env := &cli.Env[any]{Out: &b, Err: io.Discard, Args: tt.args}
+		if got := cmd.Execute(context.Background(), env, nil); got != tt.wantStatus {
+			t.Errorf("with args %v: cmd.Execute()=%v, want %v", tt.args, got, tt.wantStatus)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("with args %v: cmd.Execute() wrote %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonathonwebb/x/internal/term"
+)
+
+// A Bar is a determinate progress bar. Its methods are safe for concurrent
+// use, and do nothing on a nil Bar.
+type Bar struct {
+	mu      sync.Mutex
+	w       io.Writer
+	label   string
+	total   int64
+	current int64
+}
+
+const barWidth = 30
+
+// NewBar returns a progress bar for total units of work that renders to w.
+func NewBar(w io.Writer, label string, total int64) *Bar {
+	b := &Bar{w: w, label: label, total: total}
+	b.render()
+	return b
+}
+
+// Bar returns a progress bar that renders to the error output stream, or nil
+// if the stream is not a terminal.
+func (e Env[M]) Bar(label string, total int64) *Bar {
+	if e.Err == nil || !term.IsTerminal(e.Err) {
+		return nil
+	}
+	return NewBar(e.Err, label, total)
+}
+
+// Add adds n completed units of work. A negative n undoes completed work. The
+// completed work is kept between zero and the bar's total.
+func (b *Bar) Add(n int64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.current = max(min(b.current+n, b.total), 0)
+	b.render()
+}
+
+// Done completes the bar and ends its line.
+func (b *Bar) Done() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.current = b.total
+	b.render()
+	fmt.Fprintln(b.w)
+}
+
+func (b *Bar) render() {
+	filled, percent := barWidth, 100
+	if b.total > 0 {
+		filled = int(b.current * barWidth / b.total)
+		percent = int(b.current * 100 / b.total)
+	}
+	fmt.Fprintf(b.w, "\r%s [%s%s] %3d%% (%d/%d)", b.label,
+		strings.Repeat("=", filled), strings.Repeat(" ", barWidth-filled),
+		percent, b.current, b.total)
+}
+
+// A Spinner is an indeterminate progress indicator. Its methods do nothing on
+// a nil Spinner.
+type Spinner struct {
+	w     io.Writer
+	label string
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+const defaultSpinnerInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// NewSpinner starts a spinner that renders to w every interval until it is
+// stopped. A non-positive interval defaults to 100ms.
+func NewSpinner(w io.Writer, label string, interval time.Duration) *Spinner {
+	if interval <= 0 {
+		interval = defaultSpinnerInterval
+	}
+	s := &Spinner{
+		w:     w,
+		label: label,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go s.run(interval)
+	return s
+}
+
+// Spinner starts a spinner that renders to the error output stream, or returns
+// nil if the stream is not a terminal.
+func (e Env[M]) Spinner(label string) *Spinner {
+	if e.Err == nil || !term.IsTerminal(e.Err) {
+		return nil
+	}
+	return NewSpinner(e.Err, label, defaultSpinnerInterval)
+}
+
+func (s *Spinner) run(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; ; i++ {
+		fmt.Fprintf(s.w, "\r%s %s", s.label, spinnerFrames[i%len(spinnerFrames)])
+		select {
+		case <-s.stop:
+			fmt.Fprintf(s.w, "\r\033[K")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop stops the spinner and clears its line. It returns once the spinner has
+// stopped rendering.
+func (s *Spinner) Stop() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() { close(s.stop) })
+	<-s.done
+}
@@ -0,0 +1,99 @@
+package cli_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonathonwebb/x/cli"
+)
+
+func TestBar(t *testing.T) {
+	var b strings.Builder
+	bar := cli.NewBar(&b, "copy", 4)
+	bar.Add(1)
+	bar.Add(10)
+	bar.Done()
+
+	want := "\rcopy [                              ]   0% (0/4)" +
+		"\rcopy [=======                       ]  25% (1/4)" +
+		"\rcopy [==============================] 100% (4/4)" +
+		"\rcopy [==============================] 100% (4/4)\n"
+	if got := b.String(); got != want {
+		t.Errorf("bar output mismatch\nwant: %q\ngot:  %q", want, got)
+	}
+}
+
+func TestBar_Negative(t *testing.T) {
+	var b strings.Builder
+	bar := cli.NewBar(&b, "copy", 4)
+	bar.Add(2)
+	bar.Add(-1)
+	bar.Add(-10)
+
+	want := "\rcopy [                              ]   0% (0/4)" +
+		"\rcopy [===============               ]  50% (2/4)" +
+		"\rcopy [=======                       ]  25% (1/4)" +
+		"\rcopy [                              ]   0% (0/4)"
+	if got := b.String(); got != want {
+		t.Errorf("bar output mismatch\nwant: %q\ngot:  %q", want, got)
+	}
+}
+
+func TestSpinner(t *testing.T) {
+	var b syncBuilder
+	s := cli.NewSpinner(&b, "wait", time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	s.Stop()
+	s.Stop()
+
+	got := b.String()
+	if !strings.HasPrefix(got, "\rwait |") || !strings.HasSuffix(got, "\r\033[K") {
+		t.Errorf("unexpected spinner output %q", got)
+	}
+}
+
+func TestSpinner_DefaultInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		var b syncBuilder
+		s := cli.NewSpinner(&b, "wait", interval)
+		s.Stop()
+
+		if got := b.String(); !strings.HasPrefix(got, "\rwait |") {
+			t.Errorf("NewSpinner(w, label, %v): unexpected output %q", interval, got)
+		}
+	}
+}
+
+func TestEnv_ProgressNotTerminal(t *testing.T) {
+	var b strings.Builder
+	env := cli.Env[any]{Err: &b}
+
+	bar := env.Bar("copy", 10)
+	bar.Add(5)
+	bar.Done()
+	env.Spinner("wait").Stop()
+
+	if got := b.String(); got != "" {
+		t.Errorf("want no output when not a terminal, got %q", got)
+	}
+}
+
+// syncBuilder is a strings.Builder that is safe for concurrent use.
+type syncBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuilder) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
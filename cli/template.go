@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"flag"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// TemplateData describes a command to its Usage, Help and Vars templates. The
+// templates are executed with the environment's metadata as dot, and can access
+// the command's data with the cmd function, as in {{cmd.Path}}.
+type TemplateData struct {
+	Name       string             // command name
+	Path       string             // names of the command's parents and the command, separated by spaces
+	Flags      []TemplateFlag     // flags, excluding deprecated ones
+	Commands   []string           // names of subcommands, excluding hidden and deprecated ones
+	Categories []TemplateCategory // Commands grouped by category, uncategorized commands first
+}
+
+// A TemplateCategory is a group of subcommands in [TemplateData].
+type TemplateCategory struct {
+	Name     string   // category name, empty for uncategorized commands
+	Commands []string // names of the subcommands in the category
+}
+
+// A TemplateFlag describes a flag in [TemplateData].
+type TemplateFlag struct {
+	Name    string   // flag name, without a leading dash
+	Aliases []string // flag aliases, without leading dashes
+	Type    string   // type name, empty for boolean flags
+	Usage   string   // usage text
+	Default string   // default value, empty if it is the zero value
+	Var     string   // environment variable, empty if none
+}
+
+// templateData returns the command's template data. Flags must already be
+// defined.
+func (c *execution[T, M]) templateData() TemplateData {
+	data := TemplateData{Name: c.Name, Path: c.path()}
+	c.flagSet().VisitAll(func(f *flag.Flag) {
+		if _, ok := c.DeprecatedFlags[f.Name]; ok {
+			return
+		}
+		if _, ok := c.Aliases[f.Name]; ok {
+			return
+		}
+		name, usage := unquoteUsage(f)
+		tf := TemplateFlag{Name: f.Name, Type: name, Usage: usage}
+		for alias, name := range c.Aliases {
+			if name == f.Name {
+				tf.Aliases = append(tf.Aliases, alias)
+			}
+		}
+		slices.Sort(tf.Aliases)
+		if !isZeroFlagValue(f) {
+			tf.Default = f.DefValue
+		}
+		tf.Var, _ = c.getFlagVar(f.Name)
+		data.Flags = append(data.Flags, tf)
+	})
+
+	categories := []TemplateCategory{{}}
+	add := func(category, name string) {
+		data.Commands = append(data.Commands, name)
+		i := slices.IndexFunc(categories, func(tc TemplateCategory) bool { return tc.Name == category })
+		if i < 0 {
+			i = len(categories)
+			categories = append(categories, TemplateCategory{Name: category})
+		}
+		categories[i].Commands = append(categories[i].Commands, name)
+	}
+	for _, sub := range c.Subcommands {
+		if !sub.Hidden && sub.Deprecated == "" {
+			add(sub.Category, sub.Name)
+		}
+	}
+	if c.hasVersionCommand() {
+		add("", "version")
+	}
+	if c.hasHelpCommand() {
+		add("", "help")
+	}
+	for _, tc := range categories {
+		if len(tc.Commands) > 0 {
+			data.Categories = append(data.Categories, tc)
+		}
+	}
+	return data
+}
+
+// funcs returns the template functions of the command's parents and the
+// command, with the command's taking precedence.
+func (c *execution[T, M]) funcs() template.FuncMap {
+	funcs := make(template.FuncMap)
+	if c.parent != nil {
+		funcs = c.parent.funcs()
+	}
+	maps.Copy(funcs, c.Funcs)
+	return funcs
+}
+
+// execTmpl executes the template s against env's metadata, with the command's
+// template functions.
+func (c *execution[T, M]) execTmpl(env *Env[M], s string) (string, error) {
+	funcs := c.funcs()
+	funcs["cmd"] = c.templateData
+	tmpl, err := template.New(c.Name).Funcs(funcs).Parse(s)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, env.Meta); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
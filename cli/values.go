@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// EnumVar defines a string flag with the specified name, default value, and
+// usage string that only accepts one of the allowed values. The argument p
+// points to a string variable in which to store the value of the flag.
+func EnumVar(flags *flag.FlagSet, p *string, name, value string, allowed []string, usage string) {
+	*p = value
+	flags.Var(&enumValue{p: p, allowed: allowed}, name, usage)
+}
+
+type enumValue struct {
+	p       *string
+	allowed []string
+}
+
+func (v *enumValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return *v.p
+}
+
+func (v *enumValue) Set(s string) error {
+	if !slices.Contains(v.allowed, s) {
+		return fmt.Errorf("must be one of %s", strings.Join(v.allowed, ", "))
+	}
+	*v.p = s
+	return nil
+}
+
+// StringsVar defines a repeatable string flag with the specified name, default
+// value, and usage string. Each use of the flag appends to the list, and a
+// comma-separated value appends each element, so the flag can also be set from
+// a single environment variable. The first use replaces the default value. The
+// argument p points to a []string variable in which to store the value of the
+// flag.
+func StringsVar(flags *flag.FlagSet, p *[]string, name string, value []string, usage string) {
+	*p = value
+	flags.Var(&stringsValue{p: p}, name, usage)
+}
+
+type stringsValue struct {
+	p   *[]string
+	set bool
+}
+
+func (v *stringsValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return strings.Join(*v.p, ",")
+}
+
+func (v *stringsValue) Set(s string) error {
+	if !v.set {
+		*v.p = nil
+		v.set = true
+	}
+	*v.p = append(*v.p, strings.Split(s, ",")...)
+	return nil
+}
+
+// DurationsVar defines a repeatable [time.Duration] flag with the specified
+// name, default value, and usage string. It accumulates values like
+// [StringsVar]. The argument p points to a []time.Duration variable in which
+// to store the value of the flag.
+func DurationsVar(flags *flag.FlagSet, p *[]time.Duration, name string, value []time.Duration, usage string) {
+	*p = value
+	flags.Var(&durationsValue{p: p}, name, usage)
+}
+
+type durationsValue struct {
+	p   *[]time.Duration
+	set bool
+}
+
+func (v *durationsValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	var s []string
+	for _, d := range *v.p {
+		s = append(s, d.String())
+	}
+	return strings.Join(s, ",")
+}
+
+func (v *durationsValue) Set(s string) error {
+	var ds []time.Duration
+	for _, field := range strings.Split(s, ",") {
+		d, err := time.ParseDuration(field)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", field)
+		}
+		ds = append(ds, d)
+	}
+	if !v.set {
+		*v.p = nil
+		v.set = true
+	}
+	*v.p = append(*v.p, ds...)
+	return nil
+}
+
+// MapVar defines a repeatable key=value flag with the specified name, default
+// value, and usage string. Each use of the flag adds one or more
+// comma-separated key=value pairs, and the first use replaces the default
+// value. The argument p points to a map[string]string variable in which to
+// store the value of the flag.
+func MapVar(flags *flag.FlagSet, p *map[string]string, name string, value map[string]string, usage string) {
+	*p = value
+	flags.Var(&mapValue{p: p}, name, usage)
+}
+
+type mapValue struct {
+	p   *map[string]string
+	set bool
+}
+
+func (v *mapValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	var pairs []string
+	for _, k := range slices.Sorted(maps.Keys(*v.p)) {
+		pairs = append(pairs, k+"="+(*v.p)[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (v *mapValue) Set(s string) error {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		k, val, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("invalid pair %q, want key=value", pair)
+		}
+		m[k] = val
+	}
+	if !v.set || *v.p == nil {
+		*v.p = make(map[string]string)
+		v.set = true
+	}
+	maps.Copy(*v.p, m)
+	return nil
+}
+
+// BytesVar defines a byte size flag with the specified name, default value,
+// and usage string. The flag accepts a number of bytes with an optional unit,
+// such as "512", "10KB" or "1.5GiB". Decimal units (KB, MB, GB, TB) are powers
+// of 1000, and binary units (KiB, MiB, GiB, TiB) are powers of 1024. The
+// argument p points to an int64 variable in which to store the value of the
+// flag.
+func BytesVar(flags *flag.FlagSet, p *int64, name string, value int64, usage string) {
+	*p = value
+	flags.Var((*bytesValue)(p), name, usage)
+}
+
+type bytesValue int64
+
+type byteUnit struct {
+	name string
+	size int64
+}
+
+var byteUnits = []byteUnit{
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"TB", 1e12},
+	{"GB", 1e9},
+	{"MB", 1e6},
+	{"KB", 1e3},
+	{"B", 1},
+}
+
+func (v *bytesValue) String() string {
+	if v == nil {
+		return "0"
+	}
+	n := int64(*v)
+	for _, unit := range byteUnits {
+		if n != 0 && n%unit.size == 0 {
+			return strconv.FormatInt(n/unit.size, 10) + unit.name
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func (v *bytesValue) Set(s string) error {
+	num := strings.TrimRightFunc(s, unicode.IsLetter)
+	unit := strings.TrimSpace(s[len(num):])
+	num = strings.TrimSpace(num)
+
+	size := int64(1)
+	if unit != "" {
+		i := slices.IndexFunc(byteUnits, func(u byteUnit) bool {
+			return strings.EqualFold(u.name, unit)
+		})
+		if i < 0 {
+			return fmt.Errorf("invalid byte size %q", s)
+		}
+		size = byteUnits[i].size
+	}
+
+	if n, err := strconv.ParseInt(num, 10, 64); err == nil && n >= 0 {
+		if n > math.MaxInt64/size {
+			return fmt.Errorf("byte size %q out of range", s)
+		}
+		*v = bytesValue(n * size)
+		return nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 {
+		return fmt.Errorf("invalid byte size %q", s)
+	}
+	if f*float64(size) >= math.MaxInt64 {
+		return fmt.Errorf("byte size %q out of range", s)
+	}
+	*v = bytesValue(f * float64(size))
+	return nil
+}
@@ -0,0 +1,149 @@
+package cli_test
+
+import (
+	"context"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/x/cli"
+)
+
+type valuesTarget struct {
+	mode      string
+	tags      []string
+	intervals []time.Duration
+	labels    map[string]string
+	size      int64
+}
+
+func valuesCommand() *cli.Command[*valuesTarget, any] {
+	return &cli.Command[*valuesTarget, any]{
+		Name: "tool",
+		Flags: func(flags *flag.FlagSet, target *valuesTarget) {
+			cli.EnumVar(flags, &target.mode, "mode", "fast", []string{"fast", "slow"}, "")
+			cli.StringsVar(flags, &target.tags, "tag", []string{"default"}, "")
+			cli.DurationsVar(flags, &target.intervals, "interval", nil, "")
+			cli.MapVar(flags, &target.labels, "label", nil, "")
+			cli.BytesVar(flags, &target.size, "size", 1<<20, "")
+		},
+		Vars: map[string]string{
+			"tag":  "TOOL_TAGS",
+			"size": "TOOL_SIZE",
+		},
+		Action: func(ctx context.Context, env *cli.Env[any], target *valuesTarget) cli.ExitStatus {
+			return cli.ExitSuccess
+		},
+	}
+}
+
+func TestValues(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		vars map[string]string
+		want valuesTarget
+	}{
+		{
+			name: "defaults",
+			want: valuesTarget{mode: "fast", tags: []string{"default"}, size: 1 << 20},
+		},
+		{
+			name: "flags",
+			args: []string{"-mode=slow", "-tag=a", "-tag=b,c", "-interval=1s,2m", "-label=k=v", "-label=x=y", "-size=1.5KB"},
+			want: valuesTarget{
+				mode:      "slow",
+				tags:      []string{"a", "b", "c"},
+				intervals: []time.Duration{time.Second, 2 * time.Minute},
+				labels:    map[string]string{"k": "v", "x": "y"},
+				size:      1500,
+			},
+		},
+		{
+			name: "vars",
+			vars: map[string]string{"TOOL_TAGS": "x,y", "TOOL_SIZE": "2GiB"},
+			want: valuesTarget{mode: "fast", tags: []string{"x", "y"}, size: 2 << 30},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := &cli.Env[any]{Out: io.Discard, Err: io.Discard, Args: append([]string{"tool"}, tt.args...), Vars: tt.vars}
+			var got valuesTarget
+			if status := valuesCommand().Execute(context.Background(), env, &got); status != cli.ExitSuccess {
+				t.Fatalf("cmd.Execute()=%v, want %v", status, cli.ExitSuccess)
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(valuesTarget{})); diff != "" {
+				t.Errorf("target mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValues_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		vars    map[string]string
+		wantErr string
+	}{
+		{
+			name:    "enum",
+			args:    []string{"-mode=medium"},
+			wantErr: `invalid value "medium" for flag -mode: must be one of fast, slow`,
+		},
+		{
+			name:    "duration",
+			args:    []string{"-interval=1s,soon"},
+			wantErr: `invalid duration "soon"`,
+		},
+		{
+			name:    "map",
+			args:    []string{"-label=k"},
+			wantErr: `invalid pair "k", want key=value`,
+		},
+		{
+			name:    "bytes_var",
+			vars:    map[string]string{"TOOL_SIZE": "10XB"},
+			wantErr: `invalid value "10XB" for var $TOOL_SIZE: invalid byte size "10XB"`,
+		},
+		{
+			name:    "bytes_overflow",
+			args:    []string{"-size=9000000TiB"},
+			wantErr: `byte size "9000000TiB" out of range`,
+		},
+		{
+			name:    "bytes_float_overflow",
+			args:    []string{"-size=9223372036854775808"},
+			wantErr: `byte size "9223372036854775808" out of range`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errbuf strings.Builder
+			env := &cli.Env[any]{Out: io.Discard, Err: &errbuf, Args: append([]string{"tool"}, tt.args...), Vars: tt.vars}
+			if status := valuesCommand().Execute(context.Background(), env, &valuesTarget{}); status != cli.ExitUsage {
+				t.Fatalf("cmd.Execute()=%v, want %v", status, cli.ExitUsage)
+			}
+			if got := errbuf.String(); !strings.Contains(got, tt.wantErr) {
+				t.Errorf("cmd.Execute() wrote error=%q, want contains %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValues_Help(t *testing.T) {
+	var outbuf strings.Builder
+	env := &cli.Env[any]{Out: &outbuf, Err: io.Discard, Args: []string{"tool", "-h"}}
+	valuesCommand().Execute(context.Background(), env, &valuesTarget{})
+
+	for _, want := range []string{`(default "fast")`, `(default "default")`, `(default "1MiB")`} {
+		if got := outbuf.String(); !strings.Contains(got, want) {
+			t.Errorf("help %q does not contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package cli
+
+import "errors"
+
+// SkipSubcommands can be returned by a [Command.Walk] callback to skip the
+// subcommands of the command it was called for.
+var SkipSubcommands = errors.New("skip subcommands")
+
+// Walk calls fn for the command and each of its subcommands, depth first in
+// the order they are defined. The path passed to fn holds the names of the
+// command's parents and the command, starting with c.Name.
+//
+// If fn returns [SkipSubcommands], Walk skips the command's subcommands. If fn
+// returns any other error, Walk stops and returns it.
+func (c *Command[T, M]) Walk(fn func(path []string, cmd *Command[T, M]) error) error {
+	err := c.walk(nil, fn)
+	if errors.Is(err, SkipSubcommands) {
+		return nil
+	}
+	return err
+}
+
+func (c *Command[T, M]) walk(parents []string, fn func(path []string, cmd *Command[T, M]) error) error {
+	path := append(parents[:len(parents):len(parents)], c.Name)
+	if err := fn(path, c); err != nil {
+		return err
+	}
+	for _, sub := range c.Subcommands {
+		if err := sub.walk(path, fn); err != nil && !errors.Is(err, SkipSubcommands) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find returns the subcommand at path, the names of nested subcommands
+// relative to the command, or nil if there is none. Find with no path returns
+// the command itself.
+func (c *Command[T, M]) Find(path ...string) *Command[T, M] {
+	cmd := c
+	for _, name := range path {
+		if cmd = cmd.findSubcommand(name); cmd == nil {
+			return nil
+		}
+	}
+	return cmd
+}
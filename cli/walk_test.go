@@ -0,0 +1,86 @@
+package cli_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jonathonwebb/x/cli"
+)
+
+func walkTree() *cli.Command[any, any] {
+	return &cli.Command[any, any]{
+		Name: "root",
+		Subcommands: []*cli.Command[any, any]{
+			{
+				Name: "db",
+				Subcommands: []*cli.Command[any, any]{
+					{Name: "migrate"},
+					{Name: "seed"},
+				},
+			},
+			{
+				Name:        "admin",
+				Subcommands: []*cli.Command[any, any]{{Name: "users"}},
+			},
+		},
+	}
+}
+
+func TestCommand_Walk(t *testing.T) {
+	var visited []string
+	err := walkTree().Walk(func(path []string, cmd *cli.Command[any, any]) error {
+		visited = append(visited, strings.Join(path, " "))
+		if cmd.Name == "admin" {
+			return cli.SkipSubcommands
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk()=%v, want nil", err)
+	}
+	want := []string{"root", "root db", "root db migrate", "root db seed", "root admin"}
+	if !slices.Equal(visited, want) {
+		t.Errorf("visited mismatch\nwant: %v\ngot:  %v", want, visited)
+	}
+
+	stop := errors.New("stop")
+	visited = nil
+	err = walkTree().Walk(func(path []string, cmd *cli.Command[any, any]) error {
+		visited = append(visited, cmd.Name)
+		if cmd.Name == "migrate" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Walk()=%v, want %v", err, stop)
+	}
+	if want := []string{"root", "db", "migrate"}; !slices.Equal(visited, want) {
+		t.Errorf("visited mismatch\nwant: %v\ngot:  %v", want, visited)
+	}
+}
+
+func TestCommand_Find(t *testing.T) {
+	root := walkTree()
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{path: nil, want: "root"},
+		{path: []string{"db", "seed"}, want: "seed"},
+		{path: []string{"db", "users"}},
+		{path: []string{"nope"}},
+	}
+	for _, tt := range tests {
+		cmd := root.Find(tt.path...)
+		var got string
+		if cmd != nil {
+			got = cmd.Name
+		}
+		if got != tt.want {
+			t.Errorf("Find(%q)=%q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
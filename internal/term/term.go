@@ -0,0 +1,49 @@
+// Package term decides whether ANSI colors are written to an output stream,
+// for the cli and pretty packages.
+package term
+
+import (
+	"io"
+	"os"
+)
+
+// ANSI escape sequences for the colors shared by the cli and pretty packages.
+const (
+	Reset  = "\033[0m"
+	Red    = "\033[31m"
+	Green  = "\033[32m"
+	Yellow = "\033[33m"
+)
+
+// ColorEnabled reports whether colors should be written to w, looking up
+// environment variables with getenv. Colors are disabled if the NO_COLOR
+// variable is set and not empty, forced if CLICOLOR_FORCE is, and otherwise
+// enabled only for terminals that can render them.
+func ColorEnabled(w io.Writer, getenv func(string) string) bool {
+	if getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := getenv("CLICOLOR_FORCE"); force != "" && force != "0" {
+		EnableColor(w)
+		return true
+	}
+	return EnableColor(w)
+}
+
+// EnableColor prepares w to render colors if it is a terminal, reporting
+// whether it can.
+func EnableColor(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && isTerminal(f) && enableVirtualTerminal(f)
+}
+
+// IsTerminal reports whether w is a terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && isTerminal(f)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package term
+
+import "os"
+
+// enableVirtualTerminal reports whether the terminal f can render ANSI escape
+// sequences, which all terminals outside of Windows are assumed to.
+func enableVirtualTerminal(*os.File) bool {
+	return true
+}
@@ -0,0 +1,29 @@
+package term
+
+import (
+	"os"
+	"syscall"
+)
+
+const enableVirtualTerminalProcessing = 0x0004
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// enableVirtualTerminal enables virtual terminal processing for the console
+// f, so that it renders ANSI escape sequences, and reports whether it does.
+// Consoles that predate virtual terminal processing cannot enable it.
+func enableVirtualTerminal(f *os.File) bool {
+	h := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(h, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	if err := setConsoleMode.Find(); err != nil {
+		return false
+	}
+	ok, _, _ := setConsoleMode.Call(uintptr(h), uintptr(mode|enableVirtualTerminalProcessing))
+	return ok != 0
+}
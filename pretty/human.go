@@ -0,0 +1,58 @@
+package pretty
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// humanDuration formats d with about three significant digits, such as "1.23s"
+// or "350ms". Durations of a minute or more are rounded to the second.
+func humanDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + humanDuration(-d)
+	}
+	switch {
+	case d >= time.Minute:
+		return d.Round(time.Second).String()
+	case d >= time.Second:
+		return formatUnit(float64(d)/float64(time.Second), "s")
+	case d >= time.Millisecond:
+		return formatUnit(float64(d)/float64(time.Millisecond), "ms")
+	case d >= time.Microsecond:
+		return formatUnit(float64(d)/float64(time.Microsecond), "µs")
+	default:
+		return strconv.FormatInt(int64(d), 10) + "ns"
+	}
+}
+
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// humanBytes formats n bytes with binary units, such as "512B" or "1.5MiB".
+func humanBytes(n float64) string {
+	if n < 0 {
+		return "-" + humanBytes(-n)
+	}
+	i := 0
+	for n >= 1024 && i < len(byteUnits)-1 {
+		n /= 1024
+		i++
+	}
+	return formatUnit(n, byteUnits[i])
+}
+
+// formatUnit formats v with about three significant digits followed by unit.
+func formatUnit(v float64, unit string) string {
+	prec := 0
+	switch {
+	case v < 10:
+		prec = 2
+	case v < 100:
+		prec = 1
+	}
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s + unit
+}
@@ -0,0 +1,54 @@
+package pretty_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonathonwebb/x/pretty"
+)
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestMulti(t *testing.T) {
+	var prettyBuf, jsonBuf bytes.Buffer
+	h := pretty.Multi(
+		pretty.NewHandler(&prettyBuf, &slog.HandlerOptions{Level: slog.LevelWarn}, pretty.WithTimeFormat("")),
+		slog.NewJSONHandler(&jsonBuf, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	)
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("want enabled for debug")
+	}
+
+	logger := slog.New(h).With("app", "x").WithGroup("req")
+	logger.Debug("debug", "id", 1)
+	logger.Warn("warn", "id", 2)
+
+	if got := prettyBuf.String(); strings.Contains(got, "debug") || !strings.Contains(got, "WARN: warn") || !strings.Contains(got, `"app": "x"`) {
+		t.Errorf("unexpected pretty output %q", got)
+	}
+	if got := jsonBuf.String(); strings.Count(got, "\n") != 2 || !strings.Contains(got, `"req":{"id":2}`) {
+		t.Errorf("unexpected json output %q", got)
+	}
+}
+
+func TestMulti_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	h := pretty.Multi(
+		slog.NewTextHandler(errWriter{}, nil),
+		slog.NewTextHandler(&buf, nil),
+	)
+	err := h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "hello", 0))
+	if err == nil || !strings.Contains(err.Error(), "write failed") {
+		t.Errorf("want write error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "msg=hello") {
+		t.Errorf("want record written to the remaining handler, got %q", buf.String())
+	}
+}
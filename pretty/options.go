@@ -0,0 +1,152 @@
+package pretty
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Option configures a PrettyHandler.
+type Option func(*options)
+
+type options struct {
+	color      *bool
+	timeFormat string
+	utc        bool
+	maxDepth   int
+	multiline  bool
+	redact     []string
+	writers    []levelWriter
+	flatten    bool
+
+	humanDurations bool
+	byteKeys       []string
+	contextAttrs   func(context.Context) []slog.Attr
+	maxValueLen    int
+	maxAttrs       int
+}
+
+type levelWriter struct {
+	level slog.Leveler
+	w     io.Writer
+}
+
+// WithColor enables or disables ANSI colors regardless of the NO_COLOR and
+// CLICOLOR_FORCE variables or whether the writer is a terminal.
+func WithColor(enabled bool) Option {
+	return func(o *options) {
+		o.color = &enabled
+	}
+}
+
+// WithTimeFormat sets the layout used to render record timestamps, as accepted
+// by [time.Time.Format]. The default layout is "15:04:05.000", and an empty
+// layout omits the timestamp.
+func WithTimeFormat(layout string) Option {
+	return func(o *options) {
+		o.timeFormat = layout
+	}
+}
+
+// WithUTC renders record timestamps and time values in UTC instead of local
+// time.
+func WithUTC() Option {
+	return func(o *options) {
+		o.utc = true
+	}
+}
+
+// WithMaxDepth limits how deeply maps, slices and structs are rendered. Nested
+// values beyond the limit are elided as {...} or [...]. A limit of 0, the
+// default, renders values in full.
+func WithMaxDepth(depth int) Option {
+	return func(o *options) {
+		o.maxDepth = depth
+	}
+}
+
+// WithMultiline renders string values that contain newlines as indented blocks
+// under their key, instead of as a single escaped string.
+func WithMultiline() Option {
+	return func(o *options) {
+		o.multiline = true
+	}
+}
+
+// WithRedact replaces the values of attrs whose keys match one of the patterns
+// with "[REDACTED]", at any depth within groups and structured values.
+// Patterns use the syntax of [path.Match] and are matched case-insensitively,
+// so "password" and "*token*" cover keys such as Password and access_token.
+func WithRedact(patterns ...string) Option {
+	return func(o *options) {
+		for _, p := range patterns {
+			o.redact = append(o.redact, strings.ToLower(p))
+		}
+	}
+}
+
+// WithLevelWriter writes records at or above level to w instead of the
+// handler's writer, such as to send warnings and errors to stderr. If several
+// level writers apply to a record, the one with the highest level is used.
+// Colors are detected for w separately.
+func WithLevelWriter(level slog.Leveler, w io.Writer) Option {
+	return func(o *options) {
+		o.writers = append(o.writers, levelWriter{level: level, w: w})
+	}
+}
+
+// WithFlatten renders the attrs of groups with dotted keys, such as
+// "req.user.id", instead of as nested objects.
+func WithFlatten() Option {
+	return func(o *options) {
+		o.flatten = true
+	}
+}
+
+// WithHumanDurations renders duration values with about three significant
+// digits, such as 1.23s or 350ms, instead of at full precision.
+func WithHumanDurations() Option {
+	return func(o *options) {
+		o.humanDurations = true
+	}
+}
+
+// WithHumanBytes renders numeric values whose keys match one of the patterns
+// as byte sizes with binary units, such as 1.5MiB. Patterns are matched like
+// those of [WithRedact], and default to "*bytes" and "*size".
+func WithHumanBytes(patterns ...string) Option {
+	if len(patterns) == 0 {
+		patterns = []string{"*bytes", "*size"}
+	}
+	return func(o *options) {
+		for _, p := range patterns {
+			o.byteKeys = append(o.byteKeys, strings.ToLower(p))
+		}
+	}
+}
+
+// WithContextAttrs calls fn with the context of each record and renders the
+// attrs it returns before the others, outside of any groups. It can be used to
+// add request-scoped values, such as trace IDs, to every record.
+func WithContextAttrs(fn func(ctx context.Context) []slog.Attr) Option {
+	return func(o *options) {
+		o.contextAttrs = fn
+	}
+}
+
+// WithMaxValueLen truncates string values longer than n characters, marking
+// them with an ellipsis. A limit of 0, the default, renders values in full.
+func WithMaxValueLen(n int) Option {
+	return func(o *options) {
+		o.maxValueLen = n
+	}
+}
+
+// WithMaxAttrs renders at most n attrs of each record, followed by a note of
+// how many were omitted. A limit of 0, the default, renders all attrs.
+func WithMaxAttrs(n int) Option {
+	return func(o *options) {
+		o.maxAttrs = n
+	}
+}
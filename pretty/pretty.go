@@ -1,13 +1,24 @@
 package pretty
 
 import (
+	"bytes"
 	"context"
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
+	"path"
+	"reflect"
 	"runtime"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jonathonwebb/x/internal/term"
 )
 
 type groupOrAttrs struct {
@@ -16,29 +27,79 @@ type groupOrAttrs struct {
 }
 
 type PrettyHandler struct {
-	opts slog.HandlerOptions
-	goas []groupOrAttrs
-	mu   *sync.Mutex
-	w    io.Writer
+	opts    slog.HandlerOptions
+	o       options
+	color   bool
+	goas    []groupOrAttrs
+	mu      *sync.Mutex
+	w       io.Writer
+	writers []output
+}
+
+// output is a writer for records at or above a level.
+type output struct {
+	level slog.Leveler
+	w     io.Writer
+	color bool
 }
 
-func NewHandler(w io.Writer, opts *slog.HandlerOptions) *PrettyHandler {
-	h := &PrettyHandler{w: w, mu: &sync.Mutex{}}
+// NewHandler returns a PrettyHandler that writes to w. Colors are enabled
+// unless the NO_COLOR variable is set or w is not a terminal that can render
+// them, such as an older Windows console; use [WithColor] to override this.
+// Records can be routed to other writers by level with [WithLevelWriter].
+//
+// Error values are rendered with the errors they wrap, as returned by an
+// Unwrap() error or Unwrap() []error method, and with the stack frames of
+// errors that have a StackTrace() []uintptr method.
+func NewHandler(w io.Writer, opts *slog.HandlerOptions, with ...Option) *PrettyHandler {
+	o := options{timeFormat: "15:04:05.000"}
+	for _, opt := range with {
+		opt(&o)
+	}
+
+	h := &PrettyHandler{o: o, w: w, mu: &sync.Mutex{}}
 	if opts != nil {
 		h.opts = *opts
 	}
 	if h.opts.Level == nil {
 		h.opts.Level = slog.LevelInfo
 	}
+	h.color = h.colorEnabled(w)
+	for _, lw := range h.o.writers {
+		h.writers = append(h.writers, output{level: lw.level, w: lw.w, color: h.colorEnabled(lw.w)})
+	}
 	return h
 }
 
+func (h *PrettyHandler) colorEnabled(w io.Writer) bool {
+	if h.o.color != nil {
+		if *h.o.color {
+			term.EnableColor(w)
+		}
+		return *h.o.color
+	}
+	return term.ColorEnabled(w, os.Getenv)
+}
+
+// output returns the writer for records at level l.
+func (h *PrettyHandler) output(l slog.Level) output {
+	out := output{w: h.w, color: h.color}
+	var best slog.Level
+	found := false
+	for _, o := range h.writers {
+		if lvl := o.level.Level(); l >= lvl && (!found || lvl >= best) {
+			out, best, found = o, lvl, true
+		}
+	}
+	return out
+}
+
 func (h *PrettyHandler) Enabled(_ context.Context, l slog.Level) bool {
 	return l >= h.opts.Level.Level()
 }
 
 const (
-	ColorReset  = "\033[0m"
+	ColorReset  = term.Reset
 	ColorMuted  = "\033[90m"
 	ColorBase   = "\033[0m"
 	ColorKey    = "\033[0m"
@@ -49,159 +110,513 @@ const (
 
 	ColorDebug = "\033[37m"
 	ColorInfo  = "\033[94m"
-	ColorWarn  = "\033[33m"
-	ColorError = "\033[31m"
+	ColorWarn  = term.Yellow
+	ColorError = term.Red
 )
 
-func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
+func (h *PrettyHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := h.output(r.Level)
+	if out.color != h.color {
+		h2 := *h
+		h2.color = out.color
+		h = &h2
+	}
+
 	buf := make([]byte, 0, 1024)
-	if !r.Time.IsZero() {
-		buf = fmt.Appendf(buf, "%s[%s]%s", ColorMuted, r.Time.Format("15:04:05.000"), ColorReset)
-	}
-
-	switch r.Level {
-	case slog.LevelDebug:
-		buf = fmt.Appendf(buf, " %s%s%s:", ColorDebug, r.Level, ColorMuted)
-	case slog.LevelInfo:
-		buf = fmt.Appendf(buf, " %s%s%s:", ColorInfo, r.Level, ColorMuted)
-	case slog.LevelWarn:
-		buf = fmt.Appendf(buf, " %s%s%s:", ColorWarn, r.Level, ColorMuted)
-	case slog.LevelError:
-		buf = fmt.Appendf(buf, " %s%s%s:", ColorError, r.Level, ColorMuted)
-	default:
-		buf = fmt.Appendf(buf, " %s%s:", r.Level, ColorMuted)
+	if !r.Time.IsZero() && h.o.timeFormat != "" {
+		if a, ok := h.replace(slog.Time(slog.TimeKey, r.Time)); ok {
+			if a.Value.Kind() == slog.KindTime {
+				buf = fmt.Appendf(buf, "%s[%s]%s", h.c(ColorMuted), h.time(a.Value.Time()).Format(h.o.timeFormat), h.c(ColorReset))
+			} else {
+				buf = fmt.Appendf(buf, "%s[%s]%s", h.c(ColorMuted), a.Value, h.c(ColorReset))
+			}
+		}
 	}
 
-	buf = fmt.Appendf(buf, " %s%s%s", ColorBase, r.Message, ColorMuted)
+	if a, ok := h.replace(slog.Any(slog.LevelKey, r.Level)); ok {
+		if len(buf) > 0 {
+			buf = append(buf, ' ')
+		}
+		level, isLevel := a.Value.Any().(slog.Level)
+		switch {
+		case !isLevel:
+			buf = fmt.Appendf(buf, "%s%s:", a.Value, h.c(ColorMuted))
+		case level == slog.LevelDebug:
+			buf = fmt.Appendf(buf, "%s%s%s:", h.c(ColorDebug), level, h.c(ColorMuted))
+		case level == slog.LevelInfo:
+			buf = fmt.Appendf(buf, "%s%s%s:", h.c(ColorInfo), level, h.c(ColorMuted))
+		case level == slog.LevelWarn:
+			buf = fmt.Appendf(buf, "%s%s%s:", h.c(ColorWarn), level, h.c(ColorMuted))
+		case level == slog.LevelError:
+			buf = fmt.Appendf(buf, "%s%s%s:", h.c(ColorError), level, h.c(ColorMuted))
+		default:
+			buf = fmt.Appendf(buf, "%s%s:", level, h.c(ColorMuted))
+		}
+	}
+
+	if a, ok := h.replace(slog.String(slog.MessageKey, r.Message)); ok {
+		buf = fmt.Appendf(buf, " %s%s%s", h.c(ColorBase), a.Value, h.c(ColorMuted))
+	}
 	if r.PC != 0 {
 		fs := runtime.CallersFrames([]uintptr{r.PC})
 		f, _ := fs.Next()
-		buf = fmt.Appendf(buf, " %s:%d", f.File, f.Line)
+		src := &slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+		if a, ok := h.replace(slog.Any(slog.SourceKey, src)); ok {
+			if src, isSource := a.Value.Any().(*slog.Source); isSource {
+				buf = fmt.Appendf(buf, " %s:%d", src.File, src.Line)
+			} else {
+				buf = fmt.Appendf(buf, " %s", a.Value)
+			}
+		}
 	}
 
 	goas := h.goas
-	if r.NumAttrs() == 0 {
-		// If the record has no attrs, remove groups at the end of the list
-		// (since they're empty)
-		for len(goas) > 0 && goas[len(goas)-1].group != "" {
-			goas = goas[:len(goas)-1]
+	if h.o.contextAttrs != nil {
+		if attrs := h.o.contextAttrs(ctx); len(attrs) > 0 {
+			goas = append([]groupOrAttrs{{attrs: attrs}}, goas...)
 		}
 	}
 
-	if len(goas)+r.NumAttrs() > 0 {
-		buf = fmt.Append(buf, " {")
-
-		indentLevel := 1
-		firstProp := true
-		for _, goa := range goas {
-			if goa.group != "" {
+	start := len(buf)
+	buf = fmt.Append(buf, " {")
+	indentLevel := 1
+	firstProp := true
+	var groups []string
+	// opened holds the offsets and state around each group header, so that
+	// groups left without attrs can be removed.
+	type openGroup struct {
+		start, end int
+		firstProp  bool
+	}
+	var opened []openGroup
+	var count attrCount
+	for _, goa := range goas {
+		if goa.group != "" {
+			groups = append(groups, goa.group)
+			if !h.o.flatten {
+				g := openGroup{start: len(buf), firstProp: firstProp}
 				if !firstProp {
 					buf = fmt.Append(buf, ",")
 				}
-				buf = fmt.Appendf(buf, "\n%*s%s%q%s: {", indentLevel*2, "", ColorKey, goa.group, ColorMuted)
+				buf = fmt.Appendf(buf, "\n%*s%s%q%s: {", indentLevel*2, "", h.c(ColorKey), goa.group, h.c(ColorMuted))
+				g.end = len(buf)
+				opened = append(opened, g)
 				indentLevel++
 				firstProp = true
-				for _, a := range goa.attrs {
-					buf, firstProp = h.appendAttr(buf, a, indentLevel, firstProp)
-				}
-			} else {
-				for _, a := range goa.attrs {
-					buf, firstProp = h.appendAttr(buf, a, indentLevel, firstProp)
-				}
 			}
 		}
-		r.Attrs(func(a slog.Attr) bool {
-			buf, firstProp = h.appendAttr(buf, a, indentLevel, firstProp)
-			return true
-		})
+		for _, a := range goa.attrs {
+			buf, firstProp = h.appendAttr(buf, a, groups, indentLevel, firstProp, &count)
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		buf, firstProp = h.appendAttr(buf, a, groups, indentLevel, firstProp, &count)
+		return true
+	})
 
-		for indentLevel > 0 {
-			indentLevel--
-			buf = fmt.Appendf(buf, "\n%*s}", indentLevel*2, "")
+	for i := len(opened) - 1; i >= 0; i-- {
+		indentLevel--
+		if len(buf) == opened[i].end {
+			buf, firstProp = buf[:opened[i].start], opened[i].firstProp
+		} else {
+			buf, firstProp = fmt.Appendf(buf, "\n%*s}", indentLevel*2, ""), false
 		}
 	}
+	if count.omitted > 0 {
+		if !firstProp {
+			buf = fmt.Append(buf, ",")
+		}
+		buf = fmt.Appendf(buf, "\n  ... %d more attrs", count.omitted)
+	}
+	if len(buf) == start+len(" {") {
+		buf = buf[:start]
+	} else {
+		buf = fmt.Append(buf, "\n}")
+	}
 
-	buf = fmt.Appendf(buf, "%s\n", ColorReset)
+	buf = fmt.Appendf(buf, "%s\n", h.c(ColorReset))
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	_, err := h.w.Write(buf)
+	_, err := out.w.Write(buf)
 	return err
 }
 
-func (h *PrettyHandler) appendAttr(buf []byte, a slog.Attr, indentLevel int, firstProp bool) ([]byte, bool) {
+// c returns the color code if colors are enabled.
+func (h *PrettyHandler) c(color string) string {
+	if !h.color {
+		return ""
+	}
+	return color
+}
+
+// time returns t in UTC if the UTC option is set.
+func (h *PrettyHandler) time(t time.Time) time.Time {
+	if h.o.utc {
+		return t.UTC()
+	}
+	return t
+}
+
+// replace applies the ReplaceAttr option to a built-in attr, reporting whether
+// the attr should still be rendered.
+func (h *PrettyHandler) replace(a slog.Attr) (slog.Attr, bool) {
+	if h.opts.ReplaceAttr == nil {
+		return a, true
+	}
+	a = h.opts.ReplaceAttr(nil, a)
 	a.Value = a.Value.Resolve()
+	return a, !a.Equal(slog.Attr{})
+}
+
+// attrCount counts the attrs of a record against the max attrs option.
+type attrCount struct {
+	rendered, omitted int
+}
+
+func (h *PrettyHandler) appendAttr(buf []byte, a slog.Attr, groups []string, indentLevel int, firstProp bool, count *attrCount) ([]byte, bool) {
+	a.Value = a.Value.Resolve()
+	if rep := h.opts.ReplaceAttr; rep != nil && a.Value.Kind() != slog.KindGroup {
+		a = rep(groups, a)
+		a.Value = a.Value.Resolve()
+	}
 	if a.Equal(slog.Attr{}) {
 		return buf, firstProp
 	}
 
+	if a.Value.Kind() == slog.KindGroup && !h.redacted(a.Key) {
+		nestedGroups := groups
+		if a.Key != "" {
+			nestedGroups = append(slices.Clip(groups), a.Key)
+		}
+		// Groups without a key are inlined, like with flattened keys.
+		if a.Key == "" || h.o.flatten {
+			for _, ga := range a.Value.Group() {
+				buf, firstProp = h.appendAttr(buf, ga, nestedGroups, indentLevel, firstProp, count)
+			}
+			return buf, firstProp
+		}
+
+		start := len(buf)
+		if !firstProp {
+			buf = fmt.Append(buf, ",")
+		}
+		buf = fmt.Appendf(buf, "\n%*s%s%q%s: {", indentLevel*2, "", h.c(ColorKey), a.Key, h.c(ColorMuted))
+		end := len(buf)
+		nestedFirstProp := true
+		for _, ga := range a.Value.Group() {
+			buf, nestedFirstProp = h.appendAttr(buf, ga, nestedGroups, indentLevel+1, nestedFirstProp, count)
+		}
+		if len(buf) == end {
+			// Groups without attrs are elided.
+			return buf[:start], firstProp
+		}
+		return fmt.Appendf(buf, "\n%*s}", indentLevel*2, ""), false
+	}
+
+	if h.o.maxAttrs > 0 && count.rendered >= h.o.maxAttrs {
+		count.omitted++
+		return buf, firstProp
+	}
+	count.rendered++
+
+	key := a.Key
+	if h.o.flatten {
+		key = strings.Join(append(slices.Clip(groups), a.Key), ".")
+	}
+
 	if !firstProp {
 		buf = fmt.Append(buf, ",")
 	}
-	buf = fmt.Appendf(buf, "\n%*s%s%q%s: ", indentLevel*2, "", ColorKey, a.Key, ColorMuted)
+	buf = fmt.Appendf(buf, "\n%*s%s%q%s: ", indentLevel*2, "", h.c(ColorKey), key, h.c(ColorMuted))
+	if h.redacted(a.Key) {
+		return h.appendRedacted(buf), false
+	}
+
+	if err, ok := a.Value.Any().(error); ok && a.Value.Kind() == slog.KindAny {
+		return h.appendError(buf, err, indentLevel), false
+	}
+
+	if h.o.multiline && a.Value.Kind() == slog.KindString && strings.Contains(a.Value.String(), "\n") {
+		// The block has no closing delimiter, so the next attr is not
+		// preceded by a comma that would read as part of the block.
+		return h.appendBlock(buf, h.truncate(a.Value.String()), indentLevel), true
+	}
+
+	if n, ok := h.byteCount(a); ok {
+		return fmt.Appendf(buf, "%s%s%s", h.c(ColorNumber), encodeString(humanBytes(n)), h.c(ColorMuted)), false
+	}
 
+	var val any
 	switch a.Value.Kind() {
-	case slog.KindGroup:
-		attrs := a.Value.Group()
-		if len(attrs) == 0 {
-			buf = fmt.Append(buf, "{}")
-			return buf, false
+	case slog.KindString:
+		buf = fmt.Append(buf, h.c(ColorString))
+		val = h.truncate(a.Value.String())
+	case slog.KindInt64:
+		buf = fmt.Append(buf, h.c(ColorNumber))
+		val = a.Value.Int64()
+	case slog.KindUint64:
+		buf = fmt.Append(buf, h.c(ColorNumber))
+		val = a.Value.Uint64()
+	case slog.KindFloat64:
+		buf = fmt.Append(buf, h.c(ColorNumber))
+		val = a.Value.Float64()
+	case slog.KindBool:
+		buf = fmt.Append(buf, h.c(ColorBool))
+		val = a.Value.Bool()
+	case slog.KindDuration:
+		buf = fmt.Append(buf, h.c(ColorString))
+		if h.o.humanDurations {
+			val = humanDuration(a.Value.Duration())
+		} else {
+			val = a.Value.Duration().String()
+		}
+	case slog.KindTime:
+		buf = fmt.Append(buf, h.c(ColorString))
+		val = h.time(a.Value.Time()).Format("2006-01-02T15:04:05.000Z07:00")
+	case slog.KindAny:
+		if data, ok := marshalStructured(a.Value.Any()); ok {
+			return h.appendJSON(buf, data, indentLevel, 1), false
 		}
+		if a.Value.Any() == nil {
+			buf = fmt.Append(buf, h.c(ColorNull))
+			val = a.Value.Any()
+		} else {
+			buf = fmt.Append(buf, h.c(ColorString))
+			val = h.truncate(a.Value.String())
+		}
+	default:
+		buf = fmt.Append(buf, h.c(ColorString))
+		val = h.truncate(a.Value.String())
+	}
 
-		buf = fmt.Append(buf, "{")
-		nestedFirstProp := true
-		nestedIndentLevel := indentLevel + 1
-		for _, ga := range attrs {
-			buf, nestedFirstProp = h.appendAttr(buf, ga, nestedIndentLevel, nestedFirstProp)
+	encodedVal, err := json.Marshal(val)
+	if err != nil {
+		encodedVal = fmt.Appendf(nil, "%q", fmt.Sprintf("<error marshalling: %v>", err))
+	}
+	buf = fmt.Appendf(buf, "%s%s", encodedVal, h.c(ColorMuted))
+	return buf, false
+}
+
+// redacted reports whether the value of key should be redacted.
+func (h *PrettyHandler) redacted(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range h.o.redact {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
 		}
-		buf = fmt.Appendf(buf, "\n%*s}", indentLevel*2, "")
-		return buf, false
+	}
+	return false
+}
 
+func (h *PrettyHandler) appendRedacted(buf []byte) []byte {
+	return fmt.Appendf(buf, "%s\"[REDACTED]\"%s", h.c(ColorString), h.c(ColorMuted))
+}
+
+// byteCount returns the value of a as a number of bytes if its key matches one
+// of the byte key patterns.
+func (h *PrettyHandler) byteCount(a slog.Attr) (float64, bool) {
+	if len(h.o.byteKeys) == 0 {
+		return 0, false
+	}
+	var n float64
+	switch a.Value.Kind() {
+	case slog.KindInt64:
+		n = float64(a.Value.Int64())
+	case slog.KindUint64:
+		n = float64(a.Value.Uint64())
+	case slog.KindFloat64:
+		n = a.Value.Float64()
 	default:
-		var val any
-		switch a.Value.Kind() {
-		case slog.KindString:
-			buf = fmt.Append(buf, ColorString)
-			val = a.Value.String()
-		case slog.KindInt64:
-			buf = fmt.Append(buf, ColorNumber)
-			val = a.Value.Int64()
-		case slog.KindUint64:
-			buf = fmt.Append(buf, ColorNumber)
-			val = a.Value.Uint64()
-		case slog.KindFloat64:
-			buf = fmt.Append(buf, ColorNumber)
-			val = a.Value.Float64()
-		case slog.KindBool:
-			buf = fmt.Append(buf, ColorBool)
-			val = a.Value.Bool()
-		case slog.KindDuration:
-			buf = fmt.Append(buf, ColorString)
-			val = a.Value.Duration().String()
-		case slog.KindTime:
-			buf = fmt.Append(buf, ColorString)
-			val = a.Value.Time().Format("2006-01-02T15:04:05.000Z07:00")
-		case slog.KindAny:
-			if a.Value.Any() == nil {
-				buf = fmt.Append(buf, ColorNull)
-				val = a.Value.Any()
-			} else {
-				buf = fmt.Append(buf, ColorString)
-				val = a.Value.String()
+		return 0, false
+	}
+	key := strings.ToLower(a.Key)
+	for _, pattern := range h.o.byteKeys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// truncate shortens s to the max value length option, marking it with an
+// ellipsis.
+func (h *PrettyHandler) truncate(s string) string {
+	if h.o.maxValueLen <= 0 || utf8.RuneCountInString(s) <= h.o.maxValueLen {
+		return s
+	}
+	i := 0
+	for n := 0; n < h.o.maxValueLen; n++ {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return s[:i] + "..."
+}
+
+// appendBlock appends s as a block of lines indented under its key.
+func (h *PrettyHandler) appendBlock(buf []byte, s string, indentLevel int) []byte {
+	buf = fmt.Appendf(buf, "|%s", h.c(ColorString))
+	for line := range strings.Lines(strings.TrimRight(s, "\n")) {
+		buf = fmt.Appendf(buf, "\n%*s%s", (indentLevel+1)*2, "", strings.TrimRight(line, "\r\n"))
+	}
+	return fmt.Append(buf, h.c(ColorMuted))
+}
+
+// stackTracer is implemented by errors that carry the program counters of the
+// stack that created them.
+type stackTracer interface {
+	StackTrace() []uintptr
+}
+
+// appendError appends the message of err followed by a list of its stack
+// frames, if it has any, and the errors it wraps, each rendered the same way.
+func (h *PrettyHandler) appendError(buf []byte, err error, indentLevel int) []byte {
+	buf = fmt.Appendf(buf, "%s%s%s", h.c(ColorError), encodeString(err.Error()), h.c(ColorMuted))
+
+	var frames []runtime.Frame
+	if st, ok := err.(stackTracer); ok {
+		if pcs := st.StackTrace(); len(pcs) > 0 {
+			fs := runtime.CallersFrames(pcs)
+			for {
+				f, more := fs.Next()
+				frames = append(frames, f)
+				if !more {
+					break
+				}
 			}
-		default:
-			buf = fmt.Append(buf, ColorString)
-			val = a.Value.String()
 		}
+	}
 
-		encodedVal, err := json.Marshal(val)
-		if err != nil {
-			encodedVal = fmt.Appendf(nil, "%q", fmt.Sprintf("<error marshalling: %v>", err))
+	var causes []error
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		if cause := u.Unwrap(); cause != nil {
+			causes = append(causes, cause)
+		}
+	case interface{ Unwrap() []error }:
+		for _, cause := range u.Unwrap() {
+			if cause != nil {
+				causes = append(causes, cause)
+			}
 		}
-		buf = fmt.Appendf(buf, "%s%s", encodedVal, ColorMuted)
 	}
 
-	return buf, false
+	if len(frames)+len(causes) == 0 {
+		return buf
+	}
+
+	buf = fmt.Append(buf, " [")
+	first := true
+	for _, f := range frames {
+		if !first {
+			buf = fmt.Append(buf, ",")
+		}
+		first = false
+		buf = fmt.Appendf(buf, "\n%*s%s", (indentLevel+1)*2, "", encodeString(fmt.Sprintf("at %s (%s:%d)", f.Function, f.File, f.Line)))
+	}
+	for _, cause := range causes {
+		if !first {
+			buf = fmt.Append(buf, ",")
+		}
+		first = false
+		buf = fmt.Appendf(buf, "\n%*s", (indentLevel+1)*2, "")
+		buf = h.appendError(buf, cause, indentLevel+1)
+	}
+	return fmt.Appendf(buf, "\n%*s]", indentLevel*2, "")
+}
+
+// marshalStructured returns v encoded as JSON if it is a map, slice, array or
+// struct, or a pointer to one, that does not describe itself as text.
+func marshalStructured(v any) (json.RawMessage, bool) {
+	switch v.(type) {
+	case nil, fmt.Stringer, encoding.TextMarshaler, []byte:
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+	default:
+		return nil, false
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// appendJSON appends the JSON value data, indented and colored like groups.
+// Objects and arrays nested deeper than the max depth option are elided.
+func (h *PrettyHandler) appendJSON(buf []byte, data json.RawMessage, indentLevel, depth int) []byte {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Appendf(buf, "%s%s%s", h.c(ColorString), encodeString(string(data)), h.c(ColorMuted))
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		start, end := "{", "}"
+		if tok == '[' {
+			start, end = "[", "]"
+		}
+		if !dec.More() {
+			return fmt.Append(buf, start, end)
+		}
+		if h.o.maxDepth > 0 && depth > h.o.maxDepth {
+			return fmt.Append(buf, start, "...", end)
+		}
+
+		buf = fmt.Append(buf, start)
+		first := true
+		for dec.More() {
+			if !first {
+				buf = fmt.Append(buf, ",")
+			}
+			first = false
+			buf = fmt.Appendf(buf, "\n%*s", (indentLevel+1)*2, "")
+			redact := false
+			if tok == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					break
+				}
+				buf = fmt.Appendf(buf, "%s%s%s: ", h.c(ColorKey), encodeString(fmt.Sprint(key)), h.c(ColorMuted))
+				redact = h.redacted(fmt.Sprint(key))
+			}
+			var elem json.RawMessage
+			if err := dec.Decode(&elem); err != nil {
+				break
+			}
+			if redact {
+				buf = h.appendRedacted(buf)
+			} else {
+				buf = h.appendJSON(buf, elem, indentLevel+1, depth+1)
+			}
+		}
+		return fmt.Appendf(buf, "\n%*s%s", indentLevel*2, "", end)
+	case string:
+		return fmt.Appendf(buf, "%s%s%s", h.c(ColorString), encodeString(h.truncate(tok)), h.c(ColorMuted))
+	case json.Number:
+		return fmt.Appendf(buf, "%s%s%s", h.c(ColorNumber), tok, h.c(ColorMuted))
+	case bool:
+		return fmt.Appendf(buf, "%s%t%s", h.c(ColorBool), tok, h.c(ColorMuted))
+	default:
+		return fmt.Appendf(buf, "%snull%s", h.c(ColorNull), h.c(ColorMuted))
+	}
+}
+
+// encodeString returns s as a JSON string.
+func encodeString(s string) []byte {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Appendf(nil, "%q", s)
+	}
+	return b
 }
 
 func (h *PrettyHandler) withGroupOrAttrs(goa groupOrAttrs) *PrettyHandler {
@@ -0,0 +1,449 @@
+package pretty_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonathonwebb/x/pretty"
+)
+
+func TestHandler_ReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	var gotGroups [][]string
+	logger := slog.New(pretty.NewHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch a.Key {
+			case slog.TimeKey:
+				return slog.Attr{}
+			case slog.LevelKey:
+				return slog.String(a.Key, "NOTICE")
+			case slog.MessageKey:
+				return slog.String(a.Key, strings.ToUpper(a.Value.String()))
+			case "password":
+				gotGroups = append(gotGroups, groups)
+				return slog.String(a.Key, "xxx")
+			case "drop":
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	logger.WithGroup("req").Info("login", "password", "hunter2", "drop", 1, slog.Group("user", "password", "hunter2"))
+
+	got := buf.String()
+	for _, want := range []string{"NOTICE: LOGIN", `"password": "xxx"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output %q does not contain %q", got, want)
+		}
+	}
+	for _, unwanted := range []string{"[", "hunter2", "drop"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("output %q contains %q", got, unwanted)
+		}
+	}
+	wantGroups := [][]string{{"req"}, {"req", "user"}}
+	if len(gotGroups) != len(wantGroups) {
+		t.Fatalf("groups: want %v, got %v", wantGroups, gotGroups)
+	}
+	for i := range wantGroups {
+		if strings.Join(gotGroups[i], ".") != strings.Join(wantGroups[i], ".") {
+			t.Errorf("groups: want %v, got %v", wantGroups, gotGroups)
+		}
+	}
+}
+
+func TestHandler_Color(t *testing.T) {
+	tests := []struct {
+		name      string
+		vars      map[string]string
+		options   []pretty.Option
+		wantColor bool
+	}{
+		{name: "not_terminal"},
+		{name: "force", vars: map[string]string{"CLICOLOR_FORCE": "1"}, wantColor: true},
+		{name: "no_color", vars: map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}},
+		{name: "option", vars: map[string]string{"NO_COLOR": "1"}, options: []pretty.Option{pretty.WithColor(true)}, wantColor: true},
+		{name: "option_disabled", vars: map[string]string{"CLICOLOR_FORCE": "1"}, options: []pretty.Option{pretty.WithColor(false)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "")
+			t.Setenv("CLICOLOR_FORCE", "")
+			for k, v := range tt.vars {
+				t.Setenv(k, v)
+			}
+
+			var buf bytes.Buffer
+			slog.New(pretty.NewHandler(&buf, nil, tt.options...)).Info("hello", "n", 1)
+
+			if got := strings.Contains(buf.String(), "\033["); got != tt.wantColor {
+				t.Errorf("color: want %t, got %t in %q", tt.wantColor, got, buf.String())
+			}
+			if !strings.Contains(buf.String(), "INFO: hello") && !tt.wantColor {
+				t.Errorf("output %q does not contain the uncolored message", buf.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Time(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 45, 0, time.FixedZone("EST", -5*60*60))
+
+	tests := []struct {
+		name    string
+		options []pretty.Option
+		want    string
+	}{
+		{name: "default", want: "[12:30:45.000] INFO: hello\n"},
+		{name: "format", options: []pretty.Option{pretty.WithTimeFormat(time.DateTime)}, want: "[2024-03-01 12:30:45] INFO: hello\n"},
+		{name: "utc", options: []pretty.Option{pretty.WithTimeFormat(time.RFC3339), pretty.WithUTC()}, want: "[2024-03-01T17:30:45Z] INFO: hello\n"},
+		{name: "disabled", options: []pretty.Option{pretty.WithTimeFormat("")}, want: "INFO: hello\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := pretty.NewHandler(&buf, nil, append(tt.options, pretty.WithColor(false))...)
+			if err := h.Handle(context.Background(), slog.NewRecord(ts, slog.LevelInfo, "hello", 0)); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandler_Error(t *testing.T) {
+	var buf bytes.Buffer
+	h := pretty.NewHandler(&buf, nil, pretty.WithColor(false), pretty.WithTimeFormat(""))
+	r := slog.NewRecord(time.Time{}, slog.LevelError, "failed", 0)
+	notFound := errors.New("not found")
+	r.AddAttrs(slog.Any("err", fmt.Errorf("load: %w", errors.Join(notFound, errors.New("denied")))))
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `ERROR: failed {
+  "err": "load: not found\ndenied" [
+    "not found\ndenied" [
+      "not found",
+      "denied"
+    ]
+  ]
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+type stackError struct {
+	pcs []uintptr
+}
+
+func (e stackError) Error() string         { return "boom" }
+func (e stackError) StackTrace() []uintptr { return e.pcs }
+
+func TestHandler_ErrorStack(t *testing.T) {
+	var buf bytes.Buffer
+	h := pretty.NewHandler(&buf, nil, pretty.WithColor(false))
+	pcs := make([]uintptr, 1)
+	runtime.Callers(1, pcs)
+	slog.New(h).Error("failed", "err", stackError{pcs: pcs})
+
+	if want := `"at github.com/jonathonwebb/x/pretty_test.TestHandler_ErrorStack (`; !strings.Contains(buf.String(), want) {
+		t.Errorf("output %q does not contain %q", buf.String(), want)
+	}
+}
+
+func TestHandler_Structured(t *testing.T) {
+	type point struct {
+		Y, X int
+		Tags []string
+	}
+
+	tests := []struct {
+		name    string
+		value   any
+		options []pretty.Option
+		want    string
+	}{
+		{
+			name:  "struct",
+			value: &point{Y: 2, X: 1, Tags: []string{"a"}},
+			want: `INFO: hello {
+  "v": {
+    "Y": 2,
+    "X": 1,
+    "Tags": [
+      "a"
+    ]
+  }
+}
+`,
+		},
+		{
+			name:  "map",
+			value: map[string]any{"b": true, "a": nil, "c": []int{}},
+			want: `INFO: hello {
+  "v": {
+    "a": null,
+    "b": true,
+    "c": []
+  }
+}
+`,
+		},
+		{
+			name:    "max_depth",
+			value:   map[string]any{"a": map[string]int{"b": 1}, "c": []int{1}},
+			options: []pretty.Option{pretty.WithMaxDepth(1)},
+			want: `INFO: hello {
+  "v": {
+    "a": {...},
+    "c": [...]
+  }
+}
+`,
+		},
+		{
+			name:  "stringer",
+			value: net.IPv4(127, 0, 0, 1),
+			want: `INFO: hello {
+  "v": "127.0.0.1"
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			options := append(tt.options, pretty.WithColor(false), pretty.WithTimeFormat(""))
+			h := pretty.NewHandler(&buf, nil, options...)
+			r := slog.NewRecord(time.Time{}, slog.LevelInfo, "hello", 0)
+			r.AddAttrs(slog.Any("v", tt.value))
+			if err := h.Handle(context.Background(), r); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("want:\n%s\ngot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandler_Multiline(t *testing.T) {
+	var buf bytes.Buffer
+	h := pretty.NewHandler(&buf, nil, pretty.WithColor(false), pretty.WithTimeFormat(""), pretty.WithMultiline())
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "query", 0)
+	r.AddAttrs(slog.String("sql", "SELECT *\n  FROM users\n"), slog.Int("rows", 2), slog.String("table", "users"))
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INFO: query {
+  "sql": |
+    SELECT *
+      FROM users
+  "rows": 2,
+  "table": "users"
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestHandler_Redact(t *testing.T) {
+	var buf bytes.Buffer
+	h := pretty.NewHandler(&buf, nil, pretty.WithColor(false), pretty.WithTimeFormat(""), pretty.WithRedact("password", "*token*", "authorization"))
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "request", 0)
+	r.AddAttrs(
+		slog.String("Password", "hunter2"),
+		slog.Group("auth", slog.String("access_token", "abc"), slog.String("user", "bob")),
+		slog.Any("headers", map[string]string{"Authorization": "Bearer abc", "Accept": "*/*"}),
+	)
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INFO: request {
+  "Password": "[REDACTED]",
+  "auth": {
+    "access_token": "[REDACTED]",
+    "user": "bob"
+  },
+  "headers": {
+    "Accept": "*/*",
+    "Authorization": "[REDACTED]"
+  }
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestHandler_LevelWriter(t *testing.T) {
+	var out, warn, errOut bytes.Buffer
+	logger := slog.New(pretty.NewHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug},
+		pretty.WithTimeFormat(""),
+		pretty.WithLevelWriter(slog.LevelError, &errOut),
+		pretty.WithLevelWriter(slog.LevelWarn, &warn),
+	))
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+
+	for _, tt := range []struct {
+		name string
+		buf  *bytes.Buffer
+		want []string
+	}{
+		{"out", &out, []string{"DEBUG", "INFO"}},
+		{"warn", &warn, []string{"WARN"}},
+		{"err", &errOut, []string{"ERROR"}},
+	} {
+		lines := strings.Split(strings.TrimSpace(tt.buf.String()), "\n")
+		if len(lines) != len(tt.want) {
+			t.Errorf("%s: want %d records, got %q", tt.name, len(tt.want), tt.buf.String())
+			continue
+		}
+		for i, want := range tt.want {
+			if !strings.HasPrefix(lines[i], want+":") {
+				t.Errorf("%s: record %d: want level %s, got %q", tt.name, i, want, lines[i])
+			}
+		}
+	}
+}
+
+func TestHandler_Flatten(t *testing.T) {
+	var buf bytes.Buffer
+	h := pretty.NewHandler(&buf, nil, pretty.WithColor(false), pretty.WithTimeFormat(""), pretty.WithFlatten())
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "request", 0)
+	r.AddAttrs(slog.Group("user", slog.Int("id", 1), slog.Group("org", slog.String("name", "acme"))), slog.Int("status", 200))
+	if err := h.WithAttrs([]slog.Attr{slog.String("id", "abc")}).WithGroup("req").Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INFO: request {
+  "id": "abc",
+  "req.user.id": 1,
+  "req.user.org.name": "acme",
+  "req.status": 200
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestHandler_Humanize(t *testing.T) {
+	var buf bytes.Buffer
+	h := pretty.NewHandler(&buf, nil, pretty.WithColor(false), pretty.WithTimeFormat(""), pretty.WithHumanDurations(), pretty.WithHumanBytes())
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "done", 0)
+	r.AddAttrs(
+		slog.Duration("total", 1234567890*time.Nanosecond),
+		slog.Duration("query", 350*time.Millisecond),
+		slog.Duration("wait", 90*time.Second+400*time.Millisecond),
+		slog.Int("size", 1536),
+		slog.Uint64("body_bytes", 512),
+		slog.Int("count", 2048),
+	)
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INFO: done {
+  "total": "1.23s",
+  "query": "350ms",
+  "wait": "1m30s",
+  "size": "1.5KiB",
+  "body_bytes": "512B",
+  "count": 2048
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+type traceKey struct{}
+
+func TestHandler_ContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	h := pretty.NewHandler(&buf, nil, pretty.WithColor(false), pretty.WithTimeFormat(""), pretty.WithContextAttrs(func(ctx context.Context) []slog.Attr {
+		if id, ok := ctx.Value(traceKey{}).(string); ok {
+			return []slog.Attr{slog.String("trace_id", id)}
+		}
+		return nil
+	}))
+	hg := h.WithGroup("req")
+
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "traced", 0)
+	r.AddAttrs(slog.Int("id", 1))
+	if err := hg.Handle(context.WithValue(context.Background(), traceKey{}, "abc"), r); err != nil {
+		t.Fatal(err)
+	}
+	if err := hg.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "untraced", 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INFO: traced {
+  "trace_id": "abc",
+  "req": {
+    "id": 1
+  }
+}
+INFO: untraced
+`
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestHandler_Truncate(t *testing.T) {
+	var buf bytes.Buffer
+	h := pretty.NewHandler(&buf, nil, pretty.WithColor(false), pretty.WithTimeFormat(""), pretty.WithMaxValueLen(5), pretty.WithMaxAttrs(3))
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "payload", 0)
+	r.AddAttrs(
+		slog.String("body", "héllo world"),
+		slog.Any("tags", []string{"abcdefgh"}),
+		slog.Group("req", slog.String("id", "abc"), slog.Int("n", 1)),
+		slog.Int("status", 200),
+	)
+	if err := h.WithGroup("g").Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `INFO: payload {
+  "g": {
+    "body": "héllo...",
+    "tags": [
+      "abcde..."
+    ],
+    "req": {
+      "id": "abc"
+    }
+  },
+  ... 2 more attrs
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,163 @@
+package pretty
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SampleOptions configures a handler returned by [Sample].
+type SampleOptions struct {
+	// Interval is the window in which repeated records are counted. The
+	// default is one second.
+	Interval time.Duration
+
+	// Burst is the number of repeated records passed in each interval. The
+	// default is 1.
+	Burst int
+}
+
+// Sample returns a handler that passes records to h, but drops records with
+// the same level and message as Burst others in the same Interval. The first
+// such record passed after others were dropped is preceded by a summary record
+// whose message notes how many times it was repeated. Windows are measured
+// with record times. Once per Interval, counts whose window has ended are
+// forgotten, and the summaries of records that have not recurred are passed
+// to h before the record being handled.
+//
+// Handlers derived with WithAttrs and WithGroup share the counts of the
+// handler they were derived from.
+func Sample(h slog.Handler, opts *SampleOptions) slog.Handler {
+	s := &sampleHandler{h: h, sampler: &sampler{seen: make(map[sampleKey]*sampleCount)}}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Interval <= 0 {
+		s.opts.Interval = time.Second
+	}
+	if s.opts.Burst <= 0 {
+		s.opts.Burst = 1
+	}
+	return s
+}
+
+type sampleHandler struct {
+	h    slog.Handler
+	opts SampleOptions
+	*sampler
+}
+
+type sampler struct {
+	mu    sync.Mutex
+	seen  map[sampleKey]*sampleCount
+	swept time.Time
+}
+
+type sampleKey struct {
+	level slog.Level
+	msg   string
+}
+
+type sampleCount struct {
+	start   time.Time
+	n       int
+	dropped int
+}
+
+func (s *sampleHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return s.h.Enabled(ctx, l)
+}
+
+func (s *sampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	s.mu.Lock()
+	key := sampleKey{level: r.Level, msg: r.Message}
+	c, ok := s.seen[key]
+	if !ok {
+		c = &sampleCount{start: t}
+		s.seen[key] = c
+	}
+	var dropped int
+	if t.Sub(c.start) >= s.opts.Interval {
+		dropped = c.dropped
+		*c = sampleCount{start: t}
+	}
+	c.n++
+	pass := c.n <= s.opts.Burst
+	if !pass {
+		c.dropped++
+	}
+	expired := s.sweep(t, key, s.opts.Interval)
+	s.mu.Unlock()
+
+	for _, e := range expired {
+		if err := s.h.Handle(ctx, summarize(t, e.level, e.msg, e.dropped, 0)); err != nil {
+			return err
+		}
+	}
+	if !pass {
+		return nil
+	}
+	if dropped > 0 {
+		if err := s.h.Handle(ctx, summarize(t, r.Level, r.Message, dropped, r.PC)); err != nil {
+			return err
+		}
+	}
+	return s.h.Handle(ctx, r)
+}
+
+type expiredCount struct {
+	sampleKey
+	start   time.Time
+	dropped int
+}
+
+// sweep removes the counts other than key whose window ended by t, at most
+// once per interval, and returns those with dropped records in window order.
+// The caller must hold s.mu.
+func (s *sampler) sweep(t time.Time, key sampleKey, interval time.Duration) []expiredCount {
+	if t.Sub(s.swept) < interval {
+		return nil
+	}
+	s.swept = t
+
+	var expired []expiredCount
+	for k, c := range s.seen {
+		if k == key || t.Sub(c.start) < interval {
+			continue
+		}
+		if c.dropped > 0 {
+			expired = append(expired, expiredCount{k, c.start, c.dropped})
+		}
+		delete(s.seen, k)
+	}
+	slices.SortFunc(expired, func(a, b expiredCount) int {
+		return cmp.Or(a.start.Compare(b.start), cmp.Compare(a.level, b.level), cmp.Compare(a.msg, b.msg))
+	})
+	return expired
+}
+
+// summarize returns a record noting that msg was repeated dropped times.
+func summarize(t time.Time, level slog.Level, msg string, dropped int, pc uintptr) slog.Record {
+	return slog.NewRecord(t, level, fmt.Sprintf("%s (repeated %d times)", msg, dropped), pc)
+}
+
+func (s *sampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	s2 := *s
+	s2.h = s.h.WithAttrs(attrs)
+	return &s2
+}
+
+func (s *sampleHandler) WithGroup(name string) slog.Handler {
+	s2 := *s
+	s2.h = s.h.WithGroup(name)
+	return &s2
+}
@@ -0,0 +1,74 @@
+package pretty_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonathonwebb/x/pretty"
+)
+
+func TestSample(t *testing.T) {
+	var buf bytes.Buffer
+	h := pretty.Sample(pretty.NewHandler(&buf, nil, pretty.WithTimeFormat("")), &pretty.SampleOptions{Interval: time.Second, Burst: 2})
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		if err := h.Handle(context.Background(), slog.NewRecord(start.Add(time.Duration(i)*time.Millisecond), slog.LevelInfo, "tick", 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Handle(context.Background(), slog.NewRecord(start, slog.LevelWarn, "tick", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.WithAttrs([]slog.Attr{slog.Int("n", 1)}).Handle(context.Background(), slog.NewRecord(start.Add(time.Second), slog.LevelInfo, "tick", 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"INFO: tick",
+		"INFO: tick",
+		"WARN: tick",
+		"INFO: tick (repeated 3 times) {",
+		`  "n": 1`,
+		"}",
+		"INFO: tick {",
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < len(want) {
+		t.Fatalf("want at least %d lines, got %q", len(want), buf.String())
+	}
+	for i, w := range want {
+		if lines[i] != w {
+			t.Errorf("line %d: want %q, got %q", i, w, lines[i])
+		}
+	}
+}
+
+func TestSample_Sweep(t *testing.T) {
+	var buf bytes.Buffer
+	h := pretty.Sample(pretty.NewHandler(&buf, nil, pretty.WithTimeFormat("")), &pretty.SampleOptions{Interval: time.Second})
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []slog.Record{
+		slog.NewRecord(start, slog.LevelInfo, "a", 0),
+		slog.NewRecord(start, slog.LevelInfo, "a", 0),
+		slog.NewRecord(start, slog.LevelInfo, "a", 0),
+		slog.NewRecord(start, slog.LevelInfo, "b", 0),
+		slog.NewRecord(start.Add(2*time.Second), slog.LevelInfo, "c", 0),
+		slog.NewRecord(start.Add(2500*time.Millisecond), slog.LevelInfo, "a", 0),
+	}
+	for _, r := range records {
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := "INFO: a\nINFO: b\nINFO: a (repeated 2 times)\nINFO: c\nINFO: a\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output mismatch\nwant: %q\ngot:  %q", want, got)
+	}
+}
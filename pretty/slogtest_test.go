@@ -0,0 +1,55 @@
+package pretty_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/jonathonwebb/x/pretty"
+)
+
+func TestSlogtest(t *testing.T) {
+	var buf bytes.Buffer
+	newHandler := func(*testing.T) slog.Handler {
+		buf.Reset()
+		return pretty.NewHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) == 0 && a.Key == slog.SourceKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}, pretty.WithColor(false), pretty.WithTimeFormat(time.RFC3339Nano))
+	}
+	result := func(t *testing.T) map[string]any {
+		return parseRecord(t, buf.String())
+	}
+	slogtest.Run(t, newHandler, result)
+}
+
+// parseRecord parses a single uncolored record without source into a map in
+// the form expected by slogtest.
+func parseRecord(t *testing.T, s string) map[string]any {
+	t.Helper()
+	header, body, _ := strings.Cut(strings.TrimSuffix(s, "\n"), "\n")
+	m := make(map[string]any)
+	if h, ok := strings.CutSuffix(header, " {"); ok {
+		header = h
+		if err := json.Unmarshal([]byte("{\n"+body), &m); err != nil {
+			t.Fatalf("invalid attrs in %q: %v", s, err)
+		}
+	}
+	if rest, ok := strings.CutPrefix(header, "["); ok {
+		ts, after, _ := strings.Cut(rest, "] ")
+		m[slog.TimeKey] = ts
+		header = after
+	}
+	level, msg, _ := strings.Cut(header, ": ")
+	m[slog.LevelKey] = level
+	m[slog.MessageKey] = msg
+	return m
+}
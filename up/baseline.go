@@ -0,0 +1,72 @@
+package up
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Baseline records every source migration up to and including version as
+// applied without running it. It is used to adopt up on a database whose
+// schema was created by other tooling. Migrations that are already recorded are
+// left untouched.
+func (m *Migrator[T]) Baseline(ctx context.Context, version int64) (n int, err error) {
+	if err := m.check(); err != nil {
+		return 0, fmt.Errorf("invalid sources: %w", err)
+	}
+
+	if _, ok := m.findSource(version); !ok {
+		return 0, fmt.Errorf("invalid baseline version: %w", &MissingMigrationError{version})
+	}
+
+	err = m.withLock(ctx, func(ctx context.Context) error {
+		history, err := m.Store.History(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get version store history: %w", err)
+		}
+		applied := make(map[int64]bool, len(history))
+		for _, record := range history {
+			applied[record.Version] = true
+		}
+
+		var toMark []*Migration[T]
+		for _, migration := range m.Sources {
+			if migration.Version > version || applied[migration.Version] {
+				continue
+			}
+			m.debug("baselining migration: %d", migration.Version)
+			toMark = append(toMark, migration)
+		}
+		n, err = m.recordMany(ctx, m.Store, toMark)
+		return err
+	})
+	return n, err
+}
+
+// withLock initializes and locks the version store, calls fn, then releases
+// the lock regardless of the result.
+func (m *Migrator[T]) withLock(ctx context.Context, fn func(context.Context) error) (err error) {
+	done, err := m.acquire()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if err := m.Store.Init(ctx); err != nil {
+		return fmt.Errorf("failed to init version store: %w", err)
+	}
+
+	lockCtx, unlock, err := m.lock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get version store lock: %w", err)
+	}
+	defer func() {
+		unlock()
+		m.debug("releasing version store lock")
+		if rlErr := m.Store.Release(ctx); rlErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release version store lock: %w", rlErr))
+		}
+	}()
+
+	return fn(lockCtx)
+}
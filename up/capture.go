@@ -0,0 +1,175 @@
+package up
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Capture returns the statements that the pending migrations up to and
+// including the specified version would execute, as a single SQL script for
+// review. The special value -1 captures all pending migrations.
+//
+// Nothing is executed and the version store is not changed, not even created:
+// if the store is an [InitChecker] that reports it has not been initialized,
+// every migration is captured as pending. Migrations run
+// against a recording [*sql.DB] whose queries return no rows, so a migration
+// that branches on query results is captured along the path it would take on
+// an empty database. Migrations listed in the migrator's Skip are not run, and
+// are captured as a comment marking them skipped.
+func Capture(ctx context.Context, m *Migrator[SQLConn], to int64) (string, error) {
+	if err := m.check(); err != nil {
+		return "", fmt.Errorf("invalid sources: %w", err)
+	}
+
+	initialized, err := m.initialized(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var remoteVersion int64
+	var applied map[int64]bool
+	if initialized {
+		remoteVersion, err = m.Store.Version(ctx)
+		if err != nil && !errors.Is(err, ErrInitialVersion) {
+			return "", fmt.Errorf("failed to get version store state: %w", err)
+		}
+		applied, err = m.appliedVersions(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	toApply, err := m.pending(remoteVersion, to, applied)
+	if err != nil {
+		return "", err
+	}
+
+	rec := &recorder{}
+	db := sql.OpenDB(rec)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	var b strings.Builder
+	for _, migration := range toApply {
+		rec.stmts = nil
+		if !m.skipped(migration) {
+			if err := migration.Run(ctx, db); err != nil {
+				return "", fmt.Errorf("failed to capture migration %d: %w", migration.Version, err)
+			}
+		}
+
+		fmt.Fprintf(&b, "-- migration %d", migration.Version)
+		if migration.Name != "" {
+			fmt.Fprintf(&b, ": %s", migration.Name)
+		}
+		b.WriteString("\n")
+		if m.skipped(migration) {
+			b.WriteString("-- skipped\n")
+		}
+		for _, stmt := range rec.stmts {
+			b.WriteString(stmt)
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// recorder is a database/sql connector whose connections record statements
+// instead of executing them.
+type recorder struct {
+	mu    sync.Mutex
+	stmts []string
+}
+
+func (r *recorder) record(query string, args []driver.NamedValue) {
+	stmt := strings.TrimSpace(query)
+	if !strings.HasSuffix(stmt, ";") {
+		stmt += ";"
+	}
+	if len(args) > 0 {
+		values := make([]any, len(args))
+		for i, arg := range args {
+			values[i] = arg.Value
+		}
+		stmt += fmt.Sprintf(" -- args: %v", values)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stmts = append(r.stmts, stmt)
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return &recorderConn{r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return recorderDriver{} }
+
+type recorderDriver struct{}
+
+func (recorderDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("up: capture driver cannot be opened by name")
+}
+
+type recorderConn struct {
+	r *recorder
+}
+
+func (c *recorderConn) Prepare(query string) (driver.Stmt, error) {
+	return &recorderStmt{c.r, query}, nil
+}
+
+func (c *recorderConn) Close() error { return nil }
+
+func (c *recorderConn) Begin() (driver.Tx, error) { return recorderTx{}, nil }
+
+func (c *recorderConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.r.record(query, args)
+	return driver.RowsAffected(0), nil
+}
+
+func (c *recorderConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.r.record(query, args)
+	return emptyRows{}, nil
+}
+
+type recorderStmt struct {
+	r     *recorder
+	query string
+}
+
+func (s *recorderStmt) Close() error  { return nil }
+func (s *recorderStmt) NumInput() int { return -1 }
+
+func (s *recorderStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.r.record(s.query, named(args))
+	return driver.RowsAffected(0), nil
+}
+
+func (s *recorderStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.r.record(s.query, named(args))
+	return emptyRows{}, nil
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		nv[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return nv
+}
+
+type recorderTx struct{}
+
+func (recorderTx) Commit() error   { return nil }
+func (recorderTx) Rollback() error { return nil }
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
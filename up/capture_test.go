@@ -0,0 +1,86 @@
+package up_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/x/up"
+)
+
+func TestCapture(t *testing.T) {
+	store := &fakeStore{Versions: []int64{1}}
+	migrations := createMigrations(1, 2, 3, 4)
+	migrations[1].Name = "create_users"
+	migrations[1].RunFunc = func(ctx context.Context, db up.SQLConn) error {
+		if _, err := db.ExecContext(ctx, "CREATE TABLE users (id INTEGER)"); err != nil {
+			return err
+		}
+		_, err := db.ExecContext(ctx, "INSERT INTO users (id) VALUES (?);", 1)
+		return err
+	}
+	migrations[2].RunFunc = func(ctx context.Context, db up.SQLConn) error {
+		var n int
+		err := db.QueryRowContext(ctx, "SELECT count(*) FROM users").Scan(&n)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		return nil
+	}
+	migrations[3].Name = "backfill_users"
+	migrations[3].RunFunc = func(ctx context.Context, db up.SQLConn) error {
+		_, err := db.ExecContext(ctx, "UPDATE users SET id = id + 1")
+		return err
+	}
+	migrator := &up.Migrator[up.SQLConn]{
+		Store:   store,
+		Sources: migrations,
+		Skip:    []int64{4},
+	}
+
+	got, err := up.Capture(context.Background(), migrator, up.RunTargetLatest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `-- migration 2: create_users
+CREATE TABLE users (id INTEGER);
+INSERT INTO users (id) VALUES (?); -- args: [1]
+
+-- migration 3
+SELECT count(*) FROM users;
+
+-- migration 4: backfill_users
+-- skipped
+
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("script mismatch (-want +got):\n%s", diff)
+	}
+	if store.InitCalls > 0 || store.InsertCalls > 0 || store.LockCalls > 0 {
+		t.Error("Capture should not init, lock or write to the store")
+	}
+}
+
+func TestCapture_NotInitialized(t *testing.T) {
+	store := &initCheckStore{fakeStore: &fakeStore{Versions: []int64{1}}}
+	migrator := &up.Migrator[up.SQLConn]{
+		Store:   store,
+		Sources: createMigrations(1, 2),
+	}
+
+	got, err := up.Capture(context.Background(), migrator, up.RunTargetLatest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "-- migration 1\n\n-- migration 2\n\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("script mismatch (-want +got):\n%s", diff)
+	}
+	if store.InitCalls > 0 || store.VersionCalls > 0 {
+		t.Error("Capture should not init or read an uninitialized store")
+	}
+}
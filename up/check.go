@@ -0,0 +1,67 @@
+package up
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Check reports how many migrations [Migrator.Run] would apply to reach the
+// latest version, without acquiring the version store lock. If any are pending,
+// it returns their count and a [*PendingError], so it can gate deploys and
+// readiness probes.
+//
+// Check only reads the version store, so it can run with a read-only database
+// role. It returns [ErrNotInitialized] if the store is an [InitChecker] whose
+// version store has not been created.
+func (m *Migrator[T]) Check(ctx context.Context) (int, error) {
+	if err := m.check(); err != nil {
+		return 0, fmt.Errorf("invalid sources: %w", err)
+	}
+
+	initialized, err := m.initialized(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !initialized {
+		return 0, ErrNotInitialized
+	}
+
+	remoteVersion, err := m.Store.Version(ctx)
+	if err != nil && !errors.Is(err, ErrInitialVersion) {
+		return 0, fmt.Errorf("failed to get version store state: %w", err)
+	}
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	toApply, err := m.pending(remoteVersion, RunTargetLatest, applied)
+	if err != nil {
+		return 0, err
+	}
+	if len(toApply) == 0 {
+		return 0, nil
+	}
+
+	versions := make([]int64, len(toApply))
+	for i, migration := range toApply {
+		versions[i] = migration.Version
+	}
+	return len(versions), &PendingError{Versions: versions}
+}
+
+// initialized reports whether the version store has been created, without
+// modifying it. Stores that are not an [InitChecker] are assumed to have been.
+func (m *Migrator[T]) initialized(ctx context.Context) (bool, error) {
+	ic, ok := m.Store.(InitChecker)
+	if !ok {
+		return true, nil
+	}
+	initialized, err := ic.Initialized(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check version store: %w", err)
+	}
+	return initialized, nil
+}
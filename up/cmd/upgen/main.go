@@ -0,0 +1,79 @@
+// Upgen generates a Go file declaring up migrations from a directory of SQL
+// migration files, so the SQL is compiled into the binary.
+//
+// Usage:
+//
+//	upgen [-dir dir] [-pkg name] [-var name] [-o file]
+//
+// It is intended to be run by go:generate from the package that will contain
+// the generated file:
+//
+//	//go:generate go run github.com/jonathonwebb/x/up/cmd/upgen -dir sql -o migrations_gen.go
+//
+// By default, upgen reads the current directory, names the package after the
+// $GOPACKAGE variable set by go:generate, declares a variable named
+// Migrations, and writes to standard output.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/jonathonwebb/x/cli"
+	"github.com/jonathonwebb/x/up"
+)
+
+type config struct {
+	dir, pkg, name, out string
+}
+
+var cmd = &cli.Command[*config, any]{
+	Name:  "upgen",
+	Usage: "usage: upgen [-dir dir] [-pkg name] [-var name] [-o file]",
+	Help: `Upgen generates a Go file declaring up migrations from SQL migration files.
+
+Flags:
+  -dir dir    directory of <version>_<name>.sql files (default ".")
+  -pkg name   package of the generated file (default $GOPACKAGE)
+  -var name   name of the generated variable (default "Migrations")
+  -o file     output file (default standard output)`,
+	Flags: func(flags *flag.FlagSet, c *config) {
+		flags.StringVar(&c.dir, "dir", ".", "")
+		flags.StringVar(&c.pkg, "pkg", "", "")
+		flags.StringVar(&c.name, "var", "Migrations", "")
+		flags.StringVar(&c.out, "o", "", "")
+	},
+	Vars: map[string]string{
+		"pkg": "GOPACKAGE",
+	},
+	Action: func(ctx context.Context, env *cli.Env[any], c *config) cli.ExitStatus {
+		if c.pkg == "" {
+			env.Errorf("upgen: missing package name: set -pkg or run from go:generate\n")
+			return cli.ExitUsage
+		}
+
+		src, err := up.GenerateGo(os.DirFS(c.dir), c.pkg, c.name)
+		if err != nil {
+			env.Errorf("upgen: %v\n", err)
+			return cli.ExitFailure
+		}
+
+		if c.out == "" {
+			if _, err := env.Out.Write(src); err != nil {
+				env.Errorf("upgen: %v\n", err)
+				return cli.ExitFailure
+			}
+			return cli.ExitSuccess
+		}
+		if err := os.WriteFile(c.out, src, 0o644); err != nil {
+			env.Errorf("upgen: %v\n", err)
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+func main() {
+	cli.Run(cmd, nil, &config{})
+}
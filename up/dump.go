@@ -0,0 +1,69 @@
+package up
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// A Dumper writes the current database schema, such as to keep a canonical
+// schema file under version control. See [Migrator.SchemaFile].
+type Dumper interface {
+	DumpSchema(ctx context.Context, w io.Writer) error
+}
+
+// CommandDumper is a Dumper that runs an external command, such as
+// pg_dump --schema-only, and writes its standard output.
+type CommandDumper struct {
+	Path string
+	Args []string
+}
+
+func (d *CommandDumper) DumpSchema(ctx context.Context, w io.Writer) error {
+	cmd := exec.CommandContext(ctx, d.Path, d.Args...)
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// dumpSchema writes the schema to SchemaFile if res includes any migrations,
+// replacing the file only once the dump has succeeded. The file keeps the mode
+// of the file it replaces, or is created with mode 0644.
+func (m *Migrator[T]) dumpSchema(ctx context.Context, res *Result) (err error) {
+	if m.Dumper == nil || m.SchemaFile == "" || len(res.Migrations) == 0 {
+		return nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(m.SchemaFile), filepath.Base(m.SchemaFile)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to dump schema: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err := m.Dumper.DumpSchema(ctx, f); err != nil {
+		return fmt.Errorf("failed to dump schema: %w", err)
+	}
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(m.SchemaFile); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := f.Chmod(mode); err != nil {
+		return fmt.Errorf("failed to dump schema: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to dump schema: %w", err)
+	}
+	if err := os.Rename(f.Name(), m.SchemaFile); err != nil {
+		return fmt.Errorf("failed to dump schema: %w", err)
+	}
+	m.debug("dumped schema to %s", m.SchemaFile)
+	return nil
+}
@@ -0,0 +1,134 @@
+package up_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/jonathonwebb/x/up"
+)
+
+type dumperFunc func(context.Context, io.Writer) error
+
+func (f dumperFunc) DumpSchema(ctx context.Context, w io.Writer) error { return f(ctx, w) }
+
+func TestMigrator_SchemaFile(t *testing.T) {
+	t.Run("writes_after_run", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "schema.sql")
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   &fakeStore{},
+			Sources: createMigrations(1, 2),
+			Dumper: dumperFunc(func(_ context.Context, w io.Writer) error {
+				_, err := io.WriteString(w, "CREATE TABLE t (id INTEGER);\n")
+				return err
+			}),
+			SchemaFile: path,
+		}
+
+		if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read schema file: %v", err)
+		}
+		if want := "CREATE TABLE t (id INTEGER);\n"; string(got) != want {
+			t.Errorf("schema file = %q, want %q", got, want)
+		}
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("failed to stat schema file: %v", err)
+			}
+			if got, want := info.Mode().Perm(), os.FileMode(0o644); got != want {
+				t.Errorf("schema file mode = %v, want %v", got, want)
+			}
+		}
+	})
+
+	t.Run("keeps_file_mode", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file modes are not supported on windows")
+		}
+		path := filepath.Join(t.TempDir(), "schema.sql")
+		if err := os.WriteFile(path, []byte("old"), 0o640); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, 0o640); err != nil {
+			t.Fatal(err)
+		}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   &fakeStore{},
+			Sources: createMigrations(1),
+			Dumper: dumperFunc(func(_ context.Context, w io.Writer) error {
+				_, err := io.WriteString(w, "new")
+				return err
+			}),
+			SchemaFile: path,
+		}
+
+		if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("failed to stat schema file: %v", err)
+		}
+		if got, want := info.Mode().Perm(), os.FileMode(0o640); got != want {
+			t.Errorf("schema file mode = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("keeps_file_on_dump_error", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "schema.sql")
+		if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   &fakeStore{},
+			Sources: createMigrations(1),
+			Dumper: dumperFunc(func(_ context.Context, w io.Writer) error {
+				io.WriteString(w, "partial")
+				return errors.New("dump error")
+			}),
+			SchemaFile: path,
+		}
+
+		if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err == nil {
+			t.Fatal("want dump error, got nil")
+		}
+
+		got, _ := os.ReadFile(path)
+		if string(got) != "old" {
+			t.Errorf("schema file = %q, want %q", got, "old")
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("want only the schema file in %s, got %d entries", dir, len(entries))
+		}
+	})
+}
+
+func TestCommandDumper(t *testing.T) {
+	echo, err := exec.LookPath("echo")
+	if err != nil {
+		t.Skip("echo not found")
+	}
+	dumper := &up.CommandDumper{Path: echo, Args: []string{"CREATE TABLE t (id INTEGER);"}}
+
+	var b strings.Builder
+	if err := dumper.DumpSchema(context.Background(), &b); err != nil {
+		t.Fatalf("DumpSchema() error: %v", err)
+	}
+	if want := "CREATE TABLE t (id INTEGER);\n"; b.String() != want {
+		t.Errorf("DumpSchema() wrote %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,185 @@
+package up
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	upAnnotation   = "-- +up"
+	downAnnotation = "-- +down"
+)
+
+// FSLoader is a Loader that discovers SQL migration files in a filesystem.
+//
+// Files are named <version>_<name>.sql, such as 0001_create_users.sql, and are
+// read from the root of the filesystem; use [fs.Sub] to load from a
+// subdirectory. Within a file, statements after a "-- +up" line are run and
+// statements after a "-- +down" line are reverted. A file without annotations
+// is run in full.
+//
+// Load opens every file and reads it up to the first statement of its down
+// section, or to the end if it has none, so that reversibility is known before
+// any migration runs; RevertFunc is left nil for files without a down section,
+// so they are reported as irreversible. The file is read again and parsed when
+// its migration is run or reverted, so edits made after Load take effect.
+type FSLoader[T any] struct {
+	fsys fs.FS
+	exec func(context.Context, T, string) error
+}
+
+// NewFSLoader creates a new FSLoader that loads migrations from fsys and runs
+// them with exec. [SQLExec] runs migrations on an [SQLConn].
+func NewFSLoader[T any](fsys fs.FS, exec func(ctx context.Context, conn T, query string) error) *FSLoader[T] {
+	return &FSLoader[T]{
+		fsys: fsys,
+		exec: exec,
+	}
+}
+
+// SQLExec executes query on conn. It is intended for use with [NewFSLoader].
+func SQLExec(ctx context.Context, conn SQLConn, query string) error {
+	_, err := conn.ExecContext(ctx, query)
+	return err
+}
+
+// Load lists the migration files in the FSLoader's filesystem, scanning each
+// for a down section, and returns them sorted by version.
+func (l *FSLoader[T]) Load(ctx context.Context) ([]*Migration[T], error) {
+	entries, err := fs.ReadDir(l.fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []*Migration[T]
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		version, name, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		file := entry.Name()
+		migration := &Migration[T]{
+			Version: version,
+			Name:    name,
+			RunFunc: func(ctx context.Context, conn T) error {
+				return l.run(ctx, conn, file, upAnnotation)
+			},
+		}
+
+		down, err := l.hasDown(file)
+		if err != nil {
+			return nil, err
+		}
+		if down {
+			migration.RevertFunc = func(ctx context.Context, conn T) error {
+				return l.run(ctx, conn, file, downAnnotation)
+			}
+		}
+		migrations = append(migrations, migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b *Migration[T]) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return migrations, nil
+}
+
+func (l *FSLoader[T]) run(ctx context.Context, conn T, file, section string) error {
+	data, err := fs.ReadFile(l.fsys, file)
+	if err != nil {
+		return err
+	}
+
+	up, down, err := parseSQL(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	query := up
+	if section == downAnnotation {
+		query = down
+	}
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("%s: no %q section", file, section)
+	}
+	return l.exec(ctx, conn, query)
+}
+
+// hasDown reports whether file has a non-empty down section, reading it only
+// until the first statement after the down annotation.
+func (l *FSLoader[T]) hasDown(file string) (bool, error) {
+	f, err := l.fsys.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var inDown bool
+	for {
+		line, err := r.ReadString('\n')
+		switch trimmed := strings.TrimSpace(line); {
+		case trimmed == downAnnotation:
+			inDown = true
+		case inDown && trimmed != "":
+			return true, nil
+		}
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+}
+
+func parseFileName(file string) (int64, string, error) {
+	base := strings.TrimSuffix(file, ".sql")
+	prefix, name, _ := strings.Cut(base, "_")
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", fmt.Errorf("invalid migration file name %q: want <version>_<name>.sql", file)
+	}
+	return version, name, nil
+}
+
+func parseSQL(src string) (up, down string, err error) {
+	var upBuf, downBuf strings.Builder
+	current := &upBuf
+	var seenUp, seenDown bool
+
+	for line := range strings.Lines(src) {
+		switch strings.TrimSpace(line) {
+		case upAnnotation:
+			if seenUp || seenDown {
+				return "", "", fmt.Errorf("unexpected %q annotation", upAnnotation)
+			}
+			seenUp = true
+			current = &upBuf
+			continue
+		case downAnnotation:
+			if seenDown {
+				return "", "", fmt.Errorf("duplicate %q annotation", downAnnotation)
+			}
+			seenDown = true
+			current = &downBuf
+			continue
+		}
+		current.WriteString(line)
+	}
+	return upBuf.String(), downBuf.String(), nil
+}
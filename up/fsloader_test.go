@@ -0,0 +1,101 @@
+package up_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/x/up"
+)
+
+func recordExec(queries *[]string) func(context.Context, up.SQLConn, string) error {
+	return func(_ context.Context, _ up.SQLConn, query string) error {
+		*queries = append(*queries, query)
+		return nil
+	}
+}
+
+func TestFSLoader_Load(t *testing.T) {
+	t.Run("discovers files", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"10_add_index.sql":     {Data: []byte("CREATE INDEX i ON t (c);\n")},
+			"2_create_table.sql":   {Data: []byte("-- +up\nCREATE TABLE t (c INT);\n-- +down\nDROP TABLE t;\n")},
+			"README.md":            {Data: []byte("not a migration")},
+			"subdir/3_ignored.sql": {Data: []byte("SELECT 1;\n")},
+		}
+		loader := up.NewFSLoader(fsys, up.SQLExec)
+		migrations, err := loader.Load(context.Background())
+
+		if err != nil {
+			t.Fatalf("got %v, wanted no error", err)
+		}
+
+		var got []string
+		for _, m := range migrations {
+			got = append(got, m.Name)
+		}
+		want := []string{"create_table", "add_index"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("names mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("invalid file name", func(t *testing.T) {
+		fsys := fstest.MapFS{"create_table.sql": {Data: []byte("SELECT 1;\n")}}
+		loader := up.NewFSLoader(fsys, up.SQLExec)
+
+		if _, err := loader.Load(context.Background()); err == nil {
+			t.Error("got nil, wanted error")
+		}
+	})
+
+	t.Run("reads statements when run", func(t *testing.T) {
+		fsys := fstest.MapFS{"1_init.sql": {Data: []byte("-- +up\nOLD;\n-- +down\nOLD;\n")}}
+		var queries []string
+		loader := up.NewFSLoader(fsys, recordExec(&queries))
+		migrations, err := loader.Load(context.Background())
+		if err != nil {
+			t.Fatalf("got %v, wanted no error", err)
+		}
+
+		fsys["1_init.sql"].Data = []byte("-- +up\nCREATE TABLE t (c INT);\n-- +down\nDROP TABLE t;\n")
+
+		if err := migrations[0].Run(context.Background(), nil); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+		if err := migrations[0].Revert(context.Background(), nil); err != nil {
+			t.Fatalf("Revert() error: %v", err)
+		}
+
+		want := []string{"CREATE TABLE t (c INT);\n", "DROP TABLE t;\n"}
+		if diff := cmp.Diff(want, queries); diff != "" {
+			t.Errorf("queries mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("missing down section", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"1_init.sql":  {Data: []byte("CREATE TABLE t (c INT);\n")},
+			"2_empty.sql": {Data: []byte("-- +up\nCREATE TABLE u (c INT);\n-- +down\n\n")},
+		}
+		var queries []string
+		loader := up.NewFSLoader(fsys, recordExec(&queries))
+		migrations, err := loader.Load(context.Background())
+		if err != nil {
+			t.Fatalf("got %v, wanted no error", err)
+		}
+
+		for _, m := range migrations {
+			if m.Reversible() {
+				t.Errorf("migration %d is reversible, wanted irreversible", m.Version)
+			}
+		}
+		if err := migrations[0].Revert(context.Background(), nil); err == nil {
+			t.Error("got nil, wanted error")
+		}
+		if len(queries) != 0 {
+			t.Errorf("got queries %q, wanted none", queries)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package up
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimestampVersion returns t in UTC as a version number of the form
+// YYYYMMDDhhmmss, such as 20240615123001.
+func TimestampVersion(t time.Time) int64 {
+	v, _ := strconv.ParseInt(t.UTC().Format("20060102150405"), 10, 64)
+	return v
+}
+
+// NewMigrationFile creates a migration stub in dir, versioned with the current
+// time, and returns its path. The name's extension selects the stub: ".go"
+// creates a Go file declaring a [Migration], and ".sql" or no extension creates
+// a SQL file for [FSLoader]. For example, "add_users" creates
+// 20240615123001_add_users.sql. If a file in dir already uses the version,
+// such as a stub created earlier in the same second, the version is incremented
+// until it is free.
+func NewMigrationFile(dir, name string) (string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if ext == "" {
+		ext = ".sql"
+	}
+	if base == "" || strings.ContainsAny(base, `/\`) {
+		return "", fmt.Errorf("invalid migration name %q", name)
+	}
+
+	version, err := freeVersion(dir, TimestampVersion(time.Now()))
+	if err != nil {
+		return "", err
+	}
+	var content string
+	switch ext {
+	case ".sql":
+		content = sqlStub
+	case ".go":
+		pkg := filepath.Base(dir)
+		if abs, err := filepath.Abs(dir); err == nil {
+			pkg = filepath.Base(abs)
+		}
+		if !token.IsIdentifier(pkg) {
+			pkg = "migrations"
+		}
+		content = fmt.Sprintf(goStub, pkg, version, version, base)
+	default:
+		return "", fmt.Errorf("unsupported migration file extension %q", ext)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%d_%s%s", version, base, ext))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// freeVersion returns the first version from v that no file in dir uses.
+func freeVersion(dir string, v int64) (int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	used := make(map[int64]bool, len(entries))
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		if version, err := strconv.ParseInt(prefix, 10, 64); err == nil {
+			used[version] = true
+		}
+	}
+	for used[v] {
+		v++
+	}
+	return v, nil
+}
+
+const sqlStub = `-- +up
+
+-- +down
+`
+
+const goStub = `package %s
+
+import (
+	"context"
+
+	"github.com/jonathonwebb/x/up"
+)
+
+var migration%d = &up.Migration[up.SQLConn]{
+	Version: %d,
+	Name:    %q,
+	RunFunc: func(ctx context.Context, conn up.SQLConn) error {
+		return nil
+	},
+	RevertFunc: func(ctx context.Context, conn up.SQLConn) error {
+		return nil
+	},
+}
+`
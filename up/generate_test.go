@@ -0,0 +1,95 @@
+package up_test
+
+import (
+	"context"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonathonwebb/x/up"
+)
+
+func TestTimestampVersion(t *testing.T) {
+	ts := time.Date(2024, 6, 15, 14, 30, 1, 0, time.FixedZone("", 2*60*60))
+
+	if got, want := up.TimestampVersion(ts), int64(20240615123001); got != want {
+		t.Errorf("TimestampVersion(%v) = %d, want %d", ts, got, want)
+	}
+}
+
+func TestNewMigrationFile(t *testing.T) {
+	t.Run("sql", func(t *testing.T) {
+		dir := t.TempDir()
+		path, err := up.NewMigrationFile(dir, "add_users")
+		if err != nil {
+			t.Fatalf("NewMigrationFile() error: %v", err)
+		}
+		if !regexp.MustCompile(`^\d{14}_add_users\.sql$`).MatchString(filepath.Base(path)) {
+			t.Errorf("unexpected file name %q", filepath.Base(path))
+		}
+
+		migrations, err := up.NewFSLoader(os.DirFS(dir), up.SQLExec).Load(context.Background())
+		if err != nil {
+			t.Fatalf("failed to load stub: %v", err)
+		}
+		if len(migrations) != 1 || migrations[0].Name != "add_users" {
+			t.Errorf("unexpected migrations: %+v", migrations)
+		}
+	})
+
+	t.Run("go", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "migrations")
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		path, err := up.NewMigrationFile(dir, "add_users.go")
+		if err != nil {
+			t.Fatalf("NewMigrationFile() error: %v", err)
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+		if err != nil {
+			t.Fatalf("stub does not parse: %v", err)
+		}
+		if f.Name.Name != "migrations" {
+			t.Errorf("package = %q, want %q", f.Name.Name, "migrations")
+		}
+	})
+
+	t.Run("same_second", func(t *testing.T) {
+		dir := t.TempDir()
+		seen := make(map[string]bool)
+		for range 3 {
+			path, err := up.NewMigrationFile(dir, "add_users")
+			if err != nil {
+				t.Fatalf("NewMigrationFile() error: %v", err)
+			}
+			version, _, _ := strings.Cut(filepath.Base(path), "_")
+			if seen[version] {
+				t.Errorf("version %s reused", version)
+			}
+			seen[version] = true
+		}
+
+		migrations, err := up.NewFSLoader(os.DirFS(dir), up.SQLExec).Load(context.Background())
+		if err != nil {
+			t.Fatalf("failed to load stubs: %v", err)
+		}
+		if len(migrations) != 3 {
+			t.Errorf("want 3 migrations, got %d", len(migrations))
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, name := range []string{"", ".sql", "a/b", "add_users.txt"} {
+			if _, err := up.NewMigrationFile(t.TempDir(), name); err == nil {
+				t.Errorf("NewMigrationFile(%q) = nil, want error", name)
+			}
+		}
+	})
+}
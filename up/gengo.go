@@ -0,0 +1,127 @@
+package up
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// GenerateGo returns Go source for package pkg that declares the SQL migration
+// files in fsys as a variable of type []*Migration[SQLConn] named name, so the
+// SQL is compiled into the binary without parsing it at run time. Files are
+// named and annotated as for [FSLoader], but unlike FSLoader every file is
+// parsed up front, and a file without a down section generates a migration
+// with no revert function.
+//
+// The upgen command wraps GenerateGo for use with go:generate.
+func GenerateGo(fsys fs.FS, pkg, name string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+	if !token.IsIdentifier(name) {
+		return nil, fmt.Errorf("invalid variable name %q", name)
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	type genMigration struct {
+		Version  int64
+		Name     string
+		Up, Down string
+	}
+	var migrations []genMigration
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		version, migrationName, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		up, down, err := parseSQL(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if strings.TrimSpace(up) == "" {
+			return nil, fmt.Errorf("%s: no %q section", entry.Name(), upAnnotation)
+		}
+		if strings.TrimSpace(down) == "" {
+			down = ""
+		}
+		migrations = append(migrations, genMigration{version, migrationName, up, down})
+	}
+
+	slices.SortFunc(migrations, func(a, b genMigration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version: %d", migrations[i].Version)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := goTmpl.Execute(&buf, map[string]any{
+		"Package":    pkg,
+		"Var":        name,
+		"Migrations": migrations,
+	}); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+var goTmpl = template.Must(template.New("").Funcs(template.FuncMap{
+	"quote": quoteGo,
+}).Parse(`// Code generated by upgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+
+	"github.com/jonathonwebb/x/up"
+)
+
+var {{.Var}} = []*up.Migration[up.SQLConn]{
+{{- range .Migrations}}
+	{
+		Version: {{.Version}},
+		Name: {{printf "%q" .Name}},
+		RunFunc: func(ctx context.Context, conn up.SQLConn) error {
+			return up.SQLExec(ctx, conn, {{quote .Up}})
+		},
+		{{- if .Down}}
+		RevertFunc: func(ctx context.Context, conn up.SQLConn) error {
+			return up.SQLExec(ctx, conn, {{quote .Down}})
+		},
+		{{- end}}
+	},
+{{- end}}
+}
+`))
+
+// quoteGo returns s as a Go string literal, preferring a raw string literal so
+// generated SQL stays readable.
+func quoteGo(s string) string {
+	if !strings.Contains(s, "`") && !strings.Contains(s, "\r") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,107 @@
+package up_test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/x/up"
+)
+
+func TestGenerateGo(t *testing.T) {
+	t.Run("generates migrations", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"10_add_index.sql":   {Data: []byte("CREATE INDEX i ON t (c);\n")},
+			"2_create_table.sql": {Data: []byte("-- +up\nCREATE TABLE t (c INT);\n-- +down\nDROP TABLE t;\n")},
+			"3_quoted.sql":       {Data: []byte("-- +up\nSELECT `c` FROM t;\n")},
+			"README.md":          {Data: []byte("not a migration")},
+		}
+		src, err := up.GenerateGo(fsys, "migrations", "All")
+		if err != nil {
+			t.Fatalf("got %v, wanted no error", err)
+		}
+
+		want := "// Code generated by upgen. DO NOT EDIT.\n\npackage migrations\n" +
+			`
+import (
+	"context"
+
+	"github.com/jonathonwebb/x/up"
+)
+
+var All = []*up.Migration[up.SQLConn]{
+	{
+		Version: 2,
+		Name:    "create_table",
+		RunFunc: func(ctx context.Context, conn up.SQLConn) error {
+			return up.SQLExec(ctx, conn, ` + "`CREATE TABLE t (c INT);\n`" + `)
+		},
+		RevertFunc: func(ctx context.Context, conn up.SQLConn) error {
+			return up.SQLExec(ctx, conn, ` + "`DROP TABLE t;\n`" + `)
+		},
+	},
+	{
+		Version: 3,
+		Name:    "quoted",
+		RunFunc: func(ctx context.Context, conn up.SQLConn) error {
+			return up.SQLExec(ctx, conn, "SELECT ` + "`c`" + ` FROM t;\n")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "add_index",
+		RunFunc: func(ctx context.Context, conn up.SQLConn) error {
+			return up.SQLExec(ctx, conn, ` + "`CREATE INDEX i ON t (c);\n`" + `)
+		},
+	},
+}
+`
+		if diff := cmp.Diff(want, string(src)); diff != "" {
+			t.Errorf("source mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		pkg     string
+		wantErr string
+	}{
+		{
+			name:    "invalid package",
+			fsys:    fstest.MapFS{},
+			pkg:     "my-pkg",
+			wantErr: "invalid package name",
+		},
+		{
+			name:    "invalid file name",
+			fsys:    fstest.MapFS{"create.sql": {Data: []byte("SELECT 1;\n")}},
+			pkg:     "migrations",
+			wantErr: "invalid migration file name",
+		},
+		{
+			name:    "empty up section",
+			fsys:    fstest.MapFS{"1_empty.sql": {Data: []byte("-- +down\nDROP TABLE t;\n")}},
+			pkg:     "migrations",
+			wantErr: "no \"-- +up\" section",
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"1_a.sql":  {Data: []byte("SELECT 1;\n")},
+				"01_b.sql": {Data: []byte("SELECT 2;\n")},
+			},
+			pkg:     "migrations",
+			wantErr: "duplicate migration version: 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := up.GenerateGo(tt.fsys, tt.pkg, "Migrations")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, wanted error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package up
+
+import (
+	"context"
+	"time"
+)
+
+// StoreHooks are callbacks invoked by a store wrapped with [InstrumentStore].
+type StoreHooks struct {
+	// Call, if set, is called after every store method returns with the
+	// method's name, such as "Insert", how long it took, and its error.
+	Call func(ctx context.Context, method string, d time.Duration, err error)
+}
+
+// InstrumentStore returns a Store that calls hooks around every method of
+// store, for debugging slow version store operations or collecting metrics.
+//
+// The returned store implements [ChecksumStore] and [InitChecker], behaving as
+// the migrator does for stores that don't. It implements [BatchStore], [LeaseStore],
+// [ForceUnlocker] and [TxStore] only if store does, so wrapping a store does
+// not change how it is migrated, and stores passed to a WithTx callback are
+// instrumented too.
+func InstrumentStore[T any](store Store[T], hooks StoreHooks) Store[T] {
+	s := &instrumentedStore[T]{store: store, hooks: hooks}
+	var caps int
+	if _, ok := store.(TxStore[T]); ok {
+		caps |= capTx
+	}
+	if _, ok := store.(BatchStore); ok {
+		caps |= capBatch
+	}
+	if _, ok := store.(LeaseStore); ok {
+		caps |= capLease
+	}
+	if _, ok := store.(ForceUnlocker); ok {
+		caps |= capForceUnlock
+	}
+	switch caps {
+	case capTx:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedTx[T]
+		}{s, instrumentedTx[T]{s}}
+	case capBatch:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedBatch[T]
+		}{s, instrumentedBatch[T]{s}}
+	case capTx | capBatch:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedTx[T]
+			instrumentedBatch[T]
+		}{s, instrumentedTx[T]{s}, instrumentedBatch[T]{s}}
+	case capLease:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedLease[T]
+		}{s, instrumentedLease[T]{s}}
+	case capTx | capLease:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedTx[T]
+			instrumentedLease[T]
+		}{s, instrumentedTx[T]{s}, instrumentedLease[T]{s}}
+	case capBatch | capLease:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedBatch[T]
+			instrumentedLease[T]
+		}{s, instrumentedBatch[T]{s}, instrumentedLease[T]{s}}
+	case capTx | capBatch | capLease:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedTx[T]
+			instrumentedBatch[T]
+			instrumentedLease[T]
+		}{s, instrumentedTx[T]{s}, instrumentedBatch[T]{s}, instrumentedLease[T]{s}}
+	case capForceUnlock:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedForceUnlock[T]
+		}{s, instrumentedForceUnlock[T]{s}}
+	case capTx | capForceUnlock:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedTx[T]
+			instrumentedForceUnlock[T]
+		}{s, instrumentedTx[T]{s}, instrumentedForceUnlock[T]{s}}
+	case capBatch | capForceUnlock:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedBatch[T]
+			instrumentedForceUnlock[T]
+		}{s, instrumentedBatch[T]{s}, instrumentedForceUnlock[T]{s}}
+	case capTx | capBatch | capForceUnlock:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedTx[T]
+			instrumentedBatch[T]
+			instrumentedForceUnlock[T]
+		}{s, instrumentedTx[T]{s}, instrumentedBatch[T]{s}, instrumentedForceUnlock[T]{s}}
+	case capLease | capForceUnlock:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedLease[T]
+			instrumentedForceUnlock[T]
+		}{s, instrumentedLease[T]{s}, instrumentedForceUnlock[T]{s}}
+	case capTx | capLease | capForceUnlock:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedTx[T]
+			instrumentedLease[T]
+			instrumentedForceUnlock[T]
+		}{s, instrumentedTx[T]{s}, instrumentedLease[T]{s}, instrumentedForceUnlock[T]{s}}
+	case capBatch | capLease | capForceUnlock:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedBatch[T]
+			instrumentedLease[T]
+			instrumentedForceUnlock[T]
+		}{s, instrumentedBatch[T]{s}, instrumentedLease[T]{s}, instrumentedForceUnlock[T]{s}}
+	case capTx | capBatch | capLease | capForceUnlock:
+		return struct {
+			*instrumentedStore[T]
+			instrumentedTx[T]
+			instrumentedBatch[T]
+			instrumentedLease[T]
+			instrumentedForceUnlock[T]
+		}{s, instrumentedTx[T]{s}, instrumentedBatch[T]{s}, instrumentedLease[T]{s}, instrumentedForceUnlock[T]{s}}
+	}
+	return s
+}
+
+// Optional store interfaces passed through by [InstrumentStore].
+const (
+	capTx = 1 << iota
+	capBatch
+	capLease
+	capForceUnlock
+)
+
+type instrumentedStore[T any] struct {
+	store Store[T]
+	hooks StoreHooks
+}
+
+var (
+	_ ChecksumStore = (*instrumentedStore[SQLConn])(nil)
+	_ InitChecker   = (*instrumentedStore[SQLConn])(nil)
+	_ TxStore[any]  = struct {
+		*instrumentedStore[any]
+		instrumentedTx[any]
+	}{}
+	_ BatchStore    = instrumentedBatch[SQLConn]{}
+	_ LeaseStore    = instrumentedLease[SQLConn]{}
+	_ ForceUnlocker = instrumentedForceUnlock[SQLConn]{}
+)
+
+func (s *instrumentedStore[T]) observe(ctx context.Context, method string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	if s.hooks.Call != nil {
+		s.hooks.Call(ctx, method, time.Since(start), err)
+	}
+	return err
+}
+
+func (s *instrumentedStore[T]) Conn() T { return s.store.Conn() }
+
+func (s *instrumentedStore[T]) Init(ctx context.Context) error {
+	return s.observe(ctx, "Init", func() error { return s.store.Init(ctx) })
+}
+
+func (s *instrumentedStore[T]) Lock(ctx context.Context) error {
+	return s.observe(ctx, "Lock", func() error { return s.store.Lock(ctx) })
+}
+
+func (s *instrumentedStore[T]) Release(ctx context.Context) error {
+	return s.observe(ctx, "Release", func() error { return s.store.Release(ctx) })
+}
+
+func (s *instrumentedStore[T]) Version(ctx context.Context) (v int64, err error) {
+	err = s.observe(ctx, "Version", func() error {
+		v, err = s.store.Version(ctx)
+		return err
+	})
+	return v, err
+}
+
+func (s *instrumentedStore[T]) History(ctx context.Context) (records []Record, err error) {
+	err = s.observe(ctx, "History", func() error {
+		records, err = s.store.History(ctx)
+		return err
+	})
+	return records, err
+}
+
+func (s *instrumentedStore[T]) Insert(ctx context.Context, v int64) error {
+	return s.observe(ctx, "Insert", func() error { return s.store.Insert(ctx, v) })
+}
+
+func (s *instrumentedStore[T]) Remove(ctx context.Context, v int64) error {
+	return s.observe(ctx, "Remove", func() error { return s.store.Remove(ctx, v) })
+}
+
+// SetChecksum does nothing if the wrapped store is not a ChecksumStore.
+func (s *instrumentedStore[T]) SetChecksum(ctx context.Context, v int64, checksum string) error {
+	cs, ok := s.store.(ChecksumStore)
+	if !ok {
+		return nil
+	}
+	return s.observe(ctx, "SetChecksum", func() error { return cs.SetChecksum(ctx, v, checksum) })
+}
+
+// Initialized reports true if the wrapped store is not an InitChecker.
+func (s *instrumentedStore[T]) Initialized(ctx context.Context) (initialized bool, err error) {
+	ic, ok := s.store.(InitChecker)
+	if !ok {
+		return true, nil
+	}
+	err = s.observe(ctx, "Initialized", func() error {
+		initialized, err = ic.Initialized(ctx)
+		return err
+	})
+	return initialized, err
+}
+
+// instrumentedTx, instrumentedBatch, instrumentedLease and
+// instrumentedForceUnlock add the methods of an optional store interface to an
+// instrumentedStore whose wrapped store implements it.
+
+type instrumentedTx[T any] struct{ s *instrumentedStore[T] }
+
+func (x instrumentedTx[T]) WithTx(ctx context.Context, fn func(context.Context, Store[T]) error) error {
+	return x.s.observe(ctx, "WithTx", func() error {
+		return x.s.store.(TxStore[T]).WithTx(ctx, func(ctx context.Context, store Store[T]) error {
+			return fn(ctx, InstrumentStore(store, x.s.hooks))
+		})
+	})
+}
+
+type instrumentedBatch[T any] struct{ s *instrumentedStore[T] }
+
+func (x instrumentedBatch[T]) InsertMany(ctx context.Context, versions []int64) error {
+	return x.s.observe(ctx, "InsertMany", func() error { return x.s.store.(BatchStore).InsertMany(ctx, versions) })
+}
+
+func (x instrumentedBatch[T]) RemoveMany(ctx context.Context, versions []int64) error {
+	return x.s.observe(ctx, "RemoveMany", func() error { return x.s.store.(BatchStore).RemoveMany(ctx, versions) })
+}
+
+type instrumentedLease[T any] struct{ s *instrumentedStore[T] }
+
+func (x instrumentedLease[T]) LockLease(ctx context.Context, ttl time.Duration) error {
+	return x.s.observe(ctx, "LockLease", func() error { return x.s.store.(LeaseStore).LockLease(ctx, ttl) })
+}
+
+func (x instrumentedLease[T]) RenewLease(ctx context.Context, ttl time.Duration) error {
+	return x.s.observe(ctx, "RenewLease", func() error { return x.s.store.(LeaseStore).RenewLease(ctx, ttl) })
+}
+
+type instrumentedForceUnlock[T any] struct{ s *instrumentedStore[T] }
+
+func (x instrumentedForceUnlock[T]) ForceUnlock(ctx context.Context) error {
+	return x.s.observe(ctx, "ForceUnlock", func() error { return x.s.store.(ForceUnlocker).ForceUnlock(ctx) })
+}
@@ -0,0 +1,192 @@
+package up_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonathonwebb/x/up"
+)
+
+func TestInstrumentStore(t *testing.T) {
+	t.Run("observes calls", func(t *testing.T) {
+		var methods []string
+		var failed []string
+		inner := &fakeStore{}
+		inner.InsertFunc = func(ctx context.Context, v int64, s *fakeStore) error {
+			if v == 2 {
+				return errors.New("insert error")
+			}
+			return nil
+		}
+		store := up.InstrumentStore[up.SQLConn](inner, up.StoreHooks{
+			Call: func(ctx context.Context, method string, d time.Duration, err error) {
+				methods = append(methods, method)
+				if err != nil {
+					failed = append(failed, method)
+				}
+			},
+		})
+		if _, ok := store.(up.TxStore[up.SQLConn]); ok {
+			t.Error("instrumented store should not implement TxStore")
+		}
+		if _, ok := store.(up.BatchStore); ok {
+			t.Error("instrumented store should not implement BatchStore")
+		}
+		if _, ok := store.(up.LeaseStore); ok {
+			t.Error("instrumented store should not implement LeaseStore")
+		}
+		if _, ok := store.(up.ForceUnlocker); ok {
+			t.Error("instrumented store should not implement ForceUnlocker")
+		}
+
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: createMigrations(1, 2),
+		}
+		if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err == nil {
+			t.Fatal("expected error")
+		}
+
+		want := []string{"Init", "Lock", "Version", "Insert", "Insert", "Release"}
+		if !slices.Equal(want, methods) {
+			t.Errorf("methods mismatch\nwant: %v\ngot:  %v", want, methods)
+		}
+		if want := []string{"Version", "Insert"}; !slices.Equal(want, failed) {
+			t.Errorf("failed methods mismatch\nwant: %v\ngot:  %v", want, failed)
+		}
+	})
+
+	t.Run("instruments transactions", func(t *testing.T) {
+		var methods []string
+		inner := &txStore{fakeStore: &fakeStore{}}
+		store := up.InstrumentStore[up.SQLConn](inner, up.StoreHooks{
+			Call: func(ctx context.Context, method string, d time.Duration, err error) {
+				methods = append(methods, method)
+			},
+		})
+
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: createMigrations(1),
+			Atomic:  true,
+		}
+		if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := []string{"Init", "Lock", "Version", "Insert", "WithTx", "Release"}
+		if !slices.Equal(want, methods) {
+			t.Errorf("methods mismatch\nwant: %v\ngot:  %v", want, methods)
+		}
+		if inner.txCalls != 1 {
+			t.Errorf("txCalls: want 1, got %d", inner.txCalls)
+		}
+	})
+
+	t.Run("batches only if the store does", func(t *testing.T) {
+		var methods []string
+		inner := &batchStore{fakeStore: &fakeStore{Versions: []int64{1, 2, 3}}}
+		store := up.InstrumentStore[up.SQLConn](inner, up.StoreHooks{
+			Call: func(ctx context.Context, method string, d time.Duration, err error) {
+				methods = append(methods, method)
+			},
+		})
+
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: createMigrations(1),
+		}
+		report, err := migrator.Repair(context.Background(), up.RepairOptions{DeleteOrphans: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if want := []int64{2, 3}; !slices.Equal(want, report.Deleted) {
+			t.Errorf("deleted mismatch\nwant: %v\ngot:  %v", want, report.Deleted)
+		}
+		if !slices.Contains(methods, "RemoveMany") {
+			t.Errorf("methods %v should contain RemoveMany", methods)
+		}
+		if len(inner.removed) != 1 {
+			t.Errorf("removed batches: want 1, got %d", len(inner.removed))
+		}
+	})
+
+	t.Run("renews leases", func(t *testing.T) {
+		var mu sync.Mutex
+		var methods []string
+		inner := &leaseStore{fakeStore: &fakeStore{}}
+		store := up.InstrumentStore[up.SQLConn](inner, up.StoreHooks{
+			Call: func(ctx context.Context, method string, d time.Duration, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				methods = append(methods, method)
+			},
+		})
+		if _, ok := store.(up.BatchStore); ok {
+			t.Error("instrumented store should not implement BatchStore")
+		}
+
+		migrations := createMigrations(1)
+		migrations[0].RunFunc = func(ctx context.Context, _ up.SQLConn) error {
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:     store,
+			Sources:   migrations,
+			LockLease: 6 * time.Millisecond,
+		}
+		if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if !slices.Contains(methods, "LockLease") || slices.Contains(methods, "Lock") {
+			t.Errorf("methods %v should lock with LockLease", methods)
+		}
+		if inner.renewals.Load() == 0 {
+			t.Error("want lease renewals, got none")
+		}
+	})
+
+	t.Run("force unlocks", func(t *testing.T) {
+		var methods []string
+		inner := &forceUnlockStore{fakeStore: &fakeStore{Locked: true}}
+		store := up.InstrumentStore[up.SQLConn](inner, up.StoreHooks{
+			Call: func(ctx context.Context, method string, d time.Duration, err error) {
+				methods = append(methods, method)
+			},
+		})
+
+		fu, ok := store.(up.ForceUnlocker)
+		if !ok {
+			t.Fatal("instrumented store should implement ForceUnlocker")
+		}
+		if err := fu.ForceUnlock(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if inner.forceUnlockCalls != 1 {
+			t.Errorf("forceUnlockCalls: want 1, got %d", inner.forceUnlockCalls)
+		}
+		if want := []string{"ForceUnlock"}; !slices.Equal(want, methods) {
+			t.Errorf("methods mismatch\nwant: %v\ngot:  %v", want, methods)
+		}
+	})
+}
+
+type forceUnlockStore struct {
+	*fakeStore
+	forceUnlockCalls int
+}
+
+func (s *forceUnlockStore) ForceUnlock(ctx context.Context) error {
+	s.forceUnlockCalls++
+	s.Locked = false
+	return nil
+}
@@ -0,0 +1,58 @@
+package up
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// minLeaseRenewal is the shortest interval between lease renewals, so that a
+// tiny LockLease does not renew in a busy loop.
+const minLeaseRenewal = time.Millisecond
+
+// lock acquires the version store lock. If LockLease is set and the store
+// implements [LeaseStore], the lease is renewed in the background until the
+// returned unlock function is called, and the returned context is canceled if
+// a renewal fails. Unlock does not release the lock.
+func (m *Migrator[T]) lock(ctx context.Context) (context.Context, func(), error) {
+	ls, ok := m.Store.(LeaseStore)
+	if !ok || m.LockLease <= 0 {
+		if err := m.Store.Lock(ctx); err != nil {
+			return nil, nil, err
+		}
+		return ctx, func() {}, nil
+	}
+
+	if err := ls.LockLease(ctx, m.LockLease); err != nil {
+		return nil, nil, err
+	}
+
+	lCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(max(m.LockLease/3, minLeaseRenewal))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-lCtx.Done():
+				return
+			case <-ticker.C:
+				if err := ls.RenewLease(lCtx, m.LockLease); err != nil {
+					if lCtx.Err() == nil {
+						err = fmt.Errorf("failed to renew version store lock lease: %w", err)
+						m.log("%v", err)
+						cancel(err)
+					}
+					return
+				}
+				m.debug("renewed version store lock lease")
+			}
+		}
+	}()
+
+	return lCtx, func() {
+		cancel(nil)
+		<-done
+	}, nil
+}
@@ -1,25 +1,65 @@
 package up
 
-import "context"
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+)
 
 // A Loader provides a way to load migrations from a source.
-type Loader interface {
-	Load(context.Context) ([]*Migration, error)
+type Loader[T any] interface {
+	Load(context.Context) ([]*Migration[T], error)
 }
 
 // FuncLoader is a Loader that loads a slice of Migrations.
-type FuncLoader struct {
-	migrations []*Migration
+type FuncLoader[T any] struct {
+	migrations []*Migration[T]
 }
 
 // NewFuncLoader creates a new FuncLoader with the given migrations.
-func NewFuncLoader(migrations ...*Migration) *FuncLoader {
-	return &FuncLoader{
+func NewFuncLoader[T any](migrations ...*Migration[T]) *FuncLoader[T] {
+	return &FuncLoader[T]{
 		migrations: migrations,
 	}
 }
 
 // Load loads the migrations from the FuncLoader.
-func (l *FuncLoader) Load(ctx context.Context) ([]*Migration, error) {
+func (l *FuncLoader[T]) Load(ctx context.Context) ([]*Migration[T], error) {
 	return l.migrations, nil
 }
+
+// MultiLoader is a Loader that merges the migrations of several Loaders.
+type MultiLoader[T any] struct {
+	loaders []Loader[T]
+}
+
+// NewMultiLoader creates a new MultiLoader with the given loaders.
+func NewMultiLoader[T any](loaders ...Loader[T]) *MultiLoader[T] {
+	return &MultiLoader[T]{
+		loaders: loaders,
+	}
+}
+
+// Load loads the migrations from every loader and returns them sorted by
+// version. It returns an error if two loaders provide the same version.
+func (l *MultiLoader[T]) Load(ctx context.Context) ([]*Migration[T], error) {
+	var migrations []*Migration[T]
+	for _, loader := range l.loaders {
+		loaded, err := loader.Load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, loaded...)
+	}
+
+	slices.SortStableFunc(migrations, func(a, b *Migration[T]) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version: %d", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
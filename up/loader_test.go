@@ -2,6 +2,7 @@ package up_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -10,22 +11,22 @@ import (
 
 func TestFuncLoader_Load(t *testing.T) {
 	t.Run("empty loader", func(t *testing.T) {
-		loader := up.NewFuncLoader()
+		loader := up.NewFuncLoader[up.SQLConn]()
 		migrations, err := loader.Load(context.Background())
 
 		if err != nil {
 			t.Errorf("got %v, wanted no error", err)
 		}
 
-		var want []*up.Migration
+		var want []*up.Migration[up.SQLConn]
 		if diff := cmp.Diff(want, migrations); diff != "" {
 			t.Errorf("migrations mismatch (-want +got):\n%s", diff)
 		}
 	})
 
 	t.Run("loader with migrations", func(t *testing.T) {
-		migration1 := &up.Migration{Version: 1, Name: "First migration"}
-		migration2 := &up.Migration{Version: 2, Name: "Second migration"}
+		migration1 := &up.Migration[up.SQLConn]{Version: 1, Name: "First migration"}
+		migration2 := &up.Migration[up.SQLConn]{Version: 2, Name: "Second migration"}
 
 		loader := up.NewFuncLoader(migration1, migration2)
 		migrations, err := loader.Load(context.Background())
@@ -34,9 +35,57 @@ func TestFuncLoader_Load(t *testing.T) {
 			t.Errorf("got %v, wanted no error", err)
 		}
 
-		want := []*up.Migration{migration1, migration2}
+		want := []*up.Migration[up.SQLConn]{migration1, migration2}
 		if diff := cmp.Diff(want, migrations); diff != "" {
 			t.Errorf("migrations mismatch (-want +got):\n%s", diff)
 		}
 	})
 }
+
+func TestMultiLoader_Load(t *testing.T) {
+	migration1 := &up.Migration[up.SQLConn]{Version: 1, Name: "First migration"}
+	migration2 := &up.Migration[up.SQLConn]{Version: 2, Name: "Second migration"}
+	migration3 := &up.Migration[up.SQLConn]{Version: 3, Name: "Third migration"}
+
+	t.Run("merges by version", func(t *testing.T) {
+		loader := up.NewMultiLoader[up.SQLConn](
+			up.NewFuncLoader(migration3, migration1),
+			up.NewFuncLoader(migration2),
+		)
+		migrations, err := loader.Load(context.Background())
+
+		if err != nil {
+			t.Errorf("got %v, wanted no error", err)
+		}
+
+		want := []*up.Migration[up.SQLConn]{migration1, migration2, migration3}
+		if diff := cmp.Diff(want, migrations); diff != "" {
+			t.Errorf("migrations mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("rejects duplicates", func(t *testing.T) {
+		loader := up.NewMultiLoader[up.SQLConn](
+			up.NewFuncLoader(migration1, migration2),
+			up.NewFuncLoader(&up.Migration[up.SQLConn]{Version: 2, Name: "Other migration"}),
+		)
+		migrations, err := loader.Load(context.Background())
+
+		if err == nil {
+			t.Errorf("got %v, wanted error", migrations)
+		}
+	})
+
+	t.Run("loader error", func(t *testing.T) {
+		loader := up.NewMultiLoader[up.SQLConn](errLoader{})
+		if _, err := loader.Load(context.Background()); err == nil {
+			t.Error("got nil, wanted error")
+		}
+	})
+}
+
+type errLoader struct{}
+
+func (errLoader) Load(context.Context) ([]*up.Migration[up.SQLConn], error) {
+	return nil, errors.New("load error")
+}
@@ -2,32 +2,55 @@ package up
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 )
 
 // A Migration represents a schema change operation. Version indicates the
 // migration's order in the change sequence. The Run and Revert functions
-// are used to apply and revert the migration, respectively.
-type Migration struct {
-	Version    int64
-	Name       string
-	RunFunc    func(context.Context, *sql.DB) error
-	RevertFunc func(context.Context, *sql.DB) error
+// are used to apply and revert the migration, respectively. Checksum optionally
+// identifies the migration's contents; it is recorded by stores that implement
+// [ChecksumStore] and compared by [Migrator.Verify]. Irreversible marks a
+// migration that must never be reverted, even if it has a revert function.
+//
+// Snapshot marks a migration that replaces every migration up to and including
+// its version, so long histories can be collapsed into one step. A store with
+// no applied versions runs the latest snapshot instead of the migrations it
+// replaces; stores that already reached the snapshot's version skip it.
+// Reverting a snapshot also removes the replaced versions from the store.
+//
+// Tags restrict the migration to migrators with a matching tag, such as seed
+// data that should only be applied in development. A migration without tags is
+// always applied.
+//
+// T is the type of the connection handle provided by the [Store].
+type Migration[T any] struct {
+	Version      int64
+	Name         string
+	Checksum     string
+	Irreversible bool
+	Snapshot     bool
+	Tags         []string
+	RunFunc      func(context.Context, T) error
+	RevertFunc   func(context.Context, T) error
+}
+
+// Reversible reports whether the migration can be reverted.
+func (m *Migration[T]) Reversible() bool {
+	return !m.Irreversible && m.RevertFunc != nil
 }
 
 // Run applies the migration to the database.
-func (m *Migration) Run(ctx context.Context, db *sql.DB) error {
+func (m *Migration[T]) Run(ctx context.Context, conn T) error {
 	if m.RunFunc == nil {
 		return fmt.Errorf("migration %q (%d) has no run function", m.Name, m.Version)
 	}
-	return m.RunFunc(ctx, db)
+	return m.RunFunc(ctx, conn)
 }
 
 // Revert reverses the database migration.
-func (m *Migration) Revert(ctx context.Context, db *sql.DB) error {
+func (m *Migration[T]) Revert(ctx context.Context, conn T) error {
 	if m.RevertFunc == nil {
 		return fmt.Errorf("migration %q (%d) has no revert function", m.Name, m.Version)
 	}
-	return m.RevertFunc(ctx, db)
+	return m.RevertFunc(ctx, conn)
 }
@@ -2,7 +2,6 @@ package up_test
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"strings"
 	"testing"
@@ -13,16 +12,16 @@ import (
 func TestMigration_Run(t *testing.T) {
 	tests := []struct {
 		name      string
-		migration *up.Migration
+		migration *up.Migration[up.SQLConn]
 		wantErr   bool
 		errMsg    string
 	}{
 		{
 			name: "success",
-			migration: &up.Migration{
+			migration: &up.Migration[up.SQLConn]{
 				Version: 1,
 				Name:    "add_users_table",
-				RunFunc: func(ctx context.Context, db *sql.DB) error {
+				RunFunc: func(ctx context.Context, db up.SQLConn) error {
 					return nil
 				},
 			},
@@ -30,10 +29,10 @@ func TestMigration_Run(t *testing.T) {
 		},
 		{
 			name: "explicit_failure",
-			migration: &up.Migration{
+			migration: &up.Migration[up.SQLConn]{
 				Version: 2,
 				Name:    "add_posts_table",
-				RunFunc: func(ctx context.Context, db *sql.DB) error {
+				RunFunc: func(ctx context.Context, db up.SQLConn) error {
 					return errors.New("migration failed")
 				},
 			},
@@ -42,7 +41,7 @@ func TestMigration_Run(t *testing.T) {
 		},
 		{
 			name: "nil_run_function",
-			migration: &up.Migration{
+			migration: &up.Migration[up.SQLConn]{
 				Version: 3,
 				Name:    "add_comments_table",
 				RunFunc: nil,
@@ -79,16 +78,16 @@ func TestMigration_Run(t *testing.T) {
 func TestMigration_Revert(t *testing.T) {
 	tests := []struct {
 		name      string
-		migration *up.Migration
+		migration *up.Migration[up.SQLConn]
 		wantErr   bool
 		errMsg    string
 	}{
 		{
 			name: "success",
-			migration: &up.Migration{
+			migration: &up.Migration[up.SQLConn]{
 				Version: 1,
 				Name:    "add_users_table",
-				RevertFunc: func(ctx context.Context, db *sql.DB) error {
+				RevertFunc: func(ctx context.Context, db up.SQLConn) error {
 					return nil
 				},
 			},
@@ -96,10 +95,10 @@ func TestMigration_Revert(t *testing.T) {
 		},
 		{
 			name: "explicit_failure",
-			migration: &up.Migration{
+			migration: &up.Migration[up.SQLConn]{
 				Version: 2,
 				Name:    "add_posts_table",
-				RevertFunc: func(ctx context.Context, db *sql.DB) error {
+				RevertFunc: func(ctx context.Context, db up.SQLConn) error {
 					return errors.New("rollback failed")
 				},
 			},
@@ -108,7 +107,7 @@ func TestMigration_Revert(t *testing.T) {
 		},
 		{
 			name: "nil_revert_function",
-			migration: &up.Migration{
+			migration: &up.Migration[up.SQLConn]{
 				Version:    4,
 				Name:       "add_categories_table",
 				RevertFunc: nil,
@@ -150,10 +149,10 @@ func TestMigration_ContextPropagation(t *testing.T) {
 	t.Run("run_function", func(t *testing.T) {
 		ctx := context.WithValue(context.Background(), key, expectedValue)
 
-		migration := &up.Migration{
+		migration := &up.Migration[up.SQLConn]{
 			Version: 1,
 			Name:    "context_test_run",
-			RunFunc: func(ctx context.Context, db *sql.DB) error {
+			RunFunc: func(ctx context.Context, db up.SQLConn) error {
 				gotValue := ctx.Value(key)
 				if gotValue != expectedValue {
 					t.Errorf("ctx.Value(%q) = %v, want %v", key, gotValue, expectedValue)
@@ -170,10 +169,10 @@ func TestMigration_ContextPropagation(t *testing.T) {
 	t.Run("revert_function", func(t *testing.T) {
 		ctx := context.WithValue(context.Background(), key, expectedValue)
 
-		migration := &up.Migration{
+		migration := &up.Migration[up.SQLConn]{
 			Version: 1,
 			Name:    "context_test_revert",
-			RevertFunc: func(ctx context.Context, db *sql.DB) error {
+			RevertFunc: func(ctx context.Context, db up.SQLConn) error {
 				gotValue := ctx.Value(key)
 				if gotValue != expectedValue {
 					t.Errorf("ctx.Value(%q) = %v, want %v", key, gotValue, expectedValue)
@@ -187,3 +186,37 @@ func TestMigration_ContextPropagation(t *testing.T) {
 		}
 	})
 }
+
+func TestMigration_Reversible(t *testing.T) {
+	noop := func(context.Context, up.SQLConn) error { return nil }
+
+	tests := []struct {
+		name      string
+		migration *up.Migration[up.SQLConn]
+		want      bool
+	}{
+		{
+			name:      "with_revert_function",
+			migration: &up.Migration[up.SQLConn]{Version: 1, RevertFunc: noop},
+			want:      true,
+		},
+		{
+			name:      "nil_revert_function",
+			migration: &up.Migration[up.SQLConn]{Version: 1},
+			want:      false,
+		},
+		{
+			name:      "irreversible",
+			migration: &up.Migration[up.SQLConn]{Version: 1, RevertFunc: noop, Irreversible: true},
+			want:      false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.migration.Reversible(); got != test.want {
+				t.Errorf("Migration(%d).Reversible() = %v, want %v", test.migration.Version, got, test.want)
+			}
+		})
+	}
+}
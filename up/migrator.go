@@ -2,10 +2,13 @@
 package up
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"slices"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -14,40 +17,111 @@ const (
 )
 
 // A Migrator stores migrations and provides methods to apply or revert them.
-type Migrator struct {
-	Store     Store
-	Sources   []*Migration
+//
+// A Migrator runs one Run, Revert, Baseline or Repair call at a time; a call
+// made while another is in progress returns [ErrBusy] instead of sharing its
+// version store lock.
+//
+// T is the type of the connection handle provided by the [Store].
+type Migrator[T any] struct {
+	Store     Store[T]
+	Sources   []*Migration[T]
 	LogFunc   func(s string)
 	DebugFunc func(s string)
 
 	HoldLockOnFailure bool
+
+	// AllowOutOfOrder makes Run also apply unapplied migrations below the
+	// current version, such as those merged from a long-lived branch, in
+	// ascending order before continuing upward.
+	AllowOutOfOrder bool
+
+	// Skip lists versions that are known not to run, such as historical
+	// migrations that fail on a fresh database. Run records them as applied
+	// without running them, and Revert removes them without reverting them.
+	Skip []int64
+
+	// Tags selects the tagged migrations to apply. Migrations without tags
+	// are always applied; tagged migrations are applied only if one of their
+	// tags is listed here.
+	Tags []string
+
+	// PerMigrationTimeout bounds the time each migration's run or revert
+	// function may take. Zero means no limit.
+	PerMigrationTimeout time.Duration
+
+	// Tracer, if set, observes each operation and migration.
+	Tracer Tracer
+
+	// LockLease, if set and the store implements [LeaseStore], is the lease
+	// duration of the version store lock. The lease is renewed in the
+	// background while the lock is held, and the operation is canceled if a
+	// renewal fails. A lock held by HoldLockOnFailure expires with its lease.
+	LockLease time.Duration
+
+	// Atomic runs each Run or Revert batch in a single transaction, so a
+	// failure leaves the database at its starting version. The store must
+	// implement [TxStore].
+	Atomic bool
+
+	// Dumper, if set along with SchemaFile, writes the database schema to
+	// SchemaFile after Run or Revert successfully changes the version store.
+	Dumper     Dumper
+	SchemaFile string
+
+	// Progress, if set, is called before each migration of a Run or Revert
+	// with the number of migrations done and the total planned, and once more
+	// with done equal to total and a nil migration when the plan completes.
+	Progress func(done, total int, current *Migration[T])
+
+	busy int32 // set while an operation holds the migrator; see acquire
 }
 
-func (m *Migrator) log(f string, a ...any) {
+func (m *Migrator[T]) log(f string, a ...any) {
 	if m.LogFunc != nil {
 		m.LogFunc(fmt.Sprintf(f, a...))
 	}
 }
 
-func (m *Migrator) debug(f string, a ...any) {
+func (m *Migrator[T]) debug(f string, a ...any) {
 	if m.DebugFunc != nil {
 		m.DebugFunc(fmt.Sprintf(f, a...))
 	}
 }
 
-func (m *Migrator) check() error {
+// skipped reports whether the migration is listed in Skip.
+func (m *Migrator[T]) skipped(migration *Migration[T]) bool {
+	return slices.Contains(m.Skip, migration.Version)
+}
+
+// acquire marks the migrator busy until the returned function is called. It
+// returns [ErrBusy] if the migrator is already busy.
+func (m *Migrator[T]) acquire() (func(), error) {
+	if !atomic.CompareAndSwapInt32(&m.busy, 0, 1) {
+		return nil, ErrBusy
+	}
+	return func() { atomic.StoreInt32(&m.busy, 0) }, nil
+}
+
+func (m *Migrator[T]) progress(done, total int, current *Migration[T]) {
+	if m.Progress != nil && total > 0 {
+		m.Progress(done, total, current)
+	}
+}
+
+func (m *Migrator[T]) check() error {
 	var prev int64 = 0
 	seen := map[int64]bool{}
 
 	for _, migration := range m.Sources {
 		if migration.Version <= 0 {
-			return fmt.Errorf("migration version must be > 0, got %d", migration.Version)
+			return &ValidationError{migration.Version, fmt.Sprintf("migration version must be > 0, got %d", migration.Version)}
 		}
 		if migration.Version < prev {
-			return fmt.Errorf("migration order: %d found after %d", migration.Version, prev)
+			return &ValidationError{migration.Version, fmt.Sprintf("migration order: %d found after %d", migration.Version, prev)}
 		}
 		if _, ok := seen[migration.Version]; ok {
-			return fmt.Errorf("duplicate migration version: %d", migration.Version)
+			return &ValidationError{migration.Version, fmt.Sprintf("duplicate migration version: %d", migration.Version)}
 		} else {
 			seen[migration.Version] = true
 		}
@@ -57,22 +131,265 @@ func (m *Migrator) check() error {
 	return nil
 }
 
+// active reports whether the migration's tags match the migrator's tags.
+func (m *Migrator[T]) active(migration *Migration[T]) bool {
+	if len(migration.Tags) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(migration.Tags, func(tag string) bool {
+		return slices.Contains(m.Tags, tag)
+	})
+}
+
+// exec calls fn with a context bounded by PerMigrationTimeout, if set, and
+// reports the migration to the Tracer.
+func (m *Migrator[T]) exec(ctx context.Context, op Operation, migration *Migration[T], fn func(context.Context) error) (err error) {
+	if m.Tracer != nil {
+		var end func(error)
+		ctx, end = m.Tracer.StartMigration(ctx, op, migration.Version, migration.Name)
+		defer func() { end(err) }()
+	}
+
+	if m.PerMigrationTimeout <= 0 {
+		return fn(ctx)
+	}
+
+	mCtx, cancel := context.WithTimeout(ctx, m.PerMigrationTimeout)
+	defer cancel()
+
+	err = fn(mCtx)
+	if err != nil && errors.Is(mCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return fmt.Errorf("migration %d timed out after %s: %w", migration.Version, m.PerMigrationTimeout, err)
+	}
+	return err
+}
+
+// reverting returns the applied migrations to revert to move the store from
+// its current version down to the target version, in revert order. It returns
+// an [*IrreversibleError] if the plan includes migrations that cannot be
+// reverted.
+func (m *Migrator[T]) reverting(ctx context.Context, to int64) ([]*Migration[T], error) {
+	history, err := m.Store.History(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version store history: %w", err)
+	}
+
+	var toRevert []*Migration[T]
+	var blocking []int64
+	for _, record := range slices.Backward(history) {
+		if record.Version <= to {
+			break
+		}
+		migration, ok := m.findSource(record.Version)
+		if !ok {
+			return nil, fmt.Errorf("invalid version store state: %w", &MissingMigrationError{record.Version})
+		}
+		if !migration.Reversible() && !m.skipped(migration) {
+			blocking = append(blocking, record.Version)
+		}
+		toRevert = append(toRevert, migration)
+		if migration.Snapshot {
+			// Reverting a snapshot removes the versions it replaced.
+			break
+		}
+	}
+	if len(blocking) > 0 {
+		return nil, &IrreversibleError{Versions: blocking}
+	}
+	return toRevert, nil
+}
+
+// pending returns the source migrations to apply to move the store from the
+// current version to the target version.
+//
+// If applied is non-nil, migrations below the current version that are not in
+// applied are included too, in ascending order with the others, except those
+// replaced by an applied snapshot.
+func (m *Migrator[T]) pending(current, to int64, applied map[int64]bool) ([]*Migration[T], error) {
+	var snapshot int64
+	for _, migration := range m.Sources {
+		if migration.Snapshot && applied[migration.Version] {
+			snapshot = max(snapshot, migration.Version)
+		}
+	}
+
+	var toApply []*Migration[T]
+	for _, migration := range m.Sources {
+		if to != RunTargetLatest && migration.Version > to {
+			continue
+		}
+		if migration.Version <= current {
+			if applied == nil || applied[migration.Version] || migration.Snapshot || migration.Version <= snapshot {
+				continue
+			}
+			m.debug("filling gap at migration %d", migration.Version)
+		}
+		if !m.active(migration) {
+			m.debug("skipping migration %d: tags %v not selected", migration.Version, migration.Tags)
+			continue
+		}
+		if migration.Snapshot {
+			if current > 0 {
+				return nil, fmt.Errorf("version store at %d predates snapshot migration %d", current, migration.Version)
+			}
+			toApply = toApply[:0]
+		}
+		toApply = append(toApply, migration)
+	}
+	return toApply, nil
+}
+
+// appliedVersions returns the set of versions recorded in the version store
+// when AllowOutOfOrder is set, for use with pending. Otherwise it returns nil.
+func (m *Migrator[T]) appliedVersions(ctx context.Context) (map[int64]bool, error) {
+	if !m.AllowOutOfOrder {
+		return nil, nil
+	}
+	history, err := m.Store.History(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version store history: %w", err)
+	}
+	applied := make(map[int64]bool, len(history))
+	for _, record := range history {
+		applied[record.Version] = true
+	}
+	return applied, nil
+}
+
+// removeReplaced removes the versions replaced by a reverted snapshot from the
+// version store.
+func (m *Migrator[T]) removeReplaced(ctx context.Context, store Store[T], snapshot int64) error {
+	history, err := store.History(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get version store history: %w", err)
+	}
+	var replaced []int64
+	for _, record := range history {
+		if record.Version >= snapshot {
+			break
+		}
+		replaced = append(replaced, record.Version)
+	}
+	_, err = m.removeMany(ctx, store, replaced)
+	return err
+}
+
+// findSource returns the source migration with the given version.
+func (m *Migrator[T]) findSource(version int64) (*Migration[T], bool) {
+	idx, ok := slices.BinarySearchFunc(m.Sources, version, func(s *Migration[T], t int64) int {
+		return cmp.Compare(s.Version, t)
+	})
+	if !ok {
+		return nil, false
+	}
+	return m.Sources[idx], true
+}
+
+// record inserts the migration's version into the store, along with its
+// checksum if the store supports them.
+func (m *Migrator[T]) record(ctx context.Context, store Store[T], migration *Migration[T]) error {
+	if err := store.Insert(ctx, migration.Version); err != nil {
+		return fmt.Errorf("failed to insert migration %d: %w", migration.Version, err)
+	}
+
+	if cs, ok := store.(ChecksumStore); ok && migration.Checksum != "" {
+		if err := cs.SetChecksum(ctx, migration.Version, migration.Checksum); err != nil {
+			return fmt.Errorf("failed to record checksum for migration %d: %w", migration.Version, err)
+		}
+	}
+	return nil
+}
+
+// recordMany records each migration, in a single batch if the store
+// implements [BatchStore]. It returns the number of migrations recorded.
+func (m *Migrator[T]) recordMany(ctx context.Context, store Store[T], migrations []*Migration[T]) (int, error) {
+	bs, ok := store.(BatchStore)
+	if !ok || len(migrations) < 2 {
+		for i, migration := range migrations {
+			if err := m.record(ctx, store, migration); err != nil {
+				return i, err
+			}
+		}
+		return len(migrations), nil
+	}
+
+	versions := make([]int64, len(migrations))
+	for i, migration := range migrations {
+		versions[i] = migration.Version
+	}
+	if err := bs.InsertMany(ctx, versions); err != nil {
+		return 0, fmt.Errorf("failed to insert migrations %v: %w", versions, err)
+	}
+
+	if cs, ok := store.(ChecksumStore); ok {
+		for _, migration := range migrations {
+			if migration.Checksum == "" {
+				continue
+			}
+			if err := cs.SetChecksum(ctx, migration.Version, migration.Checksum); err != nil {
+				return len(migrations), fmt.Errorf("failed to record checksum for migration %d: %w", migration.Version, err)
+			}
+		}
+	}
+	return len(migrations), nil
+}
+
+// removeMany removes each version from the store, in a single batch if the
+// store implements [BatchStore]. It returns the number of versions removed.
+func (m *Migrator[T]) removeMany(ctx context.Context, store Store[T], versions []int64) (int, error) {
+	bs, ok := store.(BatchStore)
+	if !ok || len(versions) < 2 {
+		for i, v := range versions {
+			if err := store.Remove(ctx, v); err != nil {
+				return i, fmt.Errorf("failed to delete migration %d from version store: %w", v, err)
+			}
+		}
+		return len(versions), nil
+	}
+
+	if err := bs.RemoveMany(ctx, versions); err != nil {
+		return 0, fmt.Errorf("failed to delete migrations %v from version store: %w", versions, err)
+	}
+	return len(versions), nil
+}
+
 // Run applies migrations up to and including the specified version. The special
-// value -1 applies all pending migrations.
-func (m *Migrator) Run(ctx context.Context, to int64) (n int, err error) {
+// value -1 applies all pending migrations. The returned Result is non-nil even
+// when err is not, and describes the migrations applied before the failure.
+// Invalid sources are reported as a [*ValidationError].
+func (m *Migrator[T]) Run(ctx context.Context, to int64) (res *Result, err error) {
+	res = &Result{}
+	if m.Tracer != nil {
+		var end func(*Result, error)
+		ctx, end = m.Tracer.StartOperation(ctx, OperationRun, to)
+		defer func() { end(res, err) }()
+	}
+
+	done, err := m.acquire()
+	if err != nil {
+		return res, err
+	}
+	defer done()
+
 	if err := m.check(); err != nil {
-		return 0, fmt.Errorf("invalid sources: %w", err)
+		return res, fmt.Errorf("invalid sources: %w", err)
+	}
+
+	if _, ok := m.Store.(TxStore[T]); m.Atomic && !ok {
+		return res, errors.New("atomic mode requires a store that implements TxStore")
 	}
 
 	if err := m.Store.Init(ctx); err != nil {
-		return 0, fmt.Errorf("failed to init version store: %w", err)
+		return res, fmt.Errorf("failed to init version store: %w", err)
 	}
 
-	if err := m.Store.Lock(ctx); err != nil {
-		return 0, fmt.Errorf("failed to get version store lock: %w", err)
+	lockCtx, unlock, err := m.lock(ctx)
+	if err != nil {
+		return res, fmt.Errorf("failed to get version store lock: %w", err)
 	}
 	shouldRelease := true
-	defer func() {
+	defer func(ctx context.Context) {
+		unlock()
 		if shouldRelease {
 			m.debug("releasing version store lock")
 			if rlErr := m.Store.Release(ctx); rlErr != nil {
@@ -81,85 +398,130 @@ func (m *Migrator) Run(ctx context.Context, to int64) (n int, err error) {
 		} else {
 			m.debug("holding lock due to failure")
 		}
-	}()
+	}(ctx)
+	ctx = lockCtx
 
 	var remoteVersion int64 = 0
 	remoteVersion, err = m.Store.Version(ctx)
 	if err != nil {
 		if !errors.Is(err, ErrInitialVersion) {
-			return 0, fmt.Errorf("failed to get version store state: %w", err)
+			return res, fmt.Errorf("failed to get version store state: %w", err)
 		}
 	}
 	m.debug("current version: %d", remoteVersion)
+	res.Version = remoteVersion
 
-	var toApply []*Migration
-	for _, migration := range m.Sources {
-		if migration.Version > remoteVersion && (to == RunTargetLatest || migration.Version <= to) {
-			toApply = append(toApply, migration)
-		}
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	toApply, err := m.pending(remoteVersion, to, applied)
+	if err != nil {
+		return res, err
 	}
 
 	if len(toApply) == 0 {
-		return 0, nil
+		return res, nil
 	}
 
 	if m.HoldLockOnFailure {
 		shouldRelease = false
 	}
 
-	n = 0
-	for _, migration := range toApply {
+	if err := m.batch(ctx, res, func(ctx context.Context, store Store[T]) error {
+		return m.apply(ctx, store, toApply, res)
+	}); err != nil {
+		return res, err
+	}
+
+	if err := m.dumpSchema(ctx, res); err != nil {
+		return res, err
+	}
+
+	shouldRelease = true
+	return res, nil
+}
+
+// apply runs each migration in toApply and records it in store.
+func (m *Migrator[T]) apply(ctx context.Context, store Store[T], toApply []*Migration[T], res *Result) error {
+	for i, migration := range toApply {
+		m.progress(i, len(toApply), migration)
 		m.debug("applying migration: %d", migration.Version)
 
-		if err := migration.Run(ctx, m.Store.DB()); err != nil {
-			return n, fmt.Errorf("failed to apply migration %d: %w", migration.Version, err)
+		if m.skipped(migration) {
+			m.log("skipping migration %d", migration.Version)
+			if err := m.record(ctx, store, migration); err != nil {
+				return err
+			}
+			res.addSkipped(migration.Version, migration.Name)
+			res.Version = max(res.Version, migration.Version)
+			continue
 		}
 
-		if err := m.Store.Insert(ctx, migration.Version); err != nil {
-			return n, fmt.Errorf("failed to insert migration %d: %w", migration.Version, err)
+		start := time.Now()
+		if err := m.exec(ctx, OperationRun, migration, func(ctx context.Context) error {
+			return migration.Run(ctx, store.Conn())
+		}); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", migration.Version, err)
 		}
 
-		n += 1
-	}
+		if err := m.record(ctx, store, migration); err != nil {
+			return err
+		}
 
-	shouldRelease = true
-	return n, nil
+		res.add(migration.Version, migration.Name, time.Since(start))
+		res.Version = max(res.Version, migration.Version)
+	}
+	m.progress(len(toApply), len(toApply), nil)
+	return nil
 }
 
 // Revert reverses migrations down to and excluding the provided version. The
-// special value 0 reverts all migrations.
-func (m *Migrator) Revert(ctx context.Context, to int64) (n int, err error) {
+// special value 0 reverts all migrations. The returned Result is non-nil even
+// when err is not, and describes the migrations reverted before the failure.
+// Invalid sources are reported as a [*ValidationError], and a target or store
+// version without a source migration as a [*MissingMigrationError].
+func (m *Migrator[T]) Revert(ctx context.Context, to int64) (res *Result, err error) {
+	res = &Result{}
+	if m.Tracer != nil {
+		var end func(*Result, error)
+		ctx, end = m.Tracer.StartOperation(ctx, OperationRevert, to)
+		defer func() { end(res, err) }()
+	}
+
+	done, err := m.acquire()
+	if err != nil {
+		return res, err
+	}
+	defer done()
+
 	if err := m.check(); err != nil {
-		return 0, fmt.Errorf("invalid sources: %w", err)
+		return res, fmt.Errorf("invalid sources: %w", err)
 	}
 
-	migrationCmpFunc := func(s *Migration, t int64) int {
-		if s.Version < t {
-			return -1
-		}
-		if s.Version > t {
-			return 1
-		}
-		return 0
+	if _, ok := m.Store.(TxStore[T]); m.Atomic && !ok {
+		return res, errors.New("atomic mode requires a store that implements TxStore")
 	}
 
 	if to != RevertTargetInitial {
-		_, ok := slices.BinarySearchFunc(m.Sources, to, migrationCmpFunc)
-		if !ok {
-			return 0, fmt.Errorf("missing target version migration: %d", to)
+		if _, ok := m.findSource(to); !ok {
+			return res, fmt.Errorf("invalid target version: %w", &MissingMigrationError{to})
 		}
 	}
 
 	if err := m.Store.Init(ctx); err != nil {
-		return 0, fmt.Errorf("failed to init version store: %w", err)
+		return res, fmt.Errorf("failed to init version store: %w", err)
 	}
 
-	if err := m.Store.Lock(ctx); err != nil {
-		return 0, fmt.Errorf("failed to get version store lock: %w", err)
+	lockCtx, unlock, err := m.lock(ctx)
+	if err != nil {
+		return res, fmt.Errorf("failed to get version store lock: %w", err)
 	}
 
 	shouldRelease := true
-	defer func() {
+	defer func(ctx context.Context) {
+		unlock()
 		if shouldRelease {
 			m.debug("releasing version store lock")
 			if rlErr := m.Store.Release(ctx); rlErr != nil {
@@ -168,57 +530,102 @@ func (m *Migrator) Revert(ctx context.Context, to int64) (n int, err error) {
 		} else {
 			m.debug("holding lock due to failure")
 		}
-	}()
+	}(ctx)
+	ctx = lockCtx
 
 	var remoteVersion int64
 
 	remoteVersion, err = m.Store.Version(ctx)
 	if err != nil {
 		if errors.Is(err, ErrInitialVersion) {
-			return 0, nil
+			return res, nil
 		}
-		return 0, fmt.Errorf("failed to get version store state: %w", err)
+		return res, fmt.Errorf("failed to get version store state: %w", err)
 	}
 	m.debug("current version: %d", remoteVersion)
+	res.Version = remoteVersion
+
+	toRevert, err := m.reverting(ctx, to)
+	if err != nil {
+		return res, err
+	}
 
 	if m.HoldLockOnFailure {
 		shouldRelease = false
 	}
 
-	n = 0
-	for {
-		if remoteVersion <= to {
-			m.debug("reached target version %d, stopping", to)
-			break
-		}
+	if err := m.batch(ctx, res, func(ctx context.Context, store Store[T]) error {
+		return m.revert(ctx, store, toRevert, res)
+	}); err != nil {
+		return res, err
+	}
 
-		idx, ok := slices.BinarySearchFunc(m.Sources, remoteVersion, migrationCmpFunc)
-		if !ok {
-			return n, fmt.Errorf("missing remote version migration: %d", remoteVersion)
-		}
+	if err := m.dumpSchema(ctx, res); err != nil {
+		return res, err
+	}
 
-		migration := m.Sources[idx]
+	shouldRelease = true
+	return res, nil
+}
+
+// revert reverts the planned migrations in store, in order.
+func (m *Migrator[T]) revert(ctx context.Context, store Store[T], toRevert []*Migration[T], res *Result) error {
+	for i, migration := range toRevert {
+		m.progress(i, len(toRevert), migration)
 		m.debug("reverting migration: %d", migration.Version)
 
-		if err := migration.Revert(ctx, m.Store.DB()); err != nil {
-			return n, fmt.Errorf("failed to revert migration %d: %w", migration.Version, err)
+		start := time.Now()
+		skip := m.skipped(migration)
+		if skip {
+			m.log("skipping revert of migration %d", migration.Version)
+		} else if err := m.exec(ctx, OperationRevert, migration, func(ctx context.Context) error {
+			return migration.Revert(ctx, store.Conn())
+		}); err != nil {
+			return fmt.Errorf("failed to revert migration %d: %w", migration.Version, err)
+		}
+
+		if err := store.Remove(ctx, migration.Version); err != nil {
+			return fmt.Errorf("failed to delete migration %d from version store: %w", migration.Version, err)
 		}
 
-		if err := m.Store.Remove(ctx, migration.Version); err != nil {
-			return n, fmt.Errorf("failed to delete migration %d from version store: %w", migration.Version, err)
+		if migration.Snapshot {
+			if err := m.removeReplaced(ctx, store, migration.Version); err != nil {
+				return err
+			}
 		}
 
-		n++
+		if skip {
+			res.addSkipped(migration.Version, migration.Name)
+		} else {
+			res.add(migration.Version, migration.Name, time.Since(start))
+		}
 
-		remoteVersion, err = m.Store.Version(ctx)
+		remoteVersion, err := store.Version(ctx)
 		if err != nil {
 			if errors.Is(err, ErrInitialVersion) {
-				return n, nil
+				res.Version = 0
+				continue
 			}
-			return n, fmt.Errorf("failed to get version store state: %w", err)
+			return fmt.Errorf("failed to get version store state: %w", err)
 		}
+		res.Version = remoteVersion
 	}
+	m.progress(len(toRevert), len(toRevert), nil)
+	return nil
+}
 
-	shouldRelease = true
-	return n, nil
+// batch calls fn with the version store. When Atomic is set, fn is called with
+// a store bound to a single transaction, and res is restored to its starting
+// state if the transaction is rolled back.
+func (m *Migrator[T]) batch(ctx context.Context, res *Result, fn func(context.Context, Store[T]) error) error {
+	if !m.Atomic {
+		return fn(ctx, m.Store)
+	}
+
+	start := *res
+	err := m.Store.(TxStore[T]).WithTx(ctx, fn)
+	if err != nil {
+		*res = start
+	}
+	return err
 }
@@ -2,147 +2,34 @@ package up_test
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
 	"slices"
-	"sync"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/jonathonwebb/x/up"
+	"github.com/jonathonwebb/x/up/uptest"
 )
 
-type fakeStore struct {
-	versions []int64
-	applied  []int64
-	reverted []int64
-	locked   bool
-	mu       sync.Mutex
-
-	initCalls    int
-	lockCalls    int
-	releaseCalls int
-	versionCalls int
-	insertCalls  int
-	removeCalls  int
-
-	initFunc    func(context.Context, *fakeStore) error
-	lockFunc    func(context.Context, *fakeStore) error
-	releaseFunc func(context.Context, *fakeStore) error
-	versionFunc func(context.Context, *fakeStore) (int64, error)
-	insertFunc  func(context.Context, int64, *fakeStore) error
-	removeFunc  func(context.Context, int64, *fakeStore) error
-}
-
-func defaultInitFunc(_ context.Context, _ *fakeStore) error {
-	return nil
-}
-
-func defaultLockFunc(_ context.Context, s *fakeStore) error {
-	if s.locked {
-		return up.ErrLocked
-	}
-	s.mu.Lock()
-	s.locked = true
-	s.mu.Unlock()
-	return nil
-}
-
-func defaultReleaseFunc(_ context.Context, s *fakeStore) error {
-	s.mu.Lock()
-	s.locked = false
-	s.mu.Unlock()
-	return nil
-}
-
-func defaultVersionFunc(_ context.Context, s *fakeStore) (int64, error) {
-	if len(s.versions) == 0 {
-		return 0, up.ErrInitialVersion
-	}
-	return s.versions[len(s.versions)-1], nil
-}
-
-func defaultInsertFunc(_ context.Context, v int64, s *fakeStore) error {
-	s.mu.Lock()
-	s.versions = append(s.versions, v)
-	s.applied = append(s.applied, v)
-	s.mu.Unlock()
-	return nil
-}
-
-func defaultRemoveFunc(_ context.Context, v int64, s *fakeStore) error {
-	if len(s.versions) > 0 {
-		s.mu.Lock()
-		s.versions = s.versions[:len(s.versions)-1]
-		s.reverted = append(s.reverted, v)
-		s.mu.Unlock()
-	}
-	return nil
-}
-
-func (s *fakeStore) DB() *sql.DB { return nil }
-
-func (s *fakeStore) Init(ctx context.Context) error {
-	s.initCalls += 1
-	if s.initFunc != nil {
-		return s.initFunc(ctx, s)
-	}
-	return defaultInitFunc(ctx, s)
-}
-
-func (s *fakeStore) Lock(ctx context.Context) error {
-	s.lockCalls += 1
-	if s.lockFunc != nil {
-		return s.lockFunc(ctx, s)
-	}
-	return defaultLockFunc(ctx, s)
-}
-
-func (s *fakeStore) Release(ctx context.Context) error {
-	s.releaseCalls += 1
-	if s.releaseFunc != nil {
-		return s.releaseFunc(ctx, s)
-	}
-	return defaultReleaseFunc(ctx, s)
-}
-
-func (s *fakeStore) Version(ctx context.Context) (int64, error) {
-	s.versionCalls += 1
-	if s.versionFunc != nil {
-		return s.versionFunc(ctx, s)
-	}
-	return defaultVersionFunc(ctx, s)
-}
+type fakeStore = uptest.Store[up.SQLConn]
 
-func (s *fakeStore) Insert(ctx context.Context, v int64) error {
-	s.insertCalls += 1
-	if s.insertFunc != nil {
-		return s.insertFunc(ctx, v, s)
-	}
-	return defaultInsertFunc(ctx, v, s)
-}
-
-func (s *fakeStore) Remove(ctx context.Context, v int64) error {
-	s.removeCalls += 1
-	if s.removeFunc != nil {
-		return s.removeFunc(ctx, v, s)
-	}
-	return defaultRemoveFunc(ctx, v, s)
-}
-
-func noopMigration(ctx context.Context, db *sql.DB) error { return nil }
+func noopMigration(ctx context.Context, db up.SQLConn) error { return nil }
 
-func errorMigration(msg string) func(context.Context, *sql.DB) error {
-	return func(_ context.Context, _ *sql.DB) error {
+func errorMigration(msg string) func(context.Context, up.SQLConn) error {
+	return func(_ context.Context, _ up.SQLConn) error {
 		return errors.New(msg)
 	}
 }
 
 // Helper to create standard migrations for testing
-func createMigrations(versions ...int64) []*up.Migration {
-	migrations := make([]*up.Migration, len(versions))
+func createMigrations(versions ...int64) []*up.Migration[up.SQLConn] {
+	migrations := make([]*up.Migration[up.SQLConn], len(versions))
 	for i, v := range versions {
-		migrations[i] = &up.Migration{
+		migrations[i] = &up.Migration[up.SQLConn]{
 			Version:    v,
 			RunFunc:    noopMigration,
 			RevertFunc: noopMigration,
@@ -155,7 +42,7 @@ func TestMigrator_Run(t *testing.T) {
 	tests := []struct {
 		name              string
 		initialVersions   []int64
-		migrations        []*up.Migration
+		migrations        []*up.Migration[up.SQLConn]
 		target            int64
 		holdLockOnFailure bool
 		storeConfig       func(*fakeStore) // Configure store behavior
@@ -169,7 +56,7 @@ func TestMigrator_Run(t *testing.T) {
 		{
 			name:            "no_migrations_no_target",
 			initialVersions: []int64{},
-			migrations:      []*up.Migration{},
+			migrations:      []*up.Migration[up.SQLConn]{},
 			target:          0,
 			wantVersions:    []int64{},
 			wantApplied:     []int64{},
@@ -237,7 +124,7 @@ func TestMigrator_Run(t *testing.T) {
 		{
 			name:            "zero_version",
 			initialVersions: []int64{},
-			migrations: []*up.Migration{
+			migrations: []*up.Migration[up.SQLConn]{
 				{Version: 0, RunFunc: noopMigration, RevertFunc: noopMigration},
 				{Version: 1, RunFunc: noopMigration, RevertFunc: noopMigration},
 			},
@@ -249,7 +136,7 @@ func TestMigrator_Run(t *testing.T) {
 		{
 			name:            "negative_version",
 			initialVersions: []int64{},
-			migrations: []*up.Migration{
+			migrations: []*up.Migration[up.SQLConn]{
 				{Version: -1, RunFunc: noopMigration, RevertFunc: noopMigration},
 				{Version: 1, RunFunc: noopMigration, RevertFunc: noopMigration},
 			},
@@ -261,7 +148,7 @@ func TestMigrator_Run(t *testing.T) {
 		{
 			name:            "misordered_migrations",
 			initialVersions: []int64{},
-			migrations: []*up.Migration{
+			migrations: []*up.Migration[up.SQLConn]{
 				{Version: 3, RunFunc: noopMigration, RevertFunc: noopMigration},
 				{Version: 1, RunFunc: noopMigration, RevertFunc: noopMigration},
 				{Version: 2, RunFunc: noopMigration, RevertFunc: noopMigration},
@@ -274,7 +161,7 @@ func TestMigrator_Run(t *testing.T) {
 		{
 			name:            "duplicate_versions",
 			initialVersions: []int64{},
-			migrations: []*up.Migration{
+			migrations: []*up.Migration[up.SQLConn]{
 				{Version: 1, RunFunc: noopMigration, RevertFunc: noopMigration},
 				{Version: 2, RunFunc: noopMigration, RevertFunc: noopMigration},
 				{Version: 2, RunFunc: noopMigration, RevertFunc: noopMigration},
@@ -292,7 +179,7 @@ func TestMigrator_Run(t *testing.T) {
 			migrations:      createMigrations(1, 2, 3),
 			target:          3,
 			storeConfig: func(s *fakeStore) {
-				s.initFunc = func(ctx context.Context, _ *fakeStore) error {
+				s.InitFunc = func(ctx context.Context, _ *fakeStore) error {
 					return fmt.Errorf("init error")
 				}
 			},
@@ -306,7 +193,7 @@ func TestMigrator_Run(t *testing.T) {
 			migrations:      createMigrations(1, 2, 3),
 			target:          3,
 			storeConfig: func(s *fakeStore) {
-				s.lockFunc = func(ctx context.Context, _ *fakeStore) error {
+				s.LockFunc = func(ctx context.Context, _ *fakeStore) error {
 					return fmt.Errorf("lock error")
 				}
 			},
@@ -320,7 +207,7 @@ func TestMigrator_Run(t *testing.T) {
 			migrations:      createMigrations(1),
 			target:          1,
 			storeConfig: func(s *fakeStore) {
-				s.locked = true
+				s.Locked = true
 			},
 			wantErr:      true,
 			wantVersions: []int64{},
@@ -333,7 +220,7 @@ func TestMigrator_Run(t *testing.T) {
 			migrations:      createMigrations(1, 2, 3),
 			target:          3,
 			storeConfig: func(s *fakeStore) {
-				s.versionFunc = func(ctx context.Context, s *fakeStore) (int64, error) {
+				s.VersionFunc = func(ctx context.Context, s *fakeStore) (int64, error) {
 					return 0, fmt.Errorf("version error")
 				}
 			},
@@ -347,11 +234,11 @@ func TestMigrator_Run(t *testing.T) {
 			migrations:      createMigrations(1, 2, 3),
 			target:          3,
 			storeConfig: func(s *fakeStore) {
-				s.insertFunc = func(ctx context.Context, v int64, s *fakeStore) error {
-					if s.insertCalls == 2 { // Fail on second migration
+				s.InsertFunc = func(ctx context.Context, v int64, s *fakeStore) error {
+					if s.InsertCalls == 2 { // Fail on second migration
 						return fmt.Errorf("insert error")
 					}
-					return defaultInsertFunc(ctx, v, s)
+					return uptest.DefaultInsert(ctx, v, s)
 				}
 			},
 			wantErr:      true,
@@ -364,7 +251,7 @@ func TestMigrator_Run(t *testing.T) {
 			migrations:      createMigrations(1, 2, 3),
 			target:          3,
 			storeConfig: func(s *fakeStore) {
-				s.releaseFunc = func(ctx context.Context, _ *fakeStore) error {
+				s.ReleaseFunc = func(ctx context.Context, _ *fakeStore) error {
 					return fmt.Errorf("release error")
 				}
 			},
@@ -378,7 +265,7 @@ func TestMigrator_Run(t *testing.T) {
 		{
 			name:            "migration_run_error",
 			initialVersions: []int64{},
-			migrations: []*up.Migration{
+			migrations: []*up.Migration[up.SQLConn]{
 				{Version: 1, RunFunc: noopMigration, RevertFunc: noopMigration},
 				{Version: 2, RunFunc: errorMigration("run error"), RevertFunc: noopMigration},
 				{Version: 3, RunFunc: noopMigration, RevertFunc: noopMigration},
@@ -391,7 +278,7 @@ func TestMigrator_Run(t *testing.T) {
 		{
 			name:            "migration_run_error_hold_lock",
 			initialVersions: []int64{},
-			migrations: []*up.Migration{
+			migrations: []*up.Migration[up.SQLConn]{
 				{Version: 1, RunFunc: noopMigration, RevertFunc: noopMigration},
 				{Version: 2, RunFunc: errorMigration("run error"), RevertFunc: noopMigration},
 				{Version: 3, RunFunc: noopMigration, RevertFunc: noopMigration},
@@ -408,13 +295,13 @@ func TestMigrator_Run(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			store := &fakeStore{
-				versions: slices.Clone(tt.initialVersions),
+				Versions: slices.Clone(tt.initialVersions),
 			}
 			if tt.storeConfig != nil {
 				tt.storeConfig(store)
 			}
 
-			migrator := &up.Migrator{
+			migrator := &up.Migrator[up.SQLConn]{
 				Store:             store,
 				Sources:           tt.migrations,
 				HoldLockOnFailure: tt.holdLockOnFailure,
@@ -429,14 +316,14 @@ func TestMigrator_Run(t *testing.T) {
 				t.Errorf("expected no error but got: %v", err)
 			}
 
-			if !slices.Equal(tt.wantVersions, store.versions) {
-				t.Errorf("versions mismatch\nwant: %v\ngot:  %v", tt.wantVersions, store.versions)
+			if !slices.Equal(tt.wantVersions, store.Versions) {
+				t.Errorf("versions mismatch\nwant: %v\ngot:  %v", tt.wantVersions, store.Versions)
 			}
-			if !slices.Equal(tt.wantApplied, store.applied) {
-				t.Errorf("applied mismatch\nwant: %v\ngot:  %v", tt.wantApplied, store.applied)
+			if !slices.Equal(tt.wantApplied, store.Applied) {
+				t.Errorf("applied mismatch\nwant: %v\ngot:  %v", tt.wantApplied, store.Applied)
 			}
-			if tt.wantLocked != store.locked {
-				t.Errorf("lock state mismatch: want %v, got %v", tt.wantLocked, store.locked)
+			if tt.wantLocked != store.Locked {
+				t.Errorf("lock state mismatch: want %v, got %v", tt.wantLocked, store.Locked)
 			}
 		})
 	}
@@ -446,7 +333,7 @@ func TestMigrator_Revert(t *testing.T) {
 	tests := []struct {
 		name              string
 		initialVersions   []int64
-		migrations        []*up.Migration
+		migrations        []*up.Migration[up.SQLConn]
 		target            int64
 		holdLockOnFailure bool
 		storeConfig       func(*fakeStore)
@@ -529,7 +416,7 @@ func TestMigrator_Revert(t *testing.T) {
 			migrations:      createMigrations(1, 2, 3),
 			target:          1,
 			storeConfig: func(s *fakeStore) {
-				s.versionFunc = func(ctx context.Context, s *fakeStore) (int64, error) {
+				s.VersionFunc = func(ctx context.Context, s *fakeStore) (int64, error) {
 					return 0, fmt.Errorf("version error")
 				}
 			},
@@ -543,11 +430,11 @@ func TestMigrator_Revert(t *testing.T) {
 			migrations:      createMigrations(1, 2, 3),
 			target:          1,
 			storeConfig: func(s *fakeStore) {
-				s.versionFunc = func(ctx context.Context, s *fakeStore) (int64, error) {
-					if s.versionCalls > 1 {
+				s.VersionFunc = func(ctx context.Context, s *fakeStore) (int64, error) {
+					if s.VersionCalls > 1 {
 						return 0, fmt.Errorf("version error")
 					}
-					return defaultVersionFunc(ctx, s)
+					return uptest.DefaultVersion(ctx, s)
 				}
 			},
 			wantErr:      true,
@@ -560,11 +447,11 @@ func TestMigrator_Revert(t *testing.T) {
 			migrations:      createMigrations(1, 2, 3),
 			target:          1,
 			storeConfig: func(s *fakeStore) {
-				s.removeFunc = func(ctx context.Context, v int64, s *fakeStore) error {
-					if s.removeCalls == 2 { // Fail on second remove
+				s.RemoveFunc = func(ctx context.Context, v int64, s *fakeStore) error {
+					if s.RemoveCalls == 2 { // Fail on second remove
 						return fmt.Errorf("remove error")
 					}
-					return defaultRemoveFunc(ctx, v, s)
+					return uptest.DefaultRemove(ctx, v, s)
 				}
 			},
 			wantErr:      true,
@@ -574,7 +461,7 @@ func TestMigrator_Revert(t *testing.T) {
 		{
 			name:            "migration_revert_error",
 			initialVersions: []int64{1, 2, 3},
-			migrations: []*up.Migration{
+			migrations: []*up.Migration[up.SQLConn]{
 				{Version: 1, RunFunc: noopMigration, RevertFunc: noopMigration},
 				{Version: 2, RunFunc: noopMigration, RevertFunc: errorMigration("revert error")},
 				{Version: 3, RunFunc: noopMigration, RevertFunc: noopMigration},
@@ -587,7 +474,7 @@ func TestMigrator_Revert(t *testing.T) {
 		{
 			name:            "migration_revert_error_hold_lock",
 			initialVersions: []int64{1, 2, 3},
-			migrations: []*up.Migration{
+			migrations: []*up.Migration[up.SQLConn]{
 				{Version: 1, RunFunc: noopMigration, RevertFunc: noopMigration},
 				{Version: 2, RunFunc: noopMigration, RevertFunc: errorMigration("revert error")},
 				{Version: 3, RunFunc: noopMigration, RevertFunc: noopMigration},
@@ -604,13 +491,13 @@ func TestMigrator_Revert(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			store := &fakeStore{
-				versions: slices.Clone(tt.initialVersions),
+				Versions: slices.Clone(tt.initialVersions),
 			}
 			if tt.storeConfig != nil {
 				tt.storeConfig(store)
 			}
 
-			migrator := &up.Migrator{
+			migrator := &up.Migrator[up.SQLConn]{
 				Store:             store,
 				Sources:           tt.migrations,
 				HoldLockOnFailure: tt.holdLockOnFailure,
@@ -625,21 +512,21 @@ func TestMigrator_Revert(t *testing.T) {
 				t.Errorf("expected no error but got: %v", err)
 			}
 
-			if !slices.Equal(tt.wantVersions, store.versions) {
-				t.Errorf("versions mismatch\nwant: %v\ngot:  %v", tt.wantVersions, store.versions)
+			if !slices.Equal(tt.wantVersions, store.Versions) {
+				t.Errorf("versions mismatch\nwant: %v\ngot:  %v", tt.wantVersions, store.Versions)
 			}
-			if !slices.Equal(tt.wantReverted, store.reverted) {
-				t.Errorf("reverted mismatch\nwant: %v\ngot:  %v", tt.wantReverted, store.reverted)
+			if !slices.Equal(tt.wantReverted, store.Reverted) {
+				t.Errorf("reverted mismatch\nwant: %v\ngot:  %v", tt.wantReverted, store.Reverted)
 			}
-			if tt.wantLocked != store.locked {
-				t.Errorf("lock state mismatch: want %v, got %v", tt.wantLocked, store.locked)
+			if tt.wantLocked != store.Locked {
+				t.Errorf("lock state mismatch: want %v, got %v", tt.wantLocked, store.Locked)
 			}
 		})
 	}
 }
 
 func TestMigrator_ValidationConsistency(t *testing.T) {
-	invalidMigrations := [][]*up.Migration{
+	invalidMigrations := [][]*up.Migration[up.SQLConn]{
 		{
 			{Version: 0, RunFunc: noopMigration, RevertFunc: noopMigration},
 		},
@@ -659,7 +546,7 @@ func TestMigrator_ValidationConsistency(t *testing.T) {
 	for i, migrations := range invalidMigrations {
 		t.Run(fmt.Sprintf("invalid_migrations_%d", i), func(t *testing.T) {
 			store := &fakeStore{}
-			migrator := &up.Migrator{
+			migrator := &up.Migrator[up.SQLConn]{
 				Store:   store,
 				Sources: migrations,
 			}
@@ -676,7 +563,7 @@ func TestMigrator_ValidationConsistency(t *testing.T) {
 			}
 
 			// Store should not have been touched
-			if store.initCalls > 0 || store.lockCalls > 0 {
+			if store.InitCalls > 0 || store.LockCalls > 0 {
 				t.Error("Store should not be accessed when validation fails")
 			}
 		})
@@ -685,8 +572,8 @@ func TestMigrator_ValidationConsistency(t *testing.T) {
 
 func TestMigrator_InitialVersionHandling(t *testing.T) {
 	t.Run("run_from_initial_version", func(t *testing.T) {
-		store := &fakeStore{versions: []int64{}}
-		migrator := &up.Migrator{
+		store := &fakeStore{Versions: []int64{}}
+		migrator := &up.Migrator[up.SQLConn]{
 			Store:   store,
 			Sources: createMigrations(1, 2),
 		}
@@ -697,14 +584,14 @@ func TestMigrator_InitialVersionHandling(t *testing.T) {
 		}
 
 		want := []int64{1, 2}
-		if !slices.Equal(want, store.versions) {
-			t.Errorf("want %v, got %v", want, store.versions)
+		if !slices.Equal(want, store.Versions) {
+			t.Errorf("want %v, got %v", want, store.Versions)
 		}
 	})
 
 	t.Run("revert_from_initial_version", func(t *testing.T) {
-		store := &fakeStore{versions: []int64{}}
-		migrator := &up.Migrator{
+		store := &fakeStore{Versions: []int64{}}
+		migrator := &up.Migrator[up.SQLConn]{
 			Store:   store,
 			Sources: createMigrations(1, 2),
 		}
@@ -714,8 +601,8 @@ func TestMigrator_InitialVersionHandling(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if len(store.reverted) > 0 {
-			t.Errorf("expected no reversions, got %v", store.reverted)
+		if len(store.Reverted) > 0 {
+			t.Errorf("expected no reversions, got %v", store.Reverted)
 		}
 	})
 }
@@ -723,7 +610,7 @@ func TestMigrator_InitialVersionHandling(t *testing.T) {
 func TestMigrator_LockBehavior(t *testing.T) {
 	t.Run("successful_operations_release_lock", func(t *testing.T) {
 		store := &fakeStore{}
-		migrator := &up.Migrator{
+		migrator := &up.Migrator[up.SQLConn]{
 			Store:   store,
 			Sources: createMigrations(1, 2),
 		}
@@ -733,10 +620,10 @@ func TestMigrator_LockBehavior(t *testing.T) {
 			t.Fatalf("run failed: %v", err)
 		}
 
-		if store.lockCalls != 1 || store.releaseCalls != 1 {
-			t.Errorf("expected 1 lock/release call, got %d/%d", store.lockCalls, store.releaseCalls)
+		if store.LockCalls != 1 || store.ReleaseCalls != 1 {
+			t.Errorf("expected 1 lock/release call, got %d/%d", store.LockCalls, store.ReleaseCalls)
 		}
-		if store.locked {
+		if store.Locked {
 			t.Error("lock should be released after successful operation")
 		}
 
@@ -745,10 +632,10 @@ func TestMigrator_LockBehavior(t *testing.T) {
 			t.Fatalf("revert failed: %v", err)
 		}
 
-		if store.lockCalls != 2 || store.releaseCalls != 2 {
-			t.Errorf("expected 2 lock/release calls, got %d/%d", store.lockCalls, store.releaseCalls)
+		if store.LockCalls != 2 || store.ReleaseCalls != 2 {
+			t.Errorf("expected 2 lock/release calls, got %d/%d", store.LockCalls, store.ReleaseCalls)
 		}
-		if store.locked {
+		if store.Locked {
 			t.Error("lock should be released after successful operation")
 		}
 	})
@@ -766,9 +653,9 @@ func TestMigrator_LockBehavior(t *testing.T) {
 		for _, tt := range tests {
 			t.Run(tt.name, func(t *testing.T) {
 				store := &fakeStore{}
-				migrator := &up.Migrator{
+				migrator := &up.Migrator[up.SQLConn]{
 					Store: store,
-					Sources: []*up.Migration{
+					Sources: []*up.Migration[up.SQLConn]{
 						{Version: 1, RunFunc: errorMigration("test error"), RevertFunc: noopMigration},
 					},
 					HoldLockOnFailure: tt.holdLock,
@@ -779,8 +666,8 @@ func TestMigrator_LockBehavior(t *testing.T) {
 					t.Error("expected error from migration")
 				}
 
-				if store.locked != tt.wantLock {
-					t.Errorf("lock state: want %v, got %v", tt.wantLock, store.locked)
+				if store.Locked != tt.wantLock {
+					t.Errorf("lock state: want %v, got %v", tt.wantLock, store.Locked)
 				}
 			})
 		}
@@ -790,7 +677,7 @@ func TestMigrator_LockBehavior(t *testing.T) {
 func TestMigrator_StoreCallPatterns(t *testing.T) {
 	t.Run("run_call_sequence", func(t *testing.T) {
 		store := &fakeStore{}
-		migrator := &up.Migrator{
+		migrator := &up.Migrator[up.SQLConn]{
 			Store:   store,
 			Sources: createMigrations(1, 2),
 		}
@@ -800,28 +687,28 @@ func TestMigrator_StoreCallPatterns(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if store.initCalls != 1 {
-			t.Errorf("expected 1 init call, got %d", store.initCalls)
+		if store.InitCalls != 1 {
+			t.Errorf("expected 1 init call, got %d", store.InitCalls)
 		}
-		if store.lockCalls != 1 {
-			t.Errorf("expected 1 lock call, got %d", store.lockCalls)
+		if store.LockCalls != 1 {
+			t.Errorf("expected 1 lock call, got %d", store.LockCalls)
 		}
-		if store.versionCalls != 1 {
-			t.Errorf("expected 1 version call, got %d", store.versionCalls)
+		if store.VersionCalls != 1 {
+			t.Errorf("expected 1 version call, got %d", store.VersionCalls)
 		}
-		if store.insertCalls != 2 {
-			t.Errorf("expected 2 insert calls, got %d", store.insertCalls)
+		if store.InsertCalls != 2 {
+			t.Errorf("expected 2 insert calls, got %d", store.InsertCalls)
 		}
-		if store.releaseCalls != 1 {
-			t.Errorf("expected 1 release call, got %d", store.releaseCalls)
+		if store.ReleaseCalls != 1 {
+			t.Errorf("expected 1 release call, got %d", store.ReleaseCalls)
 		}
 	})
 
 	t.Run("revert_call_sequence", func(t *testing.T) {
 		store := &fakeStore{
-			versions: []int64{1, 2, 3},
+			Versions: []int64{1, 2, 3},
 		}
-		migrator := &up.Migrator{
+		migrator := &up.Migrator[up.SQLConn]{
 			Store:   store,
 			Sources: createMigrations(1, 2, 3),
 		}
@@ -832,11 +719,11 @@ func TestMigrator_StoreCallPatterns(t *testing.T) {
 		}
 
 		expectedVersionCalls := 3
-		if store.versionCalls != expectedVersionCalls {
-			t.Errorf("expected %d version calls, got %d", expectedVersionCalls, store.versionCalls)
+		if store.VersionCalls != expectedVersionCalls {
+			t.Errorf("expected %d version calls, got %d", expectedVersionCalls, store.VersionCalls)
 		}
-		if store.removeCalls != 2 {
-			t.Errorf("expected 2 remove calls, got %d", store.removeCalls)
+		if store.RemoveCalls != 2 {
+			t.Errorf("expected 2 remove calls, got %d", store.RemoveCalls)
 		}
 	})
 }
@@ -844,7 +731,7 @@ func TestMigrator_StoreCallPatterns(t *testing.T) {
 func TestMigrator_BoundaryConditions(t *testing.T) {
 	t.Run("zero_target_version", func(t *testing.T) {
 		store := &fakeStore{}
-		migrator := &up.Migrator{
+		migrator := &up.Migrator[up.SQLConn]{
 			Store:   store,
 			Sources: createMigrations(1, 2, 3),
 		}
@@ -854,14 +741,14 @@ func TestMigrator_BoundaryConditions(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if len(store.applied) > 0 {
-			t.Errorf("expected no migrations applied, got %v", store.applied)
+		if len(store.Applied) > 0 {
+			t.Errorf("expected no migrations applied, got %v", store.Applied)
 		}
 	})
 
 	t.Run("very_high_target_version", func(t *testing.T) {
 		store := &fakeStore{}
-		migrator := &up.Migrator{
+		migrator := &up.Migrator[up.SQLConn]{
 			Store:   store,
 			Sources: createMigrations(1, 2, 3),
 		}
@@ -872,14 +759,14 @@ func TestMigrator_BoundaryConditions(t *testing.T) {
 		}
 
 		want := []int64{1, 2, 3}
-		if !slices.Equal(want, store.applied) {
-			t.Errorf("want %v, got %v", want, store.applied)
+		if !slices.Equal(want, store.Applied) {
+			t.Errorf("want %v, got %v", want, store.Applied)
 		}
 	})
 
 	t.Run("single_migration", func(t *testing.T) {
-		store := &fakeStore{versions: []int64{1, 2}}
-		migrator := &up.Migrator{
+		store := &fakeStore{Versions: []int64{1, 2}}
+		migrator := &up.Migrator[up.SQLConn]{
 			Store:   store,
 			Sources: createMigrations(1, 2, 3),
 		}
@@ -890,8 +777,8 @@ func TestMigrator_BoundaryConditions(t *testing.T) {
 		}
 
 		want := []int64{3}
-		if !slices.Equal(want, store.applied) {
-			t.Errorf("want %v, got %v", want, store.applied)
+		if !slices.Equal(want, store.Applied) {
+			t.Errorf("want %v, got %v", want, store.Applied)
 		}
 	})
 }
@@ -899,7 +786,7 @@ func TestMigrator_BoundaryConditions(t *testing.T) {
 func TestMigrator_ConcurrentSafety(t *testing.T) {
 	t.Run("concurrent_lock_attempts", func(t *testing.T) {
 		store := &fakeStore{}
-		migrator := &up.Migrator{
+		migrator := &up.Migrator[up.SQLConn]{
 			Store:   store,
 			Sources: createMigrations(1),
 		}
@@ -913,7 +800,7 @@ func TestMigrator_ConcurrentSafety(t *testing.T) {
 		if err == nil {
 			t.Error("expected error when store is already locked")
 		}
-		if !store.locked {
+		if !store.Locked {
 			t.Error("store should remain locked")
 		}
 	})
@@ -922,7 +809,7 @@ func TestMigrator_ConcurrentSafety(t *testing.T) {
 func TestMigrator_Integration(t *testing.T) {
 	store := &fakeStore{}
 	migrations := createMigrations(1, 2, 3, 4, 5)
-	migrator := &up.Migrator{
+	migrator := &up.Migrator[up.SQLConn]{
 		Store:   store,
 		Sources: migrations,
 	}
@@ -943,8 +830,8 @@ func TestMigrator_Integration(t *testing.T) {
 				t.Fatalf("unexpected error: %v", err)
 			}
 
-			if !slices.Equal(step.wantVers, store.versions) {
-				t.Errorf("want %v, got %v", step.wantVers, store.versions)
+			if !slices.Equal(step.wantVers, store.Versions) {
+				t.Errorf("want %v, got %v", step.wantVers, store.Versions)
 			}
 		})
 	}
@@ -965,9 +852,1485 @@ func TestMigrator_Integration(t *testing.T) {
 				t.Fatalf("unexpected error: %v", err)
 			}
 
-			if !slices.Equal(step.wantVers, store.versions) {
-				t.Errorf("want %v, got %v", step.wantVers, store.versions)
+			if !slices.Equal(step.wantVers, store.Versions) {
+				t.Errorf("want %v, got %v", step.wantVers, store.Versions)
+			}
+		})
+	}
+}
+
+func TestMigrator_Verify(t *testing.T) {
+	tests := []struct {
+		name            string
+		initialVersions []int64
+		checksums       map[int64]string
+		migrations      []*up.Migration[up.SQLConn]
+
+		wantVersion  int64
+		wantMissing  []int64
+		wantPending  []int64
+		wantModified []int64
+		wantDrifted  bool
+	}{
+		{
+			name:            "no_drift",
+			initialVersions: []int64{1, 2},
+			migrations:      createMigrations(1, 2, 3),
+			wantVersion:     2,
+		},
+		{
+			name:            "empty_store",
+			initialVersions: []int64{},
+			migrations:      createMigrations(1, 2, 3),
+			wantVersion:     0,
+		},
+		{
+			name:            "missing_sources",
+			initialVersions: []int64{1, 2, 4},
+			migrations:      createMigrations(1, 2, 3),
+			wantVersion:     4,
+			wantMissing:     []int64{4},
+			wantPending:     []int64{3},
+			wantDrifted:     true,
+		},
+		{
+			name:            "unapplied_gap",
+			initialVersions: []int64{1, 3},
+			migrations:      createMigrations(1, 2, 3, 4),
+			wantVersion:     3,
+			wantPending:     []int64{2},
+			wantDrifted:     true,
+		},
+		{
+			name:            "started_from_snapshot",
+			initialVersions: []int64{4, 5},
+			migrations: []*up.Migration[up.SQLConn]{
+				{Version: 1, RunFunc: noopMigration},
+				{Version: 2, RunFunc: noopMigration},
+				{Version: 3, RunFunc: noopMigration},
+				{Version: 4, Snapshot: true, RunFunc: noopMigration},
+				{Version: 5, RunFunc: noopMigration},
+			},
+			wantVersion: 5,
+		},
+		{
+			name:            "modified_checksum",
+			initialVersions: []int64{1, 2},
+			checksums:       map[int64]string{1: "a", 2: "b"},
+			migrations: []*up.Migration[up.SQLConn]{
+				{Version: 1, Checksum: "a", RunFunc: noopMigration},
+				{Version: 2, Checksum: "changed", RunFunc: noopMigration},
+			},
+			wantVersion:  2,
+			wantModified: []int64{2},
+			wantDrifted:  true,
+		},
+		{
+			name:            "unrecorded_checksum",
+			initialVersions: []int64{1},
+			migrations: []*up.Migration[up.SQLConn]{
+				{Version: 1, Checksum: "a", RunFunc: noopMigration},
+			},
+			wantVersion: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{
+				Versions:  slices.Clone(tt.initialVersions),
+				Checksums: tt.checksums,
+			}
+			migrator := &up.Migrator[up.SQLConn]{
+				Store:   store,
+				Sources: tt.migrations,
+			}
+
+			report, err := migrator.Verify(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if report.Version != tt.wantVersion {
+				t.Errorf("version: want %d, got %d", tt.wantVersion, report.Version)
+			}
+			if !slices.Equal(tt.wantMissing, report.Missing) {
+				t.Errorf("missing mismatch\nwant: %v\ngot:  %v", tt.wantMissing, report.Missing)
+			}
+			if !slices.Equal(tt.wantPending, report.Pending) {
+				t.Errorf("pending mismatch\nwant: %v\ngot:  %v", tt.wantPending, report.Pending)
+			}
+			if !slices.Equal(tt.wantModified, report.Modified) {
+				t.Errorf("modified mismatch\nwant: %v\ngot:  %v", tt.wantModified, report.Modified)
+			}
+			if report.Drifted() != tt.wantDrifted {
+				t.Errorf("drifted: want %v, got %v", tt.wantDrifted, report.Drifted())
+			}
+			if store.LockCalls > 0 {
+				t.Error("Verify should not lock the store")
+			}
+		})
+	}
+
+	t.Run("records_checksums_on_run", func(t *testing.T) {
+		store := &fakeStore{}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store: store,
+			Sources: []*up.Migration[up.SQLConn]{
+				{Version: 1, Checksum: "a", RunFunc: noopMigration},
+				{Version: 2, RunFunc: noopMigration},
+			},
+		}
+
+		if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+
+		want := map[int64]string{1: "a"}
+		if !maps.Equal(want, store.Checksums) {
+			t.Errorf("checksums: want %v, got %v", want, store.Checksums)
+		}
+	})
+}
+
+func TestMigrator_Baseline(t *testing.T) {
+	tests := []struct {
+		name            string
+		initialVersions []int64
+		migrations      []*up.Migration[up.SQLConn]
+		version         int64
+		storeConfig     func(*fakeStore)
+
+		wantErr      bool
+		wantN        int
+		wantVersions []int64
+		wantLocked   bool
+	}{
+		{
+			name:            "fresh_database",
+			initialVersions: []int64{},
+			migrations:      createMigrations(1, 2, 3),
+			version:         2,
+			wantN:           2,
+			wantVersions:    []int64{1, 2},
+		},
+		{
+			name:            "partially_recorded",
+			initialVersions: []int64{1},
+			migrations:      createMigrations(1, 2, 3),
+			version:         3,
+			wantN:           2,
+			wantVersions:    []int64{1, 2, 3},
+		},
+		{
+			name:            "already_recorded",
+			initialVersions: []int64{1, 2},
+			migrations:      createMigrations(1, 2, 3),
+			version:         2,
+			wantN:           0,
+			wantVersions:    []int64{1, 2},
+		},
+		{
+			name:            "missing_version",
+			initialVersions: []int64{},
+			migrations:      createMigrations(1, 2, 4),
+			version:         3,
+			wantErr:         true,
+			wantVersions:    []int64{},
+		},
+		{
+			name:            "store_insert_error",
+			initialVersions: []int64{},
+			migrations:      createMigrations(1, 2, 3),
+			version:         3,
+			storeConfig: func(s *fakeStore) {
+				s.InsertFunc = func(ctx context.Context, v int64, s *fakeStore) error {
+					if s.InsertCalls == 2 {
+						return fmt.Errorf("insert error")
+					}
+					return uptest.DefaultInsert(ctx, v, s)
+				}
+			},
+			wantErr:      true,
+			wantN:        1,
+			wantVersions: []int64{1},
+		},
+		{
+			name:            "store_already_locked",
+			initialVersions: []int64{},
+			migrations:      createMigrations(1),
+			version:         1,
+			storeConfig: func(s *fakeStore) {
+				s.Locked = true
+			},
+			wantErr:      true,
+			wantVersions: []int64{},
+			wantLocked:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran := false
+			for _, m := range tt.migrations {
+				m.RunFunc = func(context.Context, up.SQLConn) error {
+					ran = true
+					return nil
+				}
+			}
+			store := &fakeStore{
+				Versions: slices.Clone(tt.initialVersions),
+			}
+			if tt.storeConfig != nil {
+				tt.storeConfig(store)
+			}
+			migrator := &up.Migrator[up.SQLConn]{
+				Store:   store,
+				Sources: tt.migrations,
+			}
+
+			n, err := migrator.Baseline(context.Background(), tt.version)
+
+			if tt.wantErr && err == nil {
+				t.Errorf("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("expected no error but got: %v", err)
+			}
+			if n != tt.wantN {
+				t.Errorf("n: want %d, got %d", tt.wantN, n)
+			}
+			if ran {
+				t.Error("Baseline should not run migrations")
+			}
+			if !slices.Equal(tt.wantVersions, store.Versions) {
+				t.Errorf("versions mismatch\nwant: %v\ngot:  %v", tt.wantVersions, store.Versions)
+			}
+			if tt.wantLocked != store.Locked {
+				t.Errorf("lock state mismatch: want %v, got %v", tt.wantLocked, store.Locked)
+			}
+		})
+	}
+}
+
+func TestMigrator_Repair(t *testing.T) {
+	tests := []struct {
+		name            string
+		initialVersions []int64
+		migrations      []*up.Migration[up.SQLConn]
+		opts            up.RepairOptions
+
+		wantErr      bool
+		wantDeleted  []int64
+		wantInserted []int64
+		wantVersions []int64
+	}{
+		{
+			name:            "nothing_to_do",
+			initialVersions: []int64{1, 2},
+			migrations:      createMigrations(1, 2),
+			opts:            up.RepairOptions{DeleteOrphans: true},
+			wantVersions:    []int64{1, 2},
+		},
+		{
+			name:            "delete_orphans",
+			initialVersions: []int64{1, 2, 5},
+			migrations:      createMigrations(1, 2, 3),
+			opts:            up.RepairOptions{DeleteOrphans: true},
+			wantDeleted:     []int64{5},
+			wantVersions:    []int64{1, 2},
+		},
+		{
+			name:            "keep_orphans",
+			initialVersions: []int64{1, 2, 5},
+			migrations:      createMigrations(1, 2, 3),
+			opts:            up.RepairOptions{},
+			wantVersions:    []int64{1, 2, 5},
+		},
+		{
+			name:            "mark_applied",
+			initialVersions: []int64{1},
+			migrations:      createMigrations(1, 2, 3),
+			opts:            up.RepairOptions{MarkApplied: []int64{1, 2}},
+			wantInserted:    []int64{2},
+			wantVersions:    []int64{1, 2},
+		},
+		{
+			name:            "mark_applied_missing_source",
+			initialVersions: []int64{1},
+			migrations:      createMigrations(1, 2, 3),
+			opts:            up.RepairOptions{MarkApplied: []int64{4}},
+			wantErr:         true,
+			wantVersions:    []int64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{
+				Versions: slices.Clone(tt.initialVersions),
+			}
+			migrator := &up.Migrator[up.SQLConn]{
+				Store:   store,
+				Sources: tt.migrations,
+			}
+
+			report, err := migrator.Repair(context.Background(), tt.opts)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("expected no error but got: %v", err)
+				}
+				if !slices.Equal(tt.wantDeleted, report.Deleted) {
+					t.Errorf("deleted mismatch\nwant: %v\ngot:  %v", tt.wantDeleted, report.Deleted)
+				}
+				if !slices.Equal(tt.wantInserted, report.Inserted) {
+					t.Errorf("inserted mismatch\nwant: %v\ngot:  %v", tt.wantInserted, report.Inserted)
+				}
+			}
+			if !slices.Equal(tt.wantVersions, store.Versions) {
+				t.Errorf("versions mismatch\nwant: %v\ngot:  %v", tt.wantVersions, store.Versions)
+			}
+			if store.Locked {
+				t.Error("lock should be released")
 			}
 		})
 	}
 }
+
+func TestMigrator_Result(t *testing.T) {
+	resultVersions := func(res *up.Result) []int64 {
+		var versions []int64
+		for _, m := range res.Migrations {
+			versions = append(versions, m.Version)
+		}
+		return versions
+	}
+
+	t.Run("run", func(t *testing.T) {
+		store := &fakeStore{Versions: []int64{1}}
+		migrations := createMigrations(1, 2, 3)
+		migrations[1].Name = "second"
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: migrations,
+		}
+
+		res, err := migrator.Run(context.Background(), up.RunTargetLatest)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if want := []int64{2, 3}; !slices.Equal(want, resultVersions(res)) {
+			t.Errorf("result versions: want %v, got %v", want, resultVersions(res))
+		}
+		if got, want := res.Migrations[0].Name, "second"; got != want {
+			t.Errorf("result name: want %q, got %q", want, got)
+		}
+		if got, want := res.Version, int64(3); got != want {
+			t.Errorf("result version: want %d, got %d", want, got)
+		}
+	})
+
+	t.Run("run_nothing_pending", func(t *testing.T) {
+		store := &fakeStore{Versions: []int64{1, 2}}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: createMigrations(1, 2),
+		}
+
+		res, err := migrator.Run(context.Background(), up.RunTargetLatest)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(res.Migrations) != 0 {
+			t.Errorf("result migrations: want none, got %v", res.Migrations)
+		}
+		if got, want := res.Version, int64(2); got != want {
+			t.Errorf("result version: want %d, got %d", want, got)
+		}
+	})
+
+	t.Run("run_partial_failure", func(t *testing.T) {
+		store := &fakeStore{}
+		migrations := createMigrations(1, 2, 3)
+		migrations[1].RunFunc = errorMigration("run error")
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: migrations,
+		}
+
+		res, err := migrator.Run(context.Background(), up.RunTargetLatest)
+		if err == nil {
+			t.Fatal("expected error but got none")
+		}
+
+		if want := []int64{1}; !slices.Equal(want, resultVersions(res)) {
+			t.Errorf("result versions: want %v, got %v", want, resultVersions(res))
+		}
+		if got, want := res.Version, int64(1); got != want {
+			t.Errorf("result version: want %d, got %d", want, got)
+		}
+	})
+
+	t.Run("revert", func(t *testing.T) {
+		store := &fakeStore{Versions: []int64{1, 2, 3}}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: createMigrations(1, 2, 3),
+		}
+
+		res, err := migrator.Revert(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if want := []int64{3, 2}; !slices.Equal(want, resultVersions(res)) {
+			t.Errorf("result versions: want %v, got %v", want, resultVersions(res))
+		}
+		if got, want := res.Version, int64(1); got != want {
+			t.Errorf("result version: want %d, got %d", want, got)
+		}
+	})
+
+	t.Run("revert_all", func(t *testing.T) {
+		store := &fakeStore{Versions: []int64{1, 2}}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: createMigrations(1, 2),
+		}
+
+		res, err := migrator.Revert(context.Background(), up.RevertTargetInitial)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if want := []int64{2, 1}; !slices.Equal(want, resultVersions(res)) {
+			t.Errorf("result versions: want %v, got %v", want, resultVersions(res))
+		}
+		if got, want := res.Version, int64(0); got != want {
+			t.Errorf("result version: want %d, got %d", want, got)
+		}
+	})
+}
+
+func TestMigrator_PerMigrationTimeout(t *testing.T) {
+	slowMigration := func(ctx context.Context, _ up.SQLConn) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t.Run("run_timeout", func(t *testing.T) {
+		store := &fakeStore{}
+		migrations := createMigrations(1, 2, 3)
+		migrations[1].RunFunc = slowMigration
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:               store,
+			Sources:             migrations,
+			PerMigrationTimeout: 10 * time.Millisecond,
+		}
+
+		_, err := migrator.Run(context.Background(), up.RunTargetLatest)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("want DeadlineExceeded, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "migration 2 timed out") {
+			t.Errorf("error %q does not identify the timed-out version", err)
+		}
+		if want := []int64{1}; !slices.Equal(want, store.Applied) {
+			t.Errorf("applied mismatch\nwant: %v\ngot:  %v", want, store.Applied)
+		}
+	})
+
+	t.Run("revert_timeout", func(t *testing.T) {
+		store := &fakeStore{Versions: []int64{1, 2}}
+		migrations := createMigrations(1, 2)
+		migrations[1].RevertFunc = slowMigration
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:               store,
+			Sources:             migrations,
+			PerMigrationTimeout: 10 * time.Millisecond,
+		}
+
+		_, err := migrator.Revert(context.Background(), up.RevertTargetInitial)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("want DeadlineExceeded, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "migration 2 timed out") {
+			t.Errorf("error %q does not identify the timed-out version", err)
+		}
+	})
+
+	t.Run("within_timeout", func(t *testing.T) {
+		store := &fakeStore{}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:               store,
+			Sources:             createMigrations(1, 2),
+			PerMigrationTimeout: time.Second,
+		}
+
+		if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestMigrator_Irreversible(t *testing.T) {
+	tests := []struct {
+		name            string
+		initialVersions []int64
+		target          int64
+		configure       func([]*up.Migration[up.SQLConn])
+
+		wantBlocking []int64
+		wantVersions []int64
+	}{
+		{
+			name:            "irreversible_flag",
+			initialVersions: []int64{1, 2, 3},
+			target:          0,
+			configure: func(ms []*up.Migration[up.SQLConn]) {
+				ms[1].Irreversible = true
+			},
+			wantBlocking: []int64{2},
+			wantVersions: []int64{1, 2, 3},
+		},
+		{
+			name:            "nil_revert_func",
+			initialVersions: []int64{1, 2, 3},
+			target:          0,
+			configure: func(ms []*up.Migration[up.SQLConn]) {
+				ms[0].RevertFunc = nil
+				ms[2].Irreversible = true
+			},
+			wantBlocking: []int64{3, 1},
+			wantVersions: []int64{1, 2, 3},
+		},
+		{
+			name:            "irreversible_below_target",
+			initialVersions: []int64{1, 2, 3},
+			target:          1,
+			configure: func(ms []*up.Migration[up.SQLConn]) {
+				ms[0].Irreversible = true
+			},
+			wantVersions: []int64{1},
+		},
+		{
+			name:            "irreversible_not_applied",
+			initialVersions: []int64{1},
+			target:          0,
+			configure: func(ms []*up.Migration[up.SQLConn]) {
+				ms[2].Irreversible = true
+			},
+			wantVersions: []int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{Versions: slices.Clone(tt.initialVersions)}
+			migrations := createMigrations(1, 2, 3)
+			tt.configure(migrations)
+			migrator := &up.Migrator[up.SQLConn]{
+				Store:             store,
+				Sources:           migrations,
+				HoldLockOnFailure: true,
+			}
+
+			_, err := migrator.Revert(context.Background(), tt.target)
+
+			var irrErr *up.IrreversibleError
+			if tt.wantBlocking != nil {
+				if !errors.As(err, &irrErr) {
+					t.Fatalf("want IrreversibleError, got %v", err)
+				}
+				if !slices.Equal(tt.wantBlocking, irrErr.Versions) {
+					t.Errorf("blocking mismatch\nwant: %v\ngot:  %v", tt.wantBlocking, irrErr.Versions)
+				}
+				if store.Locked {
+					t.Error("lock should be released when revert is refused")
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(tt.wantVersions, store.Versions) {
+				t.Errorf("versions mismatch\nwant: %v\ngot:  %v", tt.wantVersions, store.Versions)
+			}
+		})
+	}
+}
+
+func TestMigrator_Status(t *testing.T) {
+	store := &fakeStore{Versions: []int64{1, 2}}
+	migrations := createMigrations(1, 2, 3)
+	migrations[0].Name = "first"
+	migrations[1].Irreversible = true
+	migrator := &up.Migrator[up.SQLConn]{
+		Store:   store,
+		Sources: migrations,
+	}
+
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []up.MigrationStatus{
+		{Version: 1, Name: "first", Applied: true, Reversible: true},
+		{Version: 2, Applied: true, Reversible: false},
+		{Version: 3, Applied: false, Reversible: true},
+	}
+	if !slices.Equal(want, statuses) {
+		t.Errorf("statuses mismatch\nwant: %+v\ngot:  %+v", want, statuses)
+	}
+	if store.LockCalls > 0 {
+		t.Error("Status should not lock the store")
+	}
+}
+
+func TestReadStatus(t *testing.T) {
+	store := &fakeStore{Versions: []int64{1}}
+	reader := up.ReadOnly(store)
+	if _, ok := reader.(up.StoreWriter); ok {
+		t.Fatal("ReadOnly store should not implement StoreWriter")
+	}
+
+	statuses, err := up.ReadStatus(context.Background(), reader, createMigrations(1, 2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []up.MigrationStatus{
+		{Version: 1, Applied: true, Reversible: true},
+		{Version: 2, Applied: false, Reversible: true},
+	}
+	if !slices.Equal(want, statuses) {
+		t.Errorf("statuses mismatch\nwant: %+v\ngot:  %+v", want, statuses)
+	}
+	if store.InitCalls > 0 {
+		t.Error("ReadStatus should not init the store")
+	}
+}
+
+func TestMigrator_Snapshot(t *testing.T) {
+	tests := []struct {
+		name            string
+		initialVersions []int64
+		target          int64
+
+		wantErr      bool
+		wantApplied  []int64
+		wantVersions []int64
+	}{
+		{
+			name:         "fresh_store_runs_snapshot",
+			target:       up.RunTargetLatest,
+			wantApplied:  []int64{3, 4, 5},
+			wantVersions: []int64{3, 4, 5},
+		},
+		{
+			name:         "fresh_store_target_below_snapshot",
+			target:       2,
+			wantApplied:  []int64{1, 2},
+			wantVersions: []int64{1, 2},
+		},
+		{
+			name:            "existing_store_skips_snapshot",
+			initialVersions: []int64{1, 2, 3},
+			target:          up.RunTargetLatest,
+			wantApplied:     []int64{4, 5},
+			wantVersions:    []int64{1, 2, 3, 4, 5},
+		},
+		{
+			name:            "existing_store_below_snapshot",
+			initialVersions: []int64{1},
+			target:          up.RunTargetLatest,
+			wantErr:         true,
+			wantVersions:    []int64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{Versions: slices.Clone(tt.initialVersions)}
+			migrations := createMigrations(1, 2, 3, 4, 5)
+			migrations[2].Snapshot = true
+			migrator := &up.Migrator[up.SQLConn]{
+				Store:   store,
+				Sources: migrations,
+			}
+
+			_, err := migrator.Run(context.Background(), tt.target)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want error, got nil")
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(tt.wantApplied, store.Applied) {
+				t.Errorf("applied mismatch\nwant: %v\ngot:  %v", tt.wantApplied, store.Applied)
+			}
+			if !slices.Equal(tt.wantVersions, store.Versions) {
+				t.Errorf("versions mismatch\nwant: %v\ngot:  %v", tt.wantVersions, store.Versions)
+			}
+		})
+	}
+
+	t.Run("verify_ignores_replaced_versions", func(t *testing.T) {
+		store := &fakeStore{Versions: []int64{1, 2, 3, 4}}
+		migrations := createMigrations(3, 4)
+		migrations[0].Snapshot = true
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: migrations,
+		}
+
+		report, err := migrator.Verify(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if report.Drifted() {
+			t.Errorf("unexpected drift: %+v", report)
+		}
+	})
+
+	t.Run("revert_removes_replaced_versions", func(t *testing.T) {
+		store := &fakeStore{Versions: []int64{1, 2, 3, 4}}
+		migrations := createMigrations(3, 4)
+		migrations[0].Snapshot = true
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: migrations,
+		}
+
+		if _, err := migrator.Revert(context.Background(), up.RevertTargetInitial); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(store.Versions) != 0 {
+			t.Errorf("want empty store, got %v", store.Versions)
+		}
+		if want := []int64{4, 3, 1, 2}; !slices.Equal(want, store.Reverted) {
+			t.Errorf("reverted mismatch\nwant: %v\ngot:  %v", want, store.Reverted)
+		}
+	})
+}
+
+func TestMigrator_Tags(t *testing.T) {
+	tests := []struct {
+		name        string
+		tags        []string
+		wantApplied []int64
+	}{
+		{
+			name:        "no_tags",
+			wantApplied: []int64{1, 3},
+		},
+		{
+			name:        "matching_tag",
+			tags:        []string{"dev"},
+			wantApplied: []int64{1, 2, 3},
+		},
+		{
+			name:        "other_tag",
+			tags:        []string{"prod"},
+			wantApplied: []int64{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{}
+			migrations := createMigrations(1, 2, 3)
+			migrations[1].Tags = []string{"dev", "test"}
+			migrator := &up.Migrator[up.SQLConn]{
+				Store:   store,
+				Sources: migrations,
+				Tags:    tt.tags,
+			}
+
+			if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(tt.wantApplied, store.Applied) {
+				t.Errorf("applied mismatch\nwant: %v\ngot:  %v", tt.wantApplied, store.Applied)
+			}
+
+			report, err := migrator.Verify(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if report.Drifted() {
+				t.Errorf("unexpected drift: %+v", report)
+			}
+		})
+	}
+}
+
+type fakeTracer struct {
+	events []string
+}
+
+func (t *fakeTracer) StartOperation(ctx context.Context, op up.Operation, to int64) (context.Context, func(*up.Result, error)) {
+	t.events = append(t.events, fmt.Sprintf("start %s %d", op, to))
+	return ctx, func(res *up.Result, err error) {
+		t.events = append(t.events, fmt.Sprintf("end %s %d %v", op, res.Version, err != nil))
+	}
+}
+
+func (t *fakeTracer) StartMigration(ctx context.Context, op up.Operation, version int64, _ string) (context.Context, func(error)) {
+	t.events = append(t.events, fmt.Sprintf("start %s migration %d", op, version))
+	return ctx, func(err error) {
+		t.events = append(t.events, fmt.Sprintf("end %s migration %d %v", op, version, err != nil))
+	}
+}
+
+func TestMigrator_Tracer(t *testing.T) {
+	tracer := &fakeTracer{}
+	migrations := createMigrations(1, 2)
+	migrations[1].RevertFunc = errorMigration("revert error")
+	migrator := &up.Migrator[up.SQLConn]{
+		Store:   &fakeStore{},
+		Sources: migrations,
+		Tracer:  tracer,
+	}
+
+	if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := migrator.Revert(context.Background(), up.RevertTargetInitial); err == nil {
+		t.Fatal("want revert error, got nil")
+	}
+
+	want := []string{
+		"start run -1",
+		"start run migration 1",
+		"end run migration 1 false",
+		"start run migration 2",
+		"end run migration 2 false",
+		"end run 2 false",
+		"start revert 0",
+		"start revert migration 2",
+		"end revert migration 2 true",
+		"end revert 2 true",
+	}
+	if !slices.Equal(want, tracer.events) {
+		t.Errorf("events mismatch\nwant: %q\ngot:  %q", want, tracer.events)
+	}
+}
+
+type leaseStore struct {
+	*fakeStore
+	renewals atomic.Int32
+	renewErr error
+}
+
+func (s *leaseStore) LockLease(ctx context.Context, _ time.Duration) error {
+	return s.Lock(ctx)
+}
+
+func (s *leaseStore) RenewLease(context.Context, time.Duration) error {
+	s.renewals.Add(1)
+	return s.renewErr
+}
+
+func TestMigrator_LockLease(t *testing.T) {
+	t.Run("renews_while_running", func(t *testing.T) {
+		store := &leaseStore{fakeStore: &fakeStore{}}
+		migrations := createMigrations(1)
+		migrations[0].RunFunc = func(ctx context.Context, _ up.SQLConn) error {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:     store,
+			Sources:   migrations,
+			LockLease: 15 * time.Millisecond,
+		}
+
+		if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if store.renewals.Load() == 0 {
+			t.Error("want lease renewals, got none")
+		}
+		if store.Locked {
+			t.Error("lock should be released")
+		}
+	})
+
+	t.Run("lost_lease_cancels", func(t *testing.T) {
+		store := &leaseStore{fakeStore: &fakeStore{}, renewErr: up.ErrLeaseLost}
+		migrations := createMigrations(1)
+		migrations[0].RunFunc = func(ctx context.Context, _ up.SQLConn) error {
+			select {
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			case <-time.After(time.Second):
+				return nil
+			}
+		}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:     store,
+			Sources:   migrations,
+			LockLease: 15 * time.Millisecond,
+		}
+
+		_, err := migrator.Run(context.Background(), up.RunTargetLatest)
+		if !errors.Is(err, up.ErrLeaseLost) {
+			t.Fatalf("want ErrLeaseLost, got %v", err)
+		}
+		if len(store.Versions) != 0 {
+			t.Errorf("want no applied versions, got %v", store.Versions)
+		}
+	})
+
+	t.Run("tiny_lease", func(t *testing.T) {
+		store := &leaseStore{fakeStore: &fakeStore{}}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:     store,
+			Sources:   createMigrations(1),
+			LockLease: 2 * time.Nanosecond,
+		}
+
+		if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if store.Locked {
+			t.Error("lock should be released")
+		}
+	})
+}
+
+func TestMigrator_Check(t *testing.T) {
+	tests := []struct {
+		name            string
+		initialVersions []int64
+		wantPending     []int64
+	}{
+		{
+			name:        "fresh_store",
+			wantPending: []int64{1, 2, 3},
+		},
+		{
+			name:            "partially_applied",
+			initialVersions: []int64{1},
+			wantPending:     []int64{2, 3},
+		},
+		{
+			name:            "up_to_date",
+			initialVersions: []int64{1, 2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{Versions: slices.Clone(tt.initialVersions)}
+			migrator := &up.Migrator[up.SQLConn]{
+				Store:   store,
+				Sources: createMigrations(1, 2, 3),
+			}
+
+			n, err := migrator.Check(context.Background())
+
+			if n != len(tt.wantPending) {
+				t.Errorf("want %d pending, got %d", len(tt.wantPending), n)
+			}
+			var pendingErr *up.PendingError
+			if tt.wantPending == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			} else if !errors.As(err, &pendingErr) {
+				t.Fatalf("want PendingError, got %v", err)
+			} else if !slices.Equal(tt.wantPending, pendingErr.Versions) {
+				t.Errorf("pending mismatch\nwant: %v\ngot:  %v", tt.wantPending, pendingErr.Versions)
+			}
+			if store.LockCalls > 0 {
+				t.Error("Check should not lock the store")
+			}
+			if store.InitCalls > 0 {
+				t.Error("Check should not init the store")
+			}
+		})
+	}
+
+	t.Run("not_initialized", func(t *testing.T) {
+		store := &initCheckStore{fakeStore: &fakeStore{}}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: createMigrations(1, 2, 3),
+		}
+
+		if _, err := migrator.Check(context.Background()); !errors.Is(err, up.ErrNotInitialized) {
+			t.Errorf("want ErrNotInitialized, got %v", err)
+		}
+		if store.InitCalls > 0 {
+			t.Error("Check should not init the store")
+		}
+
+		store.initialized = true
+		if _, err := migrator.Check(context.Background()); !errors.As(err, new(*up.PendingError)) {
+			t.Errorf("want PendingError, got %v", err)
+		}
+	})
+}
+
+type initCheckStore struct {
+	*fakeStore
+	initialized bool
+}
+
+func (s *initCheckStore) Initialized(context.Context) (bool, error) {
+	return s.initialized, nil
+}
+
+type txStore struct {
+	*fakeStore
+	txCalls int
+}
+
+func (s *txStore) WithTx(ctx context.Context, fn func(context.Context, up.Store[up.SQLConn]) error) error {
+	s.txCalls++
+	saved := slices.Clone(s.Versions)
+	if err := fn(ctx, s.fakeStore); err != nil {
+		s.Versions = saved
+		return err
+	}
+	return nil
+}
+
+func TestMigrator_Atomic(t *testing.T) {
+	t.Run("rolls_back_run", func(t *testing.T) {
+		store := &txStore{fakeStore: &fakeStore{Versions: []int64{1}}}
+		migrations := createMigrations(1, 2, 3)
+		migrations[2].RunFunc = errorMigration("run error")
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: migrations,
+			Atomic:  true,
+		}
+
+		res, err := migrator.Run(context.Background(), up.RunTargetLatest)
+		if err == nil {
+			t.Fatal("want error, got nil")
+		}
+		if store.txCalls != 1 {
+			t.Errorf("want 1 transaction, got %d", store.txCalls)
+		}
+		if want := []int64{1}; !slices.Equal(want, store.Versions) {
+			t.Errorf("versions mismatch\nwant: %v\ngot:  %v", want, store.Versions)
+		}
+		if res.Version != 1 || len(res.Migrations) != 0 {
+			t.Errorf("want result at starting version, got %+v", res)
+		}
+	})
+
+	t.Run("rolls_back_revert", func(t *testing.T) {
+		store := &txStore{fakeStore: &fakeStore{Versions: []int64{1, 2, 3}}}
+		migrations := createMigrations(1, 2, 3)
+		migrations[0].RevertFunc = errorMigration("revert error")
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: migrations,
+			Atomic:  true,
+		}
+
+		res, err := migrator.Revert(context.Background(), up.RevertTargetInitial)
+		if err == nil {
+			t.Fatal("want error, got nil")
+		}
+		if want := []int64{1, 2, 3}; !slices.Equal(want, store.Versions) {
+			t.Errorf("versions mismatch\nwant: %v\ngot:  %v", want, store.Versions)
+		}
+		if res.Version != 3 || len(res.Migrations) != 0 {
+			t.Errorf("want result at starting version, got %+v", res)
+		}
+	})
+
+	t.Run("unsupported_store", func(t *testing.T) {
+		store := &fakeStore{}
+		migrator := &up.Migrator[up.SQLConn]{
+			Store:   store,
+			Sources: createMigrations(1),
+			Atomic:  true,
+		}
+
+		if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err == nil {
+			t.Fatal("want error, got nil")
+		}
+		if store.LockCalls > 0 {
+			t.Error("store should not be locked")
+		}
+	})
+}
+
+type batchStore struct {
+	*fakeStore
+	inserted [][]int64
+	removed  [][]int64
+}
+
+func (s *batchStore) InsertMany(ctx context.Context, versions []int64) error {
+	s.inserted = append(s.inserted, versions)
+	for _, v := range versions {
+		if err := uptest.DefaultInsert(ctx, v, s.fakeStore); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *batchStore) RemoveMany(ctx context.Context, versions []int64) error {
+	s.removed = append(s.removed, versions)
+	for _, v := range versions {
+		if err := uptest.DefaultRemove(ctx, v, s.fakeStore); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestMigrator_Batch(t *testing.T) {
+	store := &batchStore{fakeStore: &fakeStore{Versions: []int64{7, 8}}}
+	migrator := &up.Migrator[up.SQLConn]{
+		Store:   store,
+		Sources: createMigrations(1, 2, 3, 4),
+	}
+
+	n, err := migrator.Baseline(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("n: want 3, got %d", n)
+	}
+
+	report, err := migrator.Repair(context.Background(), up.RepairOptions{DeleteOrphans: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []int64{7, 8}; !slices.Equal(want, report.Deleted) {
+		t.Errorf("deleted mismatch\nwant: %v\ngot:  %v", want, report.Deleted)
+	}
+
+	if want := [][]int64{{1, 2, 3}}; !slices.EqualFunc(want, store.inserted, slices.Equal) {
+		t.Errorf("batch inserts mismatch\nwant: %v\ngot:  %v", want, store.inserted)
+	}
+	if want := [][]int64{{7, 8}}; !slices.EqualFunc(want, store.removed, slices.Equal) {
+		t.Errorf("batch removes mismatch\nwant: %v\ngot:  %v", want, store.removed)
+	}
+	if store.InsertCalls > 0 || store.RemoveCalls > 0 {
+		t.Error("single-version Insert and Remove should not be called")
+	}
+}
+
+func TestMigrator_TypedErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		versions    []int64
+		migrations  []*up.Migration[up.SQLConn]
+		op          func(context.Context, *up.Migrator[up.SQLConn]) error
+		wantVersion int64
+		wantMissing bool
+	}{
+		{
+			name:       "duplicate_sources",
+			migrations: createMigrations(1, 2, 2),
+			op: func(ctx context.Context, m *up.Migrator[up.SQLConn]) error {
+				_, err := m.Run(ctx, up.RunTargetLatest)
+				return err
+			},
+			wantVersion: 2,
+		},
+		{
+			name:       "misordered_sources",
+			migrations: createMigrations(1, 3, 2),
+			op: func(ctx context.Context, m *up.Migrator[up.SQLConn]) error {
+				_, err := m.Revert(ctx, up.RevertTargetInitial)
+				return err
+			},
+			wantVersion: 2,
+		},
+		{
+			name:       "missing_target",
+			versions:   []int64{1, 2},
+			migrations: createMigrations(1, 2),
+			op: func(ctx context.Context, m *up.Migrator[up.SQLConn]) error {
+				_, err := m.Revert(ctx, 5)
+				return err
+			},
+			wantVersion: 5,
+			wantMissing: true,
+		},
+		{
+			name:       "missing_store_version",
+			versions:   []int64{1, 2, 5},
+			migrations: createMigrations(1, 2),
+			op: func(ctx context.Context, m *up.Migrator[up.SQLConn]) error {
+				_, err := m.Revert(ctx, 1)
+				return err
+			},
+			wantVersion: 5,
+			wantMissing: true,
+		},
+		{
+			name:       "missing_baseline",
+			migrations: createMigrations(1, 2),
+			op: func(ctx context.Context, m *up.Migrator[up.SQLConn]) error {
+				_, err := m.Baseline(ctx, 3)
+				return err
+			},
+			wantVersion: 3,
+			wantMissing: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrator := &up.Migrator[up.SQLConn]{
+				Store:   &fakeStore{Versions: slices.Clone(tt.versions)},
+				Sources: tt.migrations,
+			}
+			err := tt.op(context.Background(), migrator)
+
+			var missingErr *up.MissingMigrationError
+			var validationErr *up.ValidationError
+			switch {
+			case tt.wantMissing:
+				if !errors.As(err, &missingErr) {
+					t.Fatalf("want MissingMigrationError, got %v", err)
+				}
+				if missingErr.Version != tt.wantVersion {
+					t.Errorf("version: want %d, got %d", tt.wantVersion, missingErr.Version)
+				}
+			default:
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("want ValidationError, got %v", err)
+				}
+				if validationErr.Version != tt.wantVersion {
+					t.Errorf("version: want %d, got %d", tt.wantVersion, validationErr.Version)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrator_Progress(t *testing.T) {
+	type call struct {
+		done, total int
+		version     int64
+	}
+	var calls []call
+	store := &fakeStore{Versions: []int64{1}}
+	migrator := &up.Migrator[up.SQLConn]{
+		Store:   store,
+		Sources: createMigrations(1, 2, 3),
+		Progress: func(done, total int, current *up.Migration[up.SQLConn]) {
+			c := call{done: done, total: total}
+			if current != nil {
+				c.version = current.Version
+			}
+			calls = append(calls, c)
+		},
+	}
+
+	if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []call{{0, 2, 2}, {1, 2, 3}, {2, 2, 0}}; !slices.Equal(want, calls) {
+		t.Errorf("run progress mismatch\nwant: %v\ngot:  %v", want, calls)
+	}
+
+	calls = nil
+	if _, err := migrator.Revert(context.Background(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []call{{0, 2, 3}, {1, 2, 2}, {2, 2, 0}}; !slices.Equal(want, calls) {
+		t.Errorf("revert progress mismatch\nwant: %v\ngot:  %v", want, calls)
+	}
+
+	calls = nil
+	if _, err := migrator.Revert(context.Background(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("want no progress calls for an empty plan, got %v", calls)
+	}
+}
+
+func TestMigrator_Busy(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	store := &fakeStore{}
+	migrator := &up.Migrator[up.SQLConn]{
+		Store: store,
+		Sources: []*up.Migration[up.SQLConn]{{
+			Version: 1,
+			RunFunc: func(ctx context.Context, conn up.SQLConn) error {
+				close(started)
+				<-unblock
+				return nil
+			},
+			RevertFunc: func(ctx context.Context, conn up.SQLConn) error { return nil },
+		}},
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := migrator.Run(context.Background(), up.RunTargetLatest)
+		errc <- err
+	}()
+	<-started
+
+	if _, err := migrator.Revert(context.Background(), up.RevertTargetInitial); !errors.Is(err, up.ErrBusy) {
+		t.Errorf("concurrent Revert: want ErrBusy, got %v", err)
+	}
+	if _, err := migrator.Baseline(context.Background(), 1); !errors.Is(err, up.ErrBusy) {
+		t.Errorf("concurrent Baseline: want ErrBusy, got %v", err)
+	}
+	if store.LockCalls != 1 {
+		t.Errorf("LockCalls: want 1, got %d", store.LockCalls)
+	}
+
+	close(unblock)
+	if err := <-errc; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := migrator.Revert(context.Background(), up.RevertTargetInitial); err != nil {
+		t.Errorf("Revert after Run: unexpected error: %v", err)
+	}
+}
+
+func TestMigrator_AllowOutOfOrder(t *testing.T) {
+	tests := []struct {
+		name            string
+		versions        []int64
+		snapshot        int64
+		allowOutOfOrder bool
+		target          int64
+		wantApplied     []int64
+		wantVersion     int64
+		wantPending     int
+	}{
+		{
+			name:        "disabled",
+			target:      up.RunTargetLatest,
+			wantApplied: []int64{5},
+			wantVersion: 5,
+			wantPending: 1,
+		},
+		{
+			name:            "fills_gaps",
+			allowOutOfOrder: true,
+			target:          up.RunTargetLatest,
+			wantApplied:     []int64{2, 3, 5},
+			wantVersion:     5,
+			wantPending:     3,
+		},
+		{
+			name:            "fills_gaps_up_to_target",
+			allowOutOfOrder: true,
+			target:          2,
+			wantApplied:     []int64{2},
+			wantVersion:     4,
+			wantPending:     3,
+		},
+		{
+			name:            "skips_replaced_by_snapshot",
+			versions:        []int64{4, 5},
+			snapshot:        4,
+			allowOutOfOrder: true,
+			target:          up.RunTargetLatest,
+			wantVersion:     5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions := tt.versions
+			if versions == nil {
+				versions = []int64{1, 4}
+			}
+			store := &fakeStore{Versions: versions}
+			sources := createMigrations(1, 2, 3, 4, 5)
+			for _, migration := range sources {
+				migration.Snapshot = migration.Version == tt.snapshot
+			}
+			migrator := &up.Migrator[up.SQLConn]{
+				Store:           store,
+				Sources:         sources,
+				AllowOutOfOrder: tt.allowOutOfOrder,
+			}
+
+			if n, _ := migrator.Check(context.Background()); n != tt.wantPending {
+				t.Errorf("pending: want %d, got %d", tt.wantPending, n)
+			}
+
+			res, err := migrator.Run(context.Background(), tt.target)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(tt.wantApplied, store.Applied) {
+				t.Errorf("applied mismatch\nwant: %v\ngot:  %v", tt.wantApplied, store.Applied)
+			}
+			if res.Version != tt.wantVersion {
+				t.Errorf("version: want %d, got %d", tt.wantVersion, res.Version)
+			}
+		})
+	}
+}
+
+func TestMigrator_Skip(t *testing.T) {
+	var ran, reverted []int64
+	var migrations []*up.Migration[up.SQLConn]
+	for _, v := range []int64{1, 2, 3} {
+		migrations = append(migrations, &up.Migration[up.SQLConn]{
+			Version:      v,
+			Irreversible: v == 2,
+			RunFunc: func(ctx context.Context, conn up.SQLConn) error {
+				if v == 2 {
+					return fmt.Errorf("migration %d is broken", v)
+				}
+				ran = append(ran, v)
+				return nil
+			},
+			RevertFunc: func(ctx context.Context, conn up.SQLConn) error {
+				reverted = append(reverted, v)
+				return nil
+			},
+		})
+	}
+	store := &fakeStore{}
+	migrator := &up.Migrator[up.SQLConn]{
+		Store:   store,
+		Sources: migrations,
+		Skip:    []int64{2},
+	}
+
+	res, err := migrator.Run(context.Background(), up.RunTargetLatest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	uptest.RequireApplied(t, store, 1, 2, 3)
+	if want := []int64{1, 3}; !slices.Equal(want, ran) {
+		t.Errorf("ran mismatch\nwant: %v\ngot:  %v", want, ran)
+	}
+	if !res.Migrations[1].Skipped || res.Migrations[0].Skipped {
+		t.Errorf("only migration 2 should be marked skipped: %+v", res.Migrations)
+	}
+
+	if _, err := migrator.Revert(context.Background(), up.RevertTargetInitial); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	uptest.RequireApplied(t, store)
+	if want := []int64{3, 1}; !slices.Equal(want, reverted) {
+		t.Errorf("reverted mismatch\nwant: %v\ngot:  %v", want, reverted)
+	}
+}
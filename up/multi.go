@@ -0,0 +1,79 @@
+package up
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// A MultiRunner applies the same migrations to many version stores, such as
+// one database per tenant.
+type MultiRunner[T any] struct {
+	// Migrator is the template used for each store. Its Store field is
+	// ignored; every other field, including Sources, is shared by all stores.
+	Migrator Migrator[T]
+	Stores   []Store[T]
+
+	// Concurrency limits how many stores are migrated at once. Zero or less
+	// means no limit.
+	Concurrency int
+}
+
+// A StoreResult describes the outcome of a MultiRunner operation on a single
+// store.
+type StoreResult struct {
+	Index  int     // index of the store in MultiRunner.Stores
+	Result *Result // never nil
+	Err    error
+}
+
+// Run calls [Migrator.Run] on every store. The returned results are in the
+// same order as the stores. The error joins the errors of every failed store.
+func (r *MultiRunner[T]) Run(ctx context.Context, to int64) ([]StoreResult, error) {
+	return r.each(ctx, func(m *Migrator[T]) (*Result, error) {
+		return m.Run(ctx, to)
+	})
+}
+
+// Revert calls [Migrator.Revert] on every store. The returned results are in
+// the same order as the stores. The error joins the errors of every failed
+// store.
+func (r *MultiRunner[T]) Revert(ctx context.Context, to int64) ([]StoreResult, error) {
+	return r.each(ctx, func(m *Migrator[T]) (*Result, error) {
+		return m.Revert(ctx, to)
+	})
+}
+
+func (r *MultiRunner[T]) each(ctx context.Context, fn func(*Migrator[T]) (*Result, error)) ([]StoreResult, error) {
+	limit := r.Concurrency
+	if limit <= 0 || limit > len(r.Stores) {
+		limit = len(r.Stores)
+	}
+	sem := make(chan struct{}, limit)
+
+	results := make([]StoreResult, len(r.Stores))
+	var wg sync.WaitGroup
+	for i, store := range r.Stores {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			m := r.Migrator
+			m.Store = store
+			res, err := fn(&m)
+			results[i] = StoreResult{Index: i, Result: res, Err: err}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, res := range results {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("store %d: %w", res.Index, res.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
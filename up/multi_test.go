@@ -0,0 +1,103 @@
+package up_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jonathonwebb/x/up"
+	"github.com/jonathonwebb/x/up/uptest"
+)
+
+func TestMultiRunner_Run(t *testing.T) {
+	stores := []*fakeStore{
+		{},
+		{Versions: []int64{1}},
+		{VersionFunc: func(context.Context, *fakeStore) (int64, error) {
+			return 0, errors.New("version error")
+		}},
+	}
+
+	var active, peak atomic.Int32
+	runner := &up.MultiRunner[up.SQLConn]{
+		Migrator: up.Migrator[up.SQLConn]{
+			Sources: createMigrations(1, 2),
+		},
+		Concurrency: 2,
+	}
+	for _, s := range stores {
+		s.LockFunc = func(ctx context.Context, s *fakeStore) error {
+			n := active.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			return uptest.DefaultLock(ctx, s)
+		}
+		s.ReleaseFunc = func(ctx context.Context, s *fakeStore) error {
+			active.Add(-1)
+			return uptest.DefaultRelease(ctx, s)
+		}
+		runner.Stores = append(runner.Stores, s)
+	}
+
+	results, err := runner.Run(context.Background(), up.RunTargetLatest)
+
+	if err == nil {
+		t.Fatal("want error from failing store, got nil")
+	}
+	if len(results) != len(stores) {
+		t.Fatalf("want %d results, got %d", len(stores), len(results))
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("want at most 2 concurrent stores, got %d", p)
+	}
+
+	wantVersions := [][]int64{{1, 2}, {1, 2}, nil}
+	for i, res := range results {
+		if res.Index != i {
+			t.Errorf("results[%d].Index = %d", i, res.Index)
+		}
+		if res.Result == nil {
+			t.Errorf("results[%d].Result is nil", i)
+		}
+		if (res.Err != nil) != (i == 2) {
+			t.Errorf("results[%d].Err = %v", i, res.Err)
+		}
+		if !slices.Equal(wantVersions[i], stores[i].Versions) {
+			t.Errorf("store %d versions mismatch\nwant: %v\ngot:  %v", i, wantVersions[i], stores[i].Versions)
+		}
+	}
+}
+
+func TestMultiRunner_Revert(t *testing.T) {
+	stores := []*fakeStore{
+		{Versions: []int64{1, 2}},
+		{Versions: []int64{1}},
+	}
+	runner := &up.MultiRunner[up.SQLConn]{
+		Migrator: up.Migrator[up.SQLConn]{
+			Sources: createMigrations(1, 2),
+		},
+	}
+	for _, s := range stores {
+		runner.Stores = append(runner.Stores, s)
+	}
+
+	results, err := runner.Revert(context.Background(), up.RevertTargetInitial)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, res := range results {
+		if res.Result.Version != 0 {
+			t.Errorf("results[%d].Result.Version = %d, want 0", i, res.Result.Version)
+		}
+		if len(stores[i].Versions) != 0 {
+			t.Errorf("store %d versions = %v, want empty", i, stores[i].Versions)
+		}
+	}
+}
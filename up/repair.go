@@ -0,0 +1,80 @@
+package up
+
+import (
+	"context"
+	"fmt"
+)
+
+// RepairOptions configures [Migrator.Repair].
+type RepairOptions struct {
+	DeleteOrphans bool    // remove applied versions with no source migration
+	MarkApplied   []int64 // record source versions known to be applied
+}
+
+// A RepairReport describes the changes made by [Migrator.Repair].
+type RepairReport struct {
+	Deleted  []int64 // orphaned versions removed from the store
+	Inserted []int64 // versions recorded as applied
+}
+
+// Repair re-syncs the version store with the sources after a partial failure,
+// without running or reverting any migrations. Versions in MarkApplied must
+// have a source migration; versions that are already recorded are skipped.
+func (m *Migrator[T]) Repair(ctx context.Context, opts RepairOptions) (*RepairReport, error) {
+	if err := m.check(); err != nil {
+		return nil, fmt.Errorf("invalid sources: %w", err)
+	}
+
+	var toMark []*Migration[T]
+	for _, v := range opts.MarkApplied {
+		migration, ok := m.findSource(v)
+		if !ok {
+			return nil, fmt.Errorf("invalid repair version: %w", &MissingMigrationError{v})
+		}
+		toMark = append(toMark, migration)
+	}
+
+	report := &RepairReport{}
+	err := m.withLock(ctx, func(ctx context.Context) error {
+		history, err := m.Store.History(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get version store history: %w", err)
+		}
+		applied := make(map[int64]bool, len(history))
+		for _, record := range history {
+			applied[record.Version] = true
+		}
+
+		if opts.DeleteOrphans {
+			var orphans []int64
+			for _, record := range history {
+				if _, ok := m.findSource(record.Version); ok {
+					continue
+				}
+				m.debug("deleting orphaned version: %d", record.Version)
+				orphans = append(orphans, record.Version)
+			}
+			n, err := m.removeMany(ctx, m.Store, orphans)
+			report.Deleted = orphans[:n]
+			if err != nil {
+				return err
+			}
+		}
+
+		var toInsert []*Migration[T]
+		for _, migration := range toMark {
+			if applied[migration.Version] {
+				continue
+			}
+			m.debug("marking migration applied: %d", migration.Version)
+			applied[migration.Version] = true
+			toInsert = append(toInsert, migration)
+		}
+		n, err := m.recordMany(ctx, m.Store, toInsert)
+		for _, migration := range toInsert[:n] {
+			report.Inserted = append(report.Inserted, migration.Version)
+		}
+		return err
+	})
+	return report, err
+}
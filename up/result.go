@@ -0,0 +1,27 @@
+package up
+
+import "time"
+
+// A MigrationResult describes a single applied or reverted migration.
+type MigrationResult struct {
+	Version  int64
+	Name     string
+	Duration time.Duration
+	Skipped  bool // recorded without running, see Migrator.Skip
+}
+
+// A Result describes the outcome of [Migrator.Run] or [Migrator.Revert]. When
+// an operation fails partway, the Result still describes the migrations that
+// completed before the failure.
+type Result struct {
+	Migrations []MigrationResult // migrations applied or reverted, in order
+	Version    int64             // store version after the operation
+}
+
+func (r *Result) add(version int64, name string, d time.Duration) {
+	r.Migrations = append(r.Migrations, MigrationResult{Version: version, Name: name, Duration: d})
+}
+
+func (r *Result) addSkipped(version int64, name string) {
+	r.Migrations = append(r.Migrations, MigrationResult{Version: version, Name: name, Skipped: true})
+}
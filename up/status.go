@@ -0,0 +1,64 @@
+package up
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// A MigrationStatus describes the state of a source migration in the version
+// store.
+type MigrationStatus struct {
+	Version    int64
+	Name       string
+	Applied    bool
+	AppliedAt  time.Time // zero if the migration is not applied
+	Reversible bool
+}
+
+// Status reports the state of every source migration, in version order.
+func (m *Migrator[T]) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.check(); err != nil {
+		return nil, fmt.Errorf("invalid sources: %w", err)
+	}
+
+	if err := m.Store.Init(ctx); err != nil {
+		return nil, fmt.Errorf("failed to init version store: %w", err)
+	}
+
+	return status(ctx, m.Store, m.Sources)
+}
+
+// ReadStatus reports the state of every source migration in the version store
+// read by r, like [Migrator.Status], without initializing or otherwise
+// modifying the store.
+func ReadStatus[T any](ctx context.Context, r StoreReader, sources []*Migration[T]) ([]MigrationStatus, error) {
+	if err := (&Migrator[T]{Sources: sources}).check(); err != nil {
+		return nil, fmt.Errorf("invalid sources: %w", err)
+	}
+	return status(ctx, r, sources)
+}
+
+func status[T any](ctx context.Context, r StoreReader, sources []*Migration[T]) ([]MigrationStatus, error) {
+	history, err := r.History(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version store history: %w", err)
+	}
+	applied := make(map[int64]Record, len(history))
+	for _, record := range history {
+		applied[record.Version] = record
+	}
+
+	statuses := make([]MigrationStatus, len(sources))
+	for i, migration := range sources {
+		record, ok := applied[migration.Version]
+		statuses[i] = MigrationStatus{
+			Version:    migration.Version,
+			Name:       migration.Name,
+			Applied:    ok,
+			AppliedAt:  record.AppliedAt,
+			Reversible: migration.Reversible(),
+		}
+	}
+	return statuses, nil
+}
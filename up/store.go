@@ -4,21 +4,171 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 )
 
+// Errors returned by stores and the [Migrator]. Stores wrap or return them
+// directly, so callers should compare with [errors.Is].
 var (
 	ErrLocked          = errors.New("version store is locked for writing")
 	ErrInitialVersion  = errors.New("initial version is current")
 	ErrVersionNotFound = errors.New("version not found")
+	ErrLeaseLost       = errors.New("version store lock lease was lost")
+	ErrBusy            = errors.New("migrator is busy")
+	ErrNotInitialized  = errors.New("version store is not initialized")
 )
 
+// An IrreversibleError is returned by [Migrator.Revert] when reaching the
+// target version would require reverting irreversible migrations.
+type IrreversibleError struct {
+	Versions []int64 // blocking versions, in revert order
+}
+
+func (e *IrreversibleError) Error() string {
+	return fmt.Sprintf("cannot revert irreversible migrations: %v", e.Versions)
+}
+
+// A MissingMigrationError is returned when a version that must be resolved
+// against the sources, such as a revert target or a version recorded in the
+// store, has no source migration.
+type MissingMigrationError struct {
+	Version int64
+}
+
+func (e *MissingMigrationError) Error() string {
+	return fmt.Sprintf("missing migration for version %d", e.Version)
+}
+
+// A ValidationError is returned when the source migrations are invalid, such as
+// when versions are duplicated or out of order.
+type ValidationError struct {
+	Version int64  // offending version
+	Reason  string // description of the problem
+}
+
+func (e *ValidationError) Error() string {
+	return e.Reason
+}
+
+// A PendingError is returned by [Migrator.Check] when source migrations have
+// not been applied.
+type PendingError struct {
+	Versions []int64 // pending versions, in apply order
+}
+
+func (e *PendingError) Error() string {
+	return fmt.Sprintf("%d pending migrations: %v", len(e.Versions), e.Versions)
+}
+
 // Store is an interface for a schema version store.
-type Store interface {
-	DB() *sql.DB
+//
+// T is the type of the connection handle passed to migrations. Stores backed by
+// database/sql use [SQLConn]; other drivers may use their native handle types.
+type Store[T any] interface {
+	Conn() T
+	StoreReader
+	StoreWriter
+}
+
+// A StoreReader reads the state of a version store. Tooling that only reports
+// on a store, such as [ReadStatus], accepts a StoreReader; wrap a Store with
+// [ReadOnly] to guarantee it is not modified.
+type StoreReader interface {
+	Version(context.Context) (int64, error)
+	History(context.Context) ([]Record, error)
+}
+
+// A StoreWriter modifies a version store.
+type StoreWriter interface {
 	Init(context.Context) error
 	Lock(context.Context) error
 	Release(context.Context) error
-	Version(context.Context) (int64, error)
 	Insert(context.Context, int64) error
 	Remove(context.Context, int64) error
 }
+
+// ReadOnly returns a StoreReader that exposes only the read methods of r, so
+// the underlying store cannot be recovered by a type assertion and modified.
+func ReadOnly(r StoreReader) StoreReader {
+	return readOnlyStore{r}
+}
+
+type readOnlyStore struct {
+	r StoreReader
+}
+
+func (s readOnlyStore) Version(ctx context.Context) (int64, error)    { return s.r.Version(ctx) }
+func (s readOnlyStore) History(ctx context.Context) ([]Record, error) { return s.r.History(ctx) }
+
+// A Record describes an applied migration in a version store. Store History
+// methods return records in ascending version order.
+type Record struct {
+	Version   int64
+	AppliedAt time.Time
+	Checksum  string // empty unless the store implements ChecksumStore
+}
+
+// A ChecksumStore is a Store that records migration checksums. Stores that
+// implement it report the recorded checksum in each [Record].
+type ChecksumStore interface {
+	SetChecksum(ctx context.Context, version int64, checksum string) error
+}
+
+// A BatchStore is a Store that can insert or remove many versions in one
+// round trip. The migrator uses it for bookkeeping that does not run
+// migrations, such as [Migrator.Baseline] and [Migrator.Repair]. Each batch is
+// applied entirely or not at all.
+type BatchStore interface {
+	InsertMany(ctx context.Context, versions []int64) error
+
+	// RemoveMany returns ErrVersionNotFound if any version is not recorded.
+	RemoveMany(ctx context.Context, versions []int64) error
+}
+
+// A LeaseStore is a Store whose lock can expire. A lock acquired with a lease
+// is held until it is released or its lease expires without being renewed, so
+// a lock left behind by a crashed process can be taken over once it expires.
+type LeaseStore interface {
+	// LockLease acquires the lock for the duration of ttl, taking over an
+	// expired lock. It returns ErrLocked if the lock is held and unexpired.
+	LockLease(ctx context.Context, ttl time.Duration) error
+
+	// RenewLease extends the lease of a lock acquired with LockLease to ttl
+	// from now. It returns ErrLeaseLost if the lock is no longer held.
+	RenewLease(ctx context.Context, ttl time.Duration) error
+}
+
+// A ForceUnlocker is a Store whose lock can be released by any process, not
+// just the one holding it. It is used to recover from a lock left behind by a
+// process that exited without releasing it.
+type ForceUnlocker interface {
+	ForceUnlock(ctx context.Context) error
+}
+
+// An InitChecker is a Store that can report whether Init has created the
+// version store without modifying the database. Operations that only read the
+// store, such as [Migrator.Check], use it instead of calling Init, so they can
+// run with a read-only database role.
+type InitChecker interface {
+	Initialized(ctx context.Context) (bool, error)
+}
+
+// A TxStore is a Store that can run a batch of migrations in a single
+// transaction. It is required by [Migrator.Atomic].
+type TxStore[T any] interface {
+	Store[T]
+
+	// WithTx calls fn with a copy of the store whose Conn and version
+	// methods use a new transaction. The transaction is committed if fn
+	// returns nil, and rolled back otherwise.
+	WithTx(ctx context.Context, fn func(context.Context, Store[T]) error) error
+}
+
+// SQLConn is the connection handle used by database/sql stores. It is
+// implemented by [*sql.DB], [*sql.Conn] and [*sql.Tx].
+type SQLConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
@@ -0,0 +1,198 @@
+// Package clickhousestore provides a ClickHouse implementation of the up.Store
+// interface.
+//
+// ClickHouse has neither transactions nor unique constraints, so the store
+// keeps an append-only log of version changes in a ReplacingMergeTree table and
+// reads the latest state of each version with FINAL. The write lock is a
+// best-effort lock row in a second ReplacingMergeTree table: a store claims the
+// lock by writing its owner token, then reads the lock back and only succeeds
+// if its token won. This protects against accidental concurrent runs, but is
+// not a substitute for coordinating deploys; see [ClickHouseStore.Lock].
+//
+// The store only depends on database/sql; callers register a ClickHouse driver
+// such as github.com/ClickHouse/clickhouse-go/v2 and pass the opened handle to
+// [New].
+package clickhousestore
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/jonathonwebb/x/up"
+)
+
+type ClickHouseStore struct {
+	instance *sql.DB
+	owner    string
+}
+
+var (
+	_ up.Store[up.SQLConn] = (*ClickHouseStore)(nil)
+	_ up.InitChecker       = (*ClickHouseStore)(nil)
+)
+
+func New(db *sql.DB) *ClickHouseStore {
+	token := make([]byte, 16)
+	rand.Read(token)
+	return &ClickHouseStore{
+		instance: db,
+		owner:    hex.EncodeToString(token),
+	}
+}
+
+func (s *ClickHouseStore) DB() *sql.DB {
+	return s.instance
+}
+
+func (s *ClickHouseStore) Conn() up.SQLConn {
+	return s.instance
+}
+
+func (s *ClickHouseStore) Init(ctx context.Context) error {
+	if _, err := s.instance.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_lock (id UInt8, owner String, locked UInt8, updated_at DateTime64(6, 'UTC') DEFAULT now64(6)) ENGINE = ReplacingMergeTree(updated_at) ORDER BY id"); err != nil {
+		return err
+	}
+
+	if _, err := s.instance.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version_id Int64, is_applied UInt8, applied_at DateTime64(6, 'UTC') DEFAULT now64(6)) ENGINE = ReplacingMergeTree(applied_at) ORDER BY version_id"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Initialized reports whether Init has created the schema_migrations table in
+// the current database.
+func (s *ClickHouseStore) Initialized(ctx context.Context) (bool, error) {
+	var n uint64
+	if err := s.instance.QueryRowContext(ctx, "SELECT count() FROM system.tables WHERE database = currentDatabase() AND name = 'schema_migrations'").Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Lock claims the lock row for the store and reads it back, returning
+// [up.ErrLocked] if another store holds it.
+//
+// Without transactions the claim is not atomic: two stores that both read the
+// lock as free before either claim is written can each read back their own
+// claim, and both succeed. Callers that cannot tolerate this must serialize
+// runs outside of the store.
+func (s *ClickHouseStore) Lock(ctx context.Context) error {
+	var locked uint8
+	err := s.instance.QueryRowContext(ctx, "SELECT locked FROM schema_lock FINAL WHERE id = 1").Scan(&locked)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if locked == 1 {
+		return up.ErrLocked
+	}
+
+	if _, err := s.instance.ExecContext(ctx, "INSERT INTO schema_lock (id, owner, locked) VALUES (1, ?, 1)", s.owner); err != nil {
+		return err
+	}
+
+	var owner string
+	if err := s.instance.QueryRowContext(ctx, "SELECT owner FROM schema_lock FINAL WHERE id = 1").Scan(&owner); err != nil {
+		return err
+	}
+	if owner != s.owner {
+		return up.ErrLocked
+	}
+	return nil
+}
+
+// Release releases the lock if the store holds it. Releasing a lock held by
+// another store does nothing.
+func (s *ClickHouseStore) Release(ctx context.Context) error {
+	var owner string
+	var locked uint8
+	err := s.instance.QueryRowContext(ctx, "SELECT owner, locked FROM schema_lock FINAL WHERE id = 1").Scan(&owner, &locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if locked == 0 || owner != s.owner {
+		return nil
+	}
+
+	if _, err := s.instance.ExecContext(ctx, "INSERT INTO schema_lock (id, owner, locked) VALUES (1, ?, 0)", s.owner); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *ClickHouseStore) Version(ctx context.Context) (int64, error) {
+	row := s.instance.QueryRowContext(ctx, "SELECT version_id FROM schema_migrations FINAL WHERE is_applied = 1 ORDER BY version_id DESC LIMIT 1")
+	var version int64
+	if err := row.Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *ClickHouseStore) History(ctx context.Context) ([]up.Record, error) {
+	rows, err := s.instance.QueryContext(ctx, "SELECT version_id, applied_at FROM schema_migrations FINAL WHERE is_applied = 1 ORDER BY version_id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []up.Record
+	for rows.Next() {
+		var r up.Record
+		if err := rows.Scan(&r.Version, &r.AppliedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (s *ClickHouseStore) Insert(ctx context.Context, v int64) error {
+	applied, err := s.isApplied(ctx, v)
+	if err != nil {
+		return err
+	}
+	if applied {
+		return fmt.Errorf("version %d is already applied", v)
+	}
+
+	if _, err := s.instance.ExecContext(ctx, "INSERT INTO schema_migrations (version_id, is_applied) VALUES (?, 1)", v); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *ClickHouseStore) Remove(ctx context.Context, v int64) error {
+	applied, err := s.isApplied(ctx, v)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return up.ErrVersionNotFound
+	}
+
+	if _, err := s.instance.ExecContext(ctx, "INSERT INTO schema_migrations (version_id, is_applied) VALUES (?, 0)", v); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *ClickHouseStore) isApplied(ctx context.Context, v int64) (bool, error) {
+	var count uint64
+	if err := s.instance.QueryRowContext(ctx, "SELECT count() FROM schema_migrations FINAL WHERE version_id = ? AND is_applied = 1", v).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
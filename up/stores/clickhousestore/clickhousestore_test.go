@@ -0,0 +1,321 @@
+package clickhousestore_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonathonwebb/x/up"
+	"github.com/jonathonwebb/x/up/stores/clickhousestore"
+)
+
+func TestNew(t *testing.T) {
+	store := clickhousestore.New(nil)
+
+	if store == nil {
+		t.Fatalf("New(nil) = %v, want non-nil", store)
+	}
+	if store.DB() != nil {
+		t.Errorf("New(nil).DB() = %v, want nil", store.DB())
+	}
+}
+
+func TestStore_Initialized(t *testing.T) {
+	db := createTestDB(t)
+	store := clickhousestore.New(db)
+
+	if ok, err := store.Initialized(t.Context()); err != nil || ok {
+		t.Errorf("Initialized(ctx) = %v, %v, want false, no error", ok, err)
+	}
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+	if ok, err := store.Initialized(t.Context()); err != nil || !ok {
+		t.Errorf("Initialized(ctx) after Init = %v, %v, want true, no error", ok, err)
+	}
+}
+
+func TestStore_Lock(t *testing.T) {
+	db := createTestDB(t)
+	first := clickhousestore.New(db)
+	second := clickhousestore.New(db)
+	if err := first.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	if err := first.Lock(t.Context()); err != nil {
+		t.Fatalf("first.Lock(ctx) = %v, want no error", err)
+	}
+	if err := second.Lock(t.Context()); !errors.Is(err, up.ErrLocked) {
+		t.Errorf("second.Lock(ctx) = %v, want ErrLocked", err)
+	}
+
+	if err := second.Release(t.Context()); err != nil {
+		t.Fatalf("second.Release(ctx) = %v, want no error", err)
+	}
+	if err := second.Lock(t.Context()); !errors.Is(err, up.ErrLocked) {
+		t.Errorf("second.Lock(ctx) after foreign release = %v, want ErrLocked", err)
+	}
+
+	if err := first.Release(t.Context()); err != nil {
+		t.Fatalf("first.Release(ctx) = %v, want no error", err)
+	}
+	if err := second.Lock(t.Context()); err != nil {
+		t.Errorf("second.Lock(ctx) after release = %v, want no error", err)
+	}
+	if err := first.Lock(t.Context()); !errors.Is(err, up.ErrLocked) {
+		t.Errorf("first.Lock(ctx) = %v, want ErrLocked", err)
+	}
+}
+
+func TestStore_InsertRemove(t *testing.T) {
+	db := createTestDB(t)
+	store := clickhousestore.New(db)
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	for _, v := range []int64{2, 1, 3} {
+		if err := store.Insert(t.Context(), v); err != nil {
+			t.Fatalf("Insert(ctx, %d) = %v, want no error", v, err)
+		}
+	}
+	if err := store.Insert(t.Context(), 2); err == nil {
+		t.Error("Insert(ctx, 2) of an applied version = nil, want error")
+	}
+
+	if err := store.Remove(t.Context(), 3); err != nil {
+		t.Fatalf("Remove(ctx, 3) = %v, want no error", err)
+	}
+	if err := store.Remove(t.Context(), 3); !errors.Is(err, up.ErrVersionNotFound) {
+		t.Errorf("Remove(ctx, 3) of a removed version = %v, want ErrVersionNotFound", err)
+	}
+
+	history, err := store.History(t.Context())
+	if err != nil {
+		t.Fatalf("History(ctx) = %v, want no error", err)
+	}
+	var versions []int64
+	for _, record := range history {
+		versions = append(versions, record.Version)
+		if record.AppliedAt.IsZero() {
+			t.Errorf("History(ctx) record %d has no applied time", record.Version)
+		}
+	}
+	if want := []int64{1, 2}; !slices.Equal(versions, want) {
+		t.Errorf("History(ctx) versions = %v, want %v", versions, want)
+	}
+
+	version, err := store.Version(t.Context())
+	if err != nil {
+		t.Fatalf("Version(ctx) = %v, want no error", err)
+	}
+	if version != 2 {
+		t.Errorf("Version(ctx) = %d, want 2", version)
+	}
+
+	if err := store.Insert(t.Context(), 3); err != nil {
+		t.Errorf("Insert(ctx, 3) of a removed version = %v, want no error", err)
+	}
+}
+
+func createTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(&fakeConnector{state: &fakeState{}})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// fakeState emulates the ReplacingMergeTree tables of the store: each table is
+// an append-only log, and FINAL reads the latest row for each key.
+type fakeState struct {
+	mu     sync.Mutex
+	tables map[string]bool
+	lock   []fakeLockRow
+	rows   []fakeVersionRow
+}
+
+type fakeLockRow struct {
+	owner  string
+	locked int64
+}
+
+type fakeVersionRow struct {
+	version   int64
+	applied   int64
+	appliedAt time.Time
+}
+
+// final returns the latest row for each version, ordered by version.
+func (s *fakeState) final() []fakeVersionRow {
+	latest := make(map[int64]fakeVersionRow)
+	for _, row := range s.rows {
+		latest[row.version] = row
+	}
+	var rows []fakeVersionRow
+	for _, row := range latest {
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, func(a, b fakeVersionRow) int { return int(a.version - b.version) })
+	return rows
+}
+
+func (s *fakeState) exec(query string, args []driver.NamedValue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch query {
+	case "INSERT INTO schema_lock (id, owner, locked) VALUES (1, ?, 1)":
+		s.lock = append(s.lock, fakeLockRow{owner: args[0].Value.(string), locked: 1})
+	case "INSERT INTO schema_lock (id, owner, locked) VALUES (1, ?, 0)":
+		s.lock = append(s.lock, fakeLockRow{owner: args[0].Value.(string), locked: 0})
+	case "INSERT INTO schema_migrations (version_id, is_applied) VALUES (?, 1)":
+		s.rows = append(s.rows, fakeVersionRow{version: args[0].Value.(int64), applied: 1, appliedAt: time.Now()})
+	case "INSERT INTO schema_migrations (version_id, is_applied) VALUES (?, 0)":
+		s.rows = append(s.rows, fakeVersionRow{version: args[0].Value.(int64), applied: 0, appliedAt: time.Now()})
+	default:
+		table, ok := strings.CutPrefix(query, "CREATE TABLE IF NOT EXISTS ")
+		if !ok {
+			return fmt.Errorf("unexpected exec %q", query)
+		}
+		if s.tables == nil {
+			s.tables = make(map[string]bool)
+		}
+		name, _, _ := strings.Cut(table, " ")
+		s.tables[name] = true
+	}
+	return nil
+}
+
+func (s *fakeState) query(query string, args []driver.NamedValue) (*fakeRows, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows := &fakeRows{}
+	switch query {
+	case "SELECT count() FROM system.tables WHERE database = currentDatabase() AND name = 'schema_migrations'":
+		rows.columns = []string{"count()"}
+		var count int64
+		if s.tables["schema_migrations"] {
+			count = 1
+		}
+		rows.values = append(rows.values, []driver.Value{count})
+	case "SELECT locked FROM schema_lock FINAL WHERE id = 1":
+		rows.columns = []string{"locked"}
+		if len(s.lock) > 0 {
+			rows.values = append(rows.values, []driver.Value{s.lock[len(s.lock)-1].locked})
+		}
+	case "SELECT owner FROM schema_lock FINAL WHERE id = 1":
+		rows.columns = []string{"owner"}
+		if len(s.lock) > 0 {
+			rows.values = append(rows.values, []driver.Value{s.lock[len(s.lock)-1].owner})
+		}
+	case "SELECT owner, locked FROM schema_lock FINAL WHERE id = 1":
+		rows.columns = []string{"owner", "locked"}
+		if len(s.lock) > 0 {
+			last := s.lock[len(s.lock)-1]
+			rows.values = append(rows.values, []driver.Value{last.owner, last.locked})
+		}
+	case "SELECT version_id FROM schema_migrations FINAL WHERE is_applied = 1 ORDER BY version_id DESC LIMIT 1":
+		rows.columns = []string{"version_id"}
+		final := s.final()
+		for _, row := range slices.Backward(final) {
+			if row.applied == 1 {
+				rows.values = append(rows.values, []driver.Value{row.version})
+				break
+			}
+		}
+	case "SELECT version_id, applied_at FROM schema_migrations FINAL WHERE is_applied = 1 ORDER BY version_id ASC":
+		rows.columns = []string{"version_id", "applied_at"}
+		for _, row := range s.final() {
+			if row.applied == 1 {
+				rows.values = append(rows.values, []driver.Value{row.version, row.appliedAt})
+			}
+		}
+	case "SELECT count() FROM schema_migrations FINAL WHERE version_id = ? AND is_applied = 1":
+		rows.columns = []string{"count()"}
+		var count int64
+		for _, row := range s.final() {
+			if row.version == args[0].Value.(int64) && row.applied == 1 {
+				count++
+			}
+		}
+		rows.values = append(rows.values, []driver.Value{count})
+	default:
+		return nil, fmt.Errorf("unexpected query %q", query)
+	}
+	return rows, nil
+}
+
+type fakeConnector struct {
+	state *fakeState
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{state: c.state}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("use a connector")
+}
+
+type fakeConn struct {
+	state *fakeState
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.state.exec(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.state.query(query, args)
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,307 @@
+// Package crdbstore provides a CockroachDB implementation of the up.Store
+// interface.
+//
+// CockroachDB runs all transactions at SERIALIZABLE isolation and reports
+// contention as a retryable error with SQLSTATE 40001. Every statement issued
+// by the store is retried when it fails with that code. Errors are matched by
+// any driver error type exposing a SQLState() string method, such as those
+// returned by pgx and lib/pq.
+//
+// Lock lease expiry times are computed by the cluster, so processes do not need
+// synchronized clocks.
+package crdbstore
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/jonathonwebb/x/retry"
+	"github.com/jonathonwebb/x/up"
+)
+
+const (
+	codeSerializationFailure = "40001"
+	codeUniqueViolation      = "23505"
+)
+
+type CrdbStore struct {
+	instance *sql.DB
+	owner    string
+	retry    []retry.Option
+}
+
+var (
+	_ up.Store[up.SQLConn] = (*CrdbStore)(nil)
+	_ up.LeaseStore        = (*CrdbStore)(nil)
+	_ up.ForceUnlocker     = (*CrdbStore)(nil)
+	_ up.InitChecker       = (*CrdbStore)(nil)
+)
+
+// New returns a store using db. The retry options customize how statements are
+// retried on serialization failures; by default a statement is tried up to 5
+// times with jittered exponential backoff starting at 50ms.
+func New(db *sql.DB, opts ...retry.Option) *CrdbStore {
+	defaults := []retry.Option{
+		retry.WithMaxTries(5),
+		retry.WithDelay(50 * time.Millisecond),
+		retry.WithMaxDelay(2 * time.Second),
+		retry.WithBackoffFactor(2),
+		retry.WithJitter(0.5),
+	}
+	token := make([]byte, 16)
+	rand.Read(token)
+	return &CrdbStore{
+		instance: db,
+		owner:    hex.EncodeToString(token),
+		retry:    append(defaults, opts...),
+	}
+}
+
+func (s *CrdbStore) DB() *sql.DB {
+	return s.instance
+}
+
+func (s *CrdbStore) Conn() up.SQLConn {
+	return s.instance
+}
+
+func (s *CrdbStore) Init(ctx context.Context) error {
+	if err := s.withRetry(ctx, func(ctx context.Context) error {
+		return s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
+			if _, err := tx.ExecContext(tCtx, "CREATE TABLE IF NOT EXISTS schema_lock (id INT8 PRIMARY KEY, owner STRING, expires_at TIMESTAMPTZ)"); err != nil {
+				return err
+			}
+
+			if _, err := tx.ExecContext(tCtx, "CREATE TABLE IF NOT EXISTS schema_migrations (id INT8 PRIMARY KEY DEFAULT unique_rowid(), version_id INT8 UNIQUE NOT NULL, applied_at TIMESTAMPTZ NOT NULL DEFAULT now())"); err != nil {
+				return err
+			}
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	// Lock tables created before leases were supported lack the owner and
+	// expiry columns. Schema changes run outside the transaction above.
+	for _, stmt := range []string{
+		"ALTER TABLE schema_lock ADD COLUMN IF NOT EXISTS owner STRING",
+		"ALTER TABLE schema_lock ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ",
+	} {
+		if err := s.withRetry(ctx, func(ctx context.Context) error {
+			_, err := s.instance.ExecContext(ctx, stmt)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CrdbStore) Lock(ctx context.Context) error {
+	err := s.withRetry(ctx, func(ctx context.Context) error {
+		_, err := s.instance.ExecContext(ctx, "INSERT INTO schema_lock (id, owner) VALUES (1, $1)", s.owner)
+		return err
+	})
+	if err == nil {
+		return nil
+	}
+
+	if sqlState(err) == codeUniqueViolation {
+		return up.ErrLocked
+	}
+	return err
+}
+
+// LockLease acquires the lock until ttl from now, taking over an expired lock.
+func (s *CrdbStore) LockLease(ctx context.Context, ttl time.Duration) error {
+	err := s.withRetry(ctx, func(ctx context.Context) error {
+		return s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
+			if _, err := tx.ExecContext(tCtx, "DELETE FROM schema_lock WHERE id = 1 AND expires_at IS NOT NULL AND expires_at <= now()"); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(tCtx, "INSERT INTO schema_lock (id, owner, expires_at) VALUES (1, $1, now() + $2 * INTERVAL '1 millisecond')", s.owner, ttl.Milliseconds())
+			return err
+		})
+	})
+	if err == nil {
+		return nil
+	}
+
+	if sqlState(err) == codeUniqueViolation {
+		return up.ErrLocked
+	}
+	return err
+}
+
+// RenewLease extends the lease of a lock held by this store until ttl from
+// now.
+func (s *CrdbStore) RenewLease(ctx context.Context, ttl time.Duration) error {
+	var rowsAffected int64
+	err := s.withRetry(ctx, func(ctx context.Context) error {
+		res, err := s.instance.ExecContext(ctx, "UPDATE schema_lock SET expires_at = now() + $1 * INTERVAL '1 millisecond' WHERE id = 1 AND owner = $2", ttl.Milliseconds(), s.owner)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return up.ErrLeaseLost
+	}
+	return nil
+}
+
+// Release releases a lock held by this store. Locks without an owner, which
+// were taken before leases were supported, are released too.
+func (s *CrdbStore) Release(ctx context.Context) error {
+	return s.withRetry(ctx, func(ctx context.Context) error {
+		_, err := s.instance.ExecContext(ctx, "DELETE FROM schema_lock WHERE id = 1 AND (owner IS NULL OR owner = $1)", s.owner)
+		return err
+	})
+}
+
+// ForceUnlock releases the lock regardless of which store holds it.
+func (s *CrdbStore) ForceUnlock(ctx context.Context) error {
+	return s.withRetry(ctx, func(ctx context.Context) error {
+		_, err := s.instance.ExecContext(ctx, "DELETE FROM schema_lock WHERE id = 1")
+		return err
+	})
+}
+
+// Initialized reports whether Init has created the schema_migrations table in
+// the current schema.
+func (s *CrdbStore) Initialized(ctx context.Context) (bool, error) {
+	var n int64
+	err := s.withRetry(ctx, func(ctx context.Context) error {
+		return s.instance.QueryRowContext(ctx, "SELECT count(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'").Scan(&n)
+	})
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *CrdbStore) Version(ctx context.Context) (int64, error) {
+	var version int64
+	err := s.withRetry(ctx, func(ctx context.Context) error {
+		row := s.instance.QueryRowContext(ctx, "SELECT version_id FROM schema_migrations ORDER BY version_id DESC LIMIT 1")
+		return row.Scan(&version)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *CrdbStore) History(ctx context.Context) ([]up.Record, error) {
+	var records []up.Record
+	err := s.withRetry(ctx, func(ctx context.Context) error {
+		records = nil
+		return queryRecords(ctx, s.instance, "SELECT version_id, applied_at FROM schema_migrations ORDER BY version_id ASC", &records)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (s *CrdbStore) Insert(ctx context.Context, v int64) error {
+	return s.withRetry(ctx, func(ctx context.Context) error {
+		_, err := s.instance.ExecContext(ctx, "INSERT INTO schema_migrations (version_id) VALUES ($1)", v)
+		return err
+	})
+}
+
+func (s *CrdbStore) Remove(ctx context.Context, v int64) error {
+	var rowsAffected int64
+	err := s.withRetry(ctx, func(ctx context.Context) error {
+		res, err := s.instance.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version_id = $1", v)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return up.ErrVersionNotFound
+	}
+	return nil
+}
+
+// withRetry calls fn until it succeeds, fails with an error other than a
+// serialization failure, or the retry options are exhausted.
+func (s *CrdbStore) withRetry(ctx context.Context, fn func(context.Context) error) error {
+	var permanentErr error
+	err := retry.RetryContext(ctx, func(ctx context.Context) error {
+		err := fn(ctx)
+		if err != nil && sqlState(err) != codeSerializationFailure {
+			permanentErr = err
+			return nil
+		}
+		return err
+	}, s.retry...)
+	if permanentErr != nil {
+		return permanentErr
+	}
+	return err
+}
+
+func (s *CrdbStore) withTx(ctx context.Context, fn func(context.Context, *sql.Tx) error) (err error) {
+	tx, err := s.instance.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				err = errors.Join(err, rollbackErr)
+			}
+		} else {
+			if commitErr := tx.Commit(); commitErr != nil {
+				err = errors.Join(err, commitErr)
+			}
+		}
+	}()
+
+	return fn(ctx, tx)
+}
+
+func queryRecords(ctx context.Context, db *sql.DB, query string, records *[]up.Record) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r up.Record
+		if err := rows.Scan(&r.Version, &r.AppliedAt); err != nil {
+			return err
+		}
+		*records = append(*records, r)
+	}
+	return rows.Err()
+}
+
+// sqlState returns the SQLSTATE code of err, or "" if err does not carry one.
+func sqlState(err error) string {
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+	return ""
+}
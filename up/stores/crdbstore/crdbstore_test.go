@@ -0,0 +1,254 @@
+package crdbstore_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jonathonwebb/x/retry"
+	"github.com/jonathonwebb/x/up"
+	"github.com/jonathonwebb/x/up/stores/crdbstore"
+)
+
+// Tests that need a database connect to the CockroachDB cluster at the URL in
+// this environment variable, and are skipped if it is unset.
+const databaseURLVar = "CRDBSTORE_TEST_DATABASE_URL"
+
+func TestNew(t *testing.T) {
+	store := crdbstore.New(nil)
+
+	if store == nil {
+		t.Fatalf("New(nil) = %v, want non-nil", store)
+	}
+}
+
+func TestCrdbStore_Retry(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "serialization_failure", code: "40001", wantCalls: 2},
+		{name: "other_error", code: "42P01", wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &failingConn{errs: []error{&stateError{code: tt.code}}}
+			db := sql.OpenDB(&failingConnector{conn: conn})
+			t.Cleanup(func() { db.Close() })
+			store := crdbstore.New(db, retry.WithDelay(0), retry.WithJitter(0))
+
+			err := store.Insert(t.Context(), 1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("store.Insert(ctx, 1) = %v, want error %t", err, tt.wantErr)
+			}
+			if conn.calls != tt.wantCalls {
+				t.Errorf("store.Insert(ctx, 1) made %d calls, want %d", conn.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// stateError is an error with a SQLSTATE code, like those of pgx.
+type stateError struct {
+	code string
+}
+
+func (e *stateError) Error() string    { return "SQLSTATE " + e.code }
+func (e *stateError) SQLState() string { return e.code }
+
+// failingConnector returns conn for every connection.
+type failingConnector struct {
+	conn *failingConn
+}
+
+func (c *failingConnector) Connect(context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c *failingConnector) Driver() driver.Driver                        { return nil }
+
+// failingConn fails statements with errs in order, then succeeds.
+type failingConn struct {
+	errs  []error
+	calls int
+}
+
+func (c *failingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *failingConn) Close() error                        { return nil }
+func (c *failingConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *failingConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	c.calls++
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func TestCrdbStore_Init(t *testing.T) {
+	db := createTestDB(t)
+	store := crdbstore.New(db)
+
+	if err := store.Init(t.Context()); err != nil {
+		t.Errorf("store.Init(ctx) = %v, want no error", err)
+	}
+
+	if err := store.Init(t.Context()); err != nil {
+		t.Errorf("store.Init(ctx) = %v, want no error", err)
+	}
+}
+
+func TestCrdbStore_Initialized(t *testing.T) {
+	db := createTestDB(t)
+	store := crdbstore.New(db)
+
+	if ok, err := store.Initialized(t.Context()); err != nil || ok {
+		t.Errorf("store.Initialized(ctx) = %v, %v, want false, no error", ok, err)
+	}
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+	if ok, err := store.Initialized(t.Context()); err != nil || !ok {
+		t.Errorf("store.Initialized(ctx) after Init = %v, %v, want true, no error", ok, err)
+	}
+}
+
+func TestCrdbStore_Lock(t *testing.T) {
+	db := createTestDB(t)
+	store := crdbstore.New(db)
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	if err := store.Lock(t.Context()); err != nil {
+		t.Errorf("store.Lock(ctx) = %v, want no error", err)
+	}
+
+	if err := store.Lock(t.Context()); !errors.Is(err, up.ErrLocked) {
+		t.Errorf("store.Lock(ctx) = %v, want ErrLocked", err)
+	}
+
+	if err := store.Release(t.Context()); err != nil {
+		t.Errorf("store.Release(ctx) = %v, want no error", err)
+	}
+
+	if err := store.Lock(t.Context()); err != nil {
+		t.Errorf("store.Lock(ctx) = %v, want no error", err)
+	}
+}
+
+func TestCrdbStore_LockLease(t *testing.T) {
+	db := createTestDB(t)
+	first := crdbstore.New(db)
+	second := crdbstore.New(db)
+	if err := first.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	if err := first.LockLease(t.Context(), time.Hour); err != nil {
+		t.Fatalf("first.LockLease(ctx) = %v, want no error", err)
+	}
+	if err := second.LockLease(t.Context(), time.Hour); !errors.Is(err, up.ErrLocked) {
+		t.Errorf("second.LockLease(ctx) = %v, want ErrLocked", err)
+	}
+	if err := first.RenewLease(t.Context(), -time.Second); err != nil {
+		t.Fatalf("first.RenewLease(ctx) = %v, want no error", err)
+	}
+
+	// The first lease has expired, so the second store takes over.
+	if err := second.LockLease(t.Context(), time.Hour); err != nil {
+		t.Fatalf("second.LockLease(ctx) = %v, want no error", err)
+	}
+	if err := first.RenewLease(t.Context(), time.Hour); !errors.Is(err, up.ErrLeaseLost) {
+		t.Errorf("first.RenewLease(ctx) = %v, want ErrLeaseLost", err)
+	}
+	if err := second.Release(t.Context()); err != nil {
+		t.Errorf("second.Release(ctx) = %v, want no error", err)
+	}
+}
+
+func TestCrdbStore_Versions(t *testing.T) {
+	db := createTestDB(t)
+	store := crdbstore.New(db)
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	if got, err := store.Version(t.Context()); err != nil || got != 0 {
+		t.Errorf("store.Version(ctx) = %d, %v, want 0, no error", got, err)
+	}
+
+	for _, v := range []int64{1, 2, 3} {
+		if err := store.Insert(t.Context(), v); err != nil {
+			t.Fatalf("failed to insert version %d: %v", v, err)
+		}
+	}
+
+	if err := store.Insert(t.Context(), 3); err == nil {
+		t.Errorf("store.Insert(ctx, 3) = nil, want error")
+	}
+
+	if got, err := store.Version(t.Context()); err != nil || got != 3 {
+		t.Errorf("store.Version(ctx) = %d, %v, want 3, no error", got, err)
+	}
+
+	if err := store.Remove(t.Context(), 3); err != nil {
+		t.Errorf("store.Remove(ctx, 3) = %v, want no error", err)
+	}
+
+	if err := store.Remove(t.Context(), 4); !errors.Is(err, up.ErrVersionNotFound) {
+		t.Errorf("store.Remove(ctx, 4) = %v, want ErrVersionNotFound", err)
+	}
+
+	if got, want := currentVersions(t, db), []int64{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("got versions %v, want %v", got, want)
+	}
+}
+
+func createTestDB(t testing.TB) *sql.DB {
+	t.Helper()
+	url := os.Getenv(databaseURLVar)
+	if url == "" {
+		t.Skipf("$%s is not set", databaseURLVar)
+	}
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for _, table := range []string{"schema_lock", "schema_migrations"} {
+		if _, err := db.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func currentVersions(t testing.TB, db *sql.DB) []int64 {
+	t.Helper()
+	var versions []int64
+	rows, err := db.Query("SELECT version_id FROM schema_migrations ORDER BY version_id ASC")
+	if err != nil {
+		t.Fatalf("failed to get current versions: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			t.Fatalf("failed to scan version: %v", err)
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to iterate over versions: %v", err)
+	}
+	return versions
+}
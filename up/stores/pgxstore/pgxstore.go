@@ -0,0 +1,242 @@
+// Package pgxstore provides a PostgreSQL implementation of the up.Store
+// interface using the native pgx driver.
+package pgxstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jonathonwebb/x/up"
+)
+
+// DB is the connection handle passed to migrations. It is implemented by
+// [*pgx.Conn], [*pgxpool.Pool] and [pgx.Tx].
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// defaultLockKey is the session-level advisory lock key used to guard the
+// version store unless configured with [WithLockKey].
+const defaultLockKey int64 = 7_303_014_565_210_112_592
+
+type PgxStore struct {
+	instance DB
+	lockKey  int64
+	lockConn *pgxpool.Conn
+}
+
+// An Option configures a PgxStore.
+type Option func(*PgxStore)
+
+// WithLockKey sets the advisory lock key used as the version store lock.
+// Applications that share a database but migrate independently can use
+// different keys so their runs don't block each other.
+func WithLockKey(key int64) Option {
+	return func(s *PgxStore) {
+		s.lockKey = key
+	}
+}
+
+var (
+	_ up.Store[DB]   = (*PgxStore)(nil)
+	_ up.TxStore[DB] = (*PgxStore)(nil)
+	_ up.BatchStore  = (*PgxStore)(nil)
+	_ up.InitChecker = (*PgxStore)(nil)
+)
+
+func New(db DB, opts ...Option) *PgxStore {
+	s := &PgxStore{instance: db, lockKey: defaultLockKey}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *PgxStore) Conn() DB {
+	return s.instance
+}
+
+// WithTx calls fn with a copy of the store bound to a new transaction.
+// Migrations created by [TxMigration] run in savepoints within it.
+func (s *PgxStore) WithTx(ctx context.Context, fn func(context.Context, up.Store[DB]) error) error {
+	return pgx.BeginFunc(ctx, s.instance, func(tx pgx.Tx) error {
+		return fn(ctx, &PgxStore{instance: tx, lockKey: s.lockKey})
+	})
+}
+
+func (s *PgxStore) Init(ctx context.Context) error {
+	return pgx.BeginFunc(ctx, s.instance, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (id BIGSERIAL PRIMARY KEY, version_id BIGINT UNIQUE NOT NULL, applied_at TIMESTAMPTZ NOT NULL DEFAULT now())"); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// Lock acquires a session-level advisory lock. When the store is backed by a
+// [*pgxpool.Pool], a connection is checked out of the pool and held until
+// [PgxStore.Release] is called, since advisory locks belong to the session
+// that took them.
+func (s *PgxStore) Lock(ctx context.Context) error {
+	if s.lockConn != nil {
+		return up.ErrLocked
+	}
+
+	var session DB = s.instance
+	var conn *pgxpool.Conn
+	if pool, ok := s.instance.(interface {
+		Acquire(context.Context) (*pgxpool.Conn, error)
+	}); ok {
+		var err error
+		conn, err = pool.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		session = conn
+	}
+
+	var locked bool
+	if err := session.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", s.lockKey).Scan(&locked); err != nil {
+		if conn != nil {
+			conn.Release()
+		}
+		return err
+	}
+	if !locked {
+		if conn != nil {
+			conn.Release()
+		}
+		return up.ErrLocked
+	}
+
+	s.lockConn = conn
+	return nil
+}
+
+func (s *PgxStore) Release(ctx context.Context) error {
+	var session DB = s.instance
+	if s.lockConn != nil {
+		session = s.lockConn
+		defer func() {
+			s.lockConn.Release()
+			s.lockConn = nil
+		}()
+	}
+
+	var unlocked bool
+	if err := session.QueryRow(ctx, "SELECT pg_advisory_unlock($1)", s.lockKey).Scan(&unlocked); err != nil {
+		return err
+	}
+	if !unlocked {
+		return errors.New("advisory lock was not held")
+	}
+	return nil
+}
+
+// Initialized reports whether Init has created the schema_migrations table in
+// the current schema.
+func (s *PgxStore) Initialized(ctx context.Context) (bool, error) {
+	var n int64
+	if err := s.instance.QueryRow(ctx, "SELECT count(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'").Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *PgxStore) Version(ctx context.Context) (int64, error) {
+	row := s.instance.QueryRow(ctx, "SELECT version_id FROM schema_migrations ORDER BY version_id DESC LIMIT 1")
+	var version int64
+	if err := row.Scan(&version); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *PgxStore) History(ctx context.Context) ([]up.Record, error) {
+	rows, err := s.instance.Query(ctx, "SELECT version_id, applied_at FROM schema_migrations ORDER BY version_id ASC")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (up.Record, error) {
+		var r up.Record
+		err := row.Scan(&r.Version, &r.AppliedAt)
+		return r, err
+	})
+}
+
+func (s *PgxStore) Insert(ctx context.Context, v int64) error {
+	if _, err := s.instance.Exec(ctx, "INSERT INTO schema_migrations (version_id) VALUES ($1)", v); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *PgxStore) Remove(ctx context.Context, v int64) error {
+	tag, err := s.instance.Exec(ctx, "DELETE FROM schema_migrations WHERE version_id = $1", v)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return up.ErrVersionNotFound
+	}
+	return nil
+}
+
+func (s *PgxStore) InsertMany(ctx context.Context, versions []int64) error {
+	if _, err := s.instance.Exec(ctx, "INSERT INTO schema_migrations (version_id) SELECT unnest($1::BIGINT[])", versions); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *PgxStore) RemoveMany(ctx context.Context, versions []int64) error {
+	return pgx.BeginFunc(ctx, s.instance, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version_id = ANY($1)", versions)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() != int64(len(versions)) {
+			return up.ErrVersionNotFound
+		}
+		return nil
+	})
+}
+
+// TxMigration returns a migration whose run and revert functions are each
+// executed inside their own transaction. The transaction is committed if the
+// function returns nil, and rolled back otherwise. A nil revert function
+// leaves the migration without a revert function.
+func TxMigration(version int64, name string, run, revert func(context.Context, pgx.Tx) error) *up.Migration[DB] {
+	m := &up.Migration[DB]{
+		Version: version,
+		Name:    name,
+	}
+	if run != nil {
+		m.RunFunc = txFunc(run)
+	}
+	if revert != nil {
+		m.RevertFunc = txFunc(revert)
+	}
+	return m
+}
+
+func txFunc(fn func(context.Context, pgx.Tx) error) func(context.Context, DB) error {
+	return func(ctx context.Context, db DB) error {
+		if err := pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+			return fn(ctx, tx)
+		}); err != nil {
+			return fmt.Errorf("transaction failed: %w", err)
+		}
+		return nil
+	}
+}
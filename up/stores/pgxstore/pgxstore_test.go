@@ -0,0 +1,407 @@
+package pgxstore_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jonathonwebb/x/up"
+	"github.com/jonathonwebb/x/up/stores/pgxstore"
+)
+
+// Tests that need a database connect to the PostgreSQL server at the URL in
+// this environment variable, and are skipped if it is unset.
+const databaseURLVar = "PGXSTORE_TEST_DATABASE_URL"
+
+func TestNew(t *testing.T) {
+	store := pgxstore.New(nil)
+
+	if store == nil {
+		t.Fatalf("New(nil) = %v, want non-nil", store)
+	}
+}
+
+func TestPgxStore_Init(t *testing.T) {
+	t.Run("fresh_database", func(t *testing.T) {
+		pool := createTestPool(t)
+		store := pgxstore.New(pool)
+
+		if err := store.Init(t.Context()); err != nil {
+			t.Errorf("store.Init(ctx) = %v, want no error", err)
+		}
+	})
+
+	t.Run("existing_database", func(t *testing.T) {
+		pool := createTestPool(t)
+		store := pgxstore.New(pool)
+		if err := store.Init(t.Context()); err != nil {
+			t.Fatalf("failed to init: %v", err)
+		}
+
+		if err := store.Init(t.Context()); err != nil {
+			t.Errorf("store.Init(ctx) = %v, want no error", err)
+		}
+	})
+}
+
+func TestPgxStore_Initialized(t *testing.T) {
+	pool := createTestPool(t)
+	store := pgxstore.New(pool)
+
+	if ok, err := store.Initialized(t.Context()); err != nil || ok {
+		t.Errorf("store.Initialized(ctx) = %v, %v, want false, no error", ok, err)
+	}
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+	if ok, err := store.Initialized(t.Context()); err != nil || !ok {
+		t.Errorf("store.Initialized(ctx) after Init = %v, %v, want true, no error", ok, err)
+	}
+}
+
+func TestPgxStore_Lock(t *testing.T) {
+	pool := createTestPool(t)
+	store := pgxstore.New(pool)
+	other := pgxstore.New(pool)
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	if err := store.Lock(t.Context()); err != nil {
+		t.Errorf("store.Lock(ctx) = %v, want no error", err)
+	}
+	t.Cleanup(func() { store.Release(context.Background()) })
+
+	if err := store.Lock(t.Context()); !errors.Is(err, up.ErrLocked) {
+		t.Errorf("store.Lock(ctx) = %v, want ErrLocked", err)
+	}
+
+	if err := other.Lock(t.Context()); !errors.Is(err, up.ErrLocked) {
+		t.Errorf("other.Lock(ctx) = %v, want ErrLocked", err)
+	}
+}
+
+func TestPgxStore_LockKey(t *testing.T) {
+	pool := createTestPool(t)
+	first := pgxstore.New(pool)
+	second := pgxstore.New(pool, pgxstore.WithLockKey(42))
+
+	if err := first.Lock(t.Context()); err != nil {
+		t.Fatalf("first.Lock(ctx) = %v, want no error", err)
+	}
+	t.Cleanup(func() { first.Release(context.Background()) })
+
+	if err := second.Lock(t.Context()); err != nil {
+		t.Errorf("second.Lock(ctx) = %v, want no error", err)
+	}
+	if err := second.Release(t.Context()); err != nil {
+		t.Errorf("second.Release(ctx) = %v, want no error", err)
+	}
+}
+
+func TestPgxStore_Release(t *testing.T) {
+	pool := createTestPool(t)
+	store := pgxstore.New(pool)
+	other := pgxstore.New(pool)
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	if err := store.Lock(t.Context()); err != nil {
+		t.Fatalf("failed to lock: %v", err)
+	}
+
+	if err := store.Release(t.Context()); err != nil {
+		t.Errorf("store.Release(ctx) = %v, want no error", err)
+	}
+
+	if err := other.Lock(t.Context()); err != nil {
+		t.Errorf("other.Lock(ctx) = %v, want no error", err)
+	}
+	if err := other.Release(t.Context()); err != nil {
+		t.Errorf("other.Release(ctx) = %v, want no error", err)
+	}
+}
+
+func TestPgxStore_Version(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []int64
+	}{
+		{
+			name:     "no_migrations",
+			versions: []int64{},
+		},
+		{
+			name:     "single_migration",
+			versions: []int64{1},
+		},
+		{
+			name:     "multiple_migrations",
+			versions: []int64{1, 2, 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := createTestPool(t)
+			store := pgxstore.New(pool)
+			if err := store.Init(t.Context()); err != nil {
+				t.Fatalf("failed to init: %v", err)
+			}
+
+			for _, v := range test.versions {
+				if err := store.Insert(t.Context(), v); err != nil {
+					t.Fatalf("failed to insert version %d: %v", v, err)
+				}
+			}
+
+			var want int64
+			if len(test.versions) > 0 {
+				want = test.versions[len(test.versions)-1]
+			}
+			got, err := store.Version(t.Context())
+			if err != nil {
+				t.Errorf("store.Version(ctx) = %v, want no error", err)
+			}
+			if got != want {
+				t.Errorf("store.Version(ctx) = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestPgxStore_Insert(t *testing.T) {
+	tests := []struct {
+		name          string
+		versions      []int64
+		insertVersion int64
+		wantErr       bool
+	}{
+		{
+			name:          "insert_first",
+			versions:      []int64{},
+			insertVersion: 1,
+			wantErr:       false,
+		},
+		{
+			name:          "insert_additional",
+			versions:      []int64{1, 2},
+			insertVersion: 3,
+			wantErr:       false,
+		},
+		{
+			name:          "insert_duplicate",
+			versions:      []int64{1, 2},
+			insertVersion: 2,
+			wantErr:       true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := createTestPool(t)
+			store := pgxstore.New(pool)
+			if err := store.Init(t.Context()); err != nil {
+				t.Fatalf("failed to initialize store: %v", err)
+			}
+
+			for _, v := range test.versions {
+				if err := store.Insert(t.Context(), v); err != nil {
+					t.Fatalf("failed to insert version %d: %v", v, err)
+				}
+			}
+
+			err := store.Insert(t.Context(), test.insertVersion)
+			if err != nil && !test.wantErr {
+				t.Errorf("store.Insert(ctx, %d) error = %v, want no error", test.insertVersion, err)
+			}
+			if err == nil && test.wantErr {
+				t.Errorf("store.Insert(ctx, %d) error = %v, want error", test.insertVersion, err)
+			}
+
+			if !test.wantErr {
+				versions := currentVersions(t, pool)
+				wantVersions := append(test.versions, test.insertVersion)
+				if !slices.Equal(versions, wantVersions) {
+					t.Errorf("got versions %v, want %v", versions, wantVersions)
+				}
+			}
+		})
+	}
+}
+
+func TestPgxStore_Remove(t *testing.T) {
+	tests := []struct {
+		name          string
+		versions      []int64
+		removeVersion int64
+		wantErr       bool
+	}{
+		{
+			name:          "remove_existing_version",
+			versions:      []int64{1, 2, 3},
+			removeVersion: 3,
+			wantErr:       false,
+		},
+		{
+			name:          "remove_nonexistent_version",
+			versions:      []int64{1, 2, 3},
+			removeVersion: 4,
+			wantErr:       true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := createTestPool(t)
+			store := pgxstore.New(pool)
+			if err := store.Init(t.Context()); err != nil {
+				t.Fatalf("failed to initialize store: %v", err)
+			}
+
+			for _, v := range test.versions {
+				if err := store.Insert(t.Context(), v); err != nil {
+					t.Fatalf("failed to insert version %d: %v", v, err)
+				}
+			}
+
+			err := store.Remove(t.Context(), test.removeVersion)
+
+			if err == nil && test.wantErr {
+				t.Errorf("store.Remove(ctx, %d) error = %v, want error", test.removeVersion, err)
+			}
+
+			if !test.wantErr {
+				versions := currentVersions(t, pool)
+				wantVersions := slices.DeleteFunc(test.versions, func(v int64) bool { return v == test.removeVersion })
+				if !slices.Equal(versions, wantVersions) {
+					t.Errorf("got versions %v, want %v", versions, wantVersions)
+				}
+			}
+		})
+	}
+}
+
+func TestTxMigration(t *testing.T) {
+	pool := createTestPool(t)
+
+	migration := pgxstore.TxMigration(1, "create_widgets",
+		func(ctx context.Context, tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, "CREATE TABLE widgets (id INTEGER)"); err != nil {
+				return err
+			}
+			return errors.New("rollback")
+		},
+		nil,
+	)
+
+	if err := migration.Run(t.Context(), pool); err == nil {
+		t.Errorf("migration.Run(ctx, pool) = nil, want error")
+	}
+
+	var exists bool
+	if err := pool.QueryRow(t.Context(), "SELECT to_regclass('widgets') IS NOT NULL").Scan(&exists); err != nil {
+		t.Fatalf("failed to check table: %v", err)
+	}
+	if exists {
+		t.Error("expected widgets table to be rolled back")
+	}
+
+	if err := migration.Revert(t.Context(), pool); err == nil {
+		t.Errorf("migration.Revert(ctx, pool) = nil, want error")
+	}
+}
+
+func TestPgxStore_WithTx(t *testing.T) {
+	pool := createTestPool(t)
+	store := pgxstore.New(pool)
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	err := store.WithTx(t.Context(), func(ctx context.Context, tx up.Store[pgxstore.DB]) error {
+		if _, err := tx.Conn().Exec(ctx, "CREATE TABLE widgets (id INTEGER)"); err != nil {
+			return err
+		}
+		if err := tx.Insert(ctx, 1); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	if err == nil {
+		t.Fatal("store.WithTx(ctx, fn) = nil, want error")
+	}
+
+	if got := currentVersions(t, pool); len(got) != 0 {
+		t.Errorf("versions = %v, want none", got)
+	}
+	var exists bool
+	if err := pool.QueryRow(t.Context(), "SELECT to_regclass('widgets') IS NOT NULL").Scan(&exists); err != nil {
+		t.Fatalf("failed to check table: %v", err)
+	}
+	if exists {
+		t.Error("expected widgets table to be rolled back")
+	}
+}
+
+func createTestPool(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv(databaseURLVar)
+	if url == "" {
+		t.Skipf("$%s is not set", databaseURLVar)
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	for _, table := range []string{"schema_migrations", "widgets"} {
+		if _, err := pool.Exec(context.Background(), "DROP TABLE IF EXISTS "+table); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return pool
+}
+
+func currentVersions(t testing.TB, db pgxstore.DB) []int64 {
+	t.Helper()
+	rows, err := db.Query(context.Background(), "SELECT version_id FROM schema_migrations ORDER BY version_id ASC")
+	if err != nil {
+		t.Fatalf("failed to get current versions: %v", err)
+	}
+	versions, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		t.Fatalf("failed to scan versions: %v", err)
+	}
+	return versions
+}
+
+func TestPgxStore_Batch(t *testing.T) {
+	pool := createTestPool(t)
+	store := pgxstore.New(pool)
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	if err := store.InsertMany(t.Context(), []int64{1, 2, 3}); err != nil {
+		t.Fatalf("store.InsertMany(ctx, versions) = %v, want no error", err)
+	}
+	if got := currentVersions(t, pool); !slices.Equal(got, []int64{1, 2, 3}) {
+		t.Errorf("got versions %v, want [1 2 3]", got)
+	}
+
+	if err := store.RemoveMany(t.Context(), []int64{2, 4}); !errors.Is(err, up.ErrVersionNotFound) {
+		t.Errorf("store.RemoveMany(ctx, missing) = %v, want ErrVersionNotFound", err)
+	}
+	if err := store.RemoveMany(t.Context(), []int64{2, 3}); err != nil {
+		t.Fatalf("store.RemoveMany(ctx, versions) = %v, want no error", err)
+	}
+	if got := currentVersions(t, pool); !slices.Equal(got, []int64{1}) {
+		t.Errorf("got versions %v, want [1]", got)
+	}
+}
@@ -1,10 +1,20 @@
 // Package sqlite3store provides a SQLite3 implementation of the up.Store interface.
+//
+// Lock leases are stored as expiry times taken from the local clock, so
+// processes sharing a database should have roughly synchronized clocks.
 package sqlite3store
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/jonathonwebb/x/up"
 	"github.com/mattn/go-sqlite3"
@@ -12,27 +22,96 @@ import (
 
 type Sqlite3Store struct {
 	instance *sql.DB
+	tx       *sql.Tx // set on stores bound to a transaction by WithTx
+	owner    string
+	lockID   int64
 }
 
-var _ up.Store = (*Sqlite3Store)(nil)
+// An Option configures a Sqlite3Store.
+type Option func(*Sqlite3Store)
 
-func New(db *sql.DB) *Sqlite3Store {
-	return &Sqlite3Store{db}
+// WithLockID sets the id of the schema_lock row used as the version store
+// lock. Applications that share a database but migrate independently can use
+// different ids so their runs don't block each other. The default is 1.
+func WithLockID(id int64) Option {
+	return func(s *Sqlite3Store) {
+		s.lockID = id
+	}
+}
+
+var (
+	_ up.Store[up.SQLConn]   = (*Sqlite3Store)(nil)
+	_ up.ChecksumStore       = (*Sqlite3Store)(nil)
+	_ up.BatchStore          = (*Sqlite3Store)(nil)
+	_ up.Dumper              = (*Sqlite3Store)(nil)
+	_ up.ForceUnlocker       = (*Sqlite3Store)(nil)
+	_ up.InitChecker         = (*Sqlite3Store)(nil)
+	_ up.LeaseStore          = (*Sqlite3Store)(nil)
+	_ up.TxStore[up.SQLConn] = (*Sqlite3Store)(nil)
+)
+
+func New(db *sql.DB, opts ...Option) *Sqlite3Store {
+	token := make([]byte, 16)
+	rand.Read(token)
+	s := &Sqlite3Store{
+		instance: db,
+		owner:    hex.EncodeToString(token),
+		lockID:   1,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *Sqlite3Store) DB() *sql.DB {
 	return s.instance
 }
 
+func (s *Sqlite3Store) Conn() up.SQLConn {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.instance
+}
+
+// WithTx calls fn with a copy of the store bound to a new transaction.
+func (s *Sqlite3Store) WithTx(ctx context.Context, fn func(context.Context, up.Store[up.SQLConn]) error) error {
+	return s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
+		txStore := *s
+		txStore.tx = tx
+		return fn(tCtx, &txStore)
+	})
+}
+
 func (s *Sqlite3Store) Init(ctx context.Context) error {
 	if err := s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
-		if _, err := tx.ExecContext(tCtx, "CREATE TABLE IF NOT EXISTS schema_lock (id INTEGER PRIMARY KEY)"); err != nil {
+		if _, err := tx.ExecContext(tCtx, "CREATE TABLE IF NOT EXISTS schema_lock (id INTEGER PRIMARY KEY, owner TEXT, expires_at INTEGER)"); err != nil {
+			return err
+		}
+
+		// Lock tables created before leases were supported lack the owner
+		// and expiry columns.
+		var leaseColumns int
+		if err := tx.QueryRowContext(tCtx, "SELECT COUNT(*) FROM pragma_table_info('schema_lock') WHERE name = 'expires_at'").Scan(&leaseColumns); err != nil {
 			return err
 		}
+		if leaseColumns == 0 {
+			if _, err := tx.ExecContext(tCtx, "ALTER TABLE schema_lock ADD COLUMN owner TEXT"); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(tCtx, "ALTER TABLE schema_lock ADD COLUMN expires_at INTEGER"); err != nil {
+				return err
+			}
+		}
 
 		if _, err := tx.ExecContext(tCtx, "CREATE TABLE IF NOT EXISTS schema_migrations (id INTEGER PRIMARY KEY, version_id INTEGER UNIQUE NOT NULL, applied_at DATETIME NOT NULL DEFAULT (datetime('now')))"); err != nil {
 			return err
 		}
+
+		if _, err := tx.ExecContext(tCtx, "CREATE TABLE IF NOT EXISTS schema_checksums (version_id INTEGER PRIMARY KEY, checksum TEXT NOT NULL)"); err != nil {
+			return err
+		}
 		return nil
 	}); err != nil {
 		return err
@@ -41,28 +120,81 @@ func (s *Sqlite3Store) Init(ctx context.Context) error {
 }
 
 func (s *Sqlite3Store) Lock(ctx context.Context) error {
-	_, err := s.instance.ExecContext(ctx, "INSERT INTO schema_lock (id) VALUES (1)")
+	_, err := s.Conn().ExecContext(ctx, "INSERT INTO schema_lock (id, owner) VALUES (?, ?)", s.lockID, s.owner)
 	if err == nil {
 		return nil
 	}
 
-	var sqliteErr sqlite3.Error
-	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+	if isConstraintErr(err) {
 		return up.ErrLocked
 	}
 	return err
 }
 
+// LockLease acquires the lock until ttl from now, taking over an expired lock.
+func (s *Sqlite3Store) LockLease(ctx context.Context, ttl time.Duration) error {
+	now := time.Now()
+	err := s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(tCtx, "DELETE FROM schema_lock WHERE id = ? AND expires_at IS NOT NULL AND expires_at <= ?", s.lockID, now.UnixMilli()); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(tCtx, "INSERT INTO schema_lock (id, owner, expires_at) VALUES (?, ?, ?)", s.lockID, s.owner, now.Add(ttl).UnixMilli())
+		return err
+	})
+	if err == nil {
+		return nil
+	}
+
+	if isConstraintErr(err) {
+		return up.ErrLocked
+	}
+	return err
+}
+
+// RenewLease extends the lease of a lock held by this store until ttl from
+// now.
+func (s *Sqlite3Store) RenewLease(ctx context.Context, ttl time.Duration) error {
+	res, err := s.Conn().ExecContext(ctx, "UPDATE schema_lock SET expires_at = ? WHERE id = ? AND owner = ?", time.Now().Add(ttl).UnixMilli(), s.lockID, s.owner)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return up.ErrLeaseLost
+	}
+	return nil
+}
+
+// Release releases a lock held by this store. Locks without an owner, which
+// were taken before leases were supported, are released too.
 func (s *Sqlite3Store) Release(ctx context.Context) error {
-	_, err := s.instance.ExecContext(ctx, "DELETE FROM schema_lock WHERE id = 1;")
+	_, err := s.Conn().ExecContext(ctx, "DELETE FROM schema_lock WHERE id = ? AND (owner IS NULL OR owner = ?)", s.lockID, s.owner)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// ForceUnlock releases the lock regardless of which store holds it.
+func (s *Sqlite3Store) ForceUnlock(ctx context.Context) error {
+	_, err := s.Conn().ExecContext(ctx, "DELETE FROM schema_lock WHERE id = ?", s.lockID)
+	return err
+}
+
+// Initialized reports whether Init has created the schema_migrations table.
+func (s *Sqlite3Store) Initialized(ctx context.Context) (bool, error) {
+	var n int
+	if err := s.Conn().QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
 func (s *Sqlite3Store) Version(ctx context.Context) (int64, error) {
-	row := s.instance.QueryRowContext(ctx, `SELECT version_id FROM schema_migrations ORDER BY version_id DESC LIMIT 1`)
+	row := s.Conn().QueryRowContext(ctx, `SELECT version_id FROM schema_migrations ORDER BY version_id DESC LIMIT 1`)
 	var version int64
 	err := row.Scan(&version)
 	if err != nil {
@@ -74,29 +206,127 @@ func (s *Sqlite3Store) Version(ctx context.Context) (int64, error) {
 	return version, err
 }
 
+func (s *Sqlite3Store) History(ctx context.Context) ([]up.Record, error) {
+	rows, err := s.Conn().QueryContext(ctx, "SELECT m.version_id, m.applied_at, COALESCE(c.checksum, '') FROM schema_migrations m LEFT JOIN schema_checksums c ON c.version_id = m.version_id ORDER BY m.version_id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []up.Record
+	for rows.Next() {
+		var r up.Record
+		if err := rows.Scan(&r.Version, &r.AppliedAt, &r.Checksum); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
 func (s *Sqlite3Store) Insert(ctx context.Context, v int64) error {
-	if _, err := s.instance.ExecContext(ctx, "INSERT INTO schema_migrations (version_id) VALUES (?)", v); err != nil {
+	if _, err := s.Conn().ExecContext(ctx, "INSERT INTO schema_migrations (version_id) VALUES (?)", v); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (s *Sqlite3Store) Remove(ctx context.Context, v int64) error {
-	res, err := s.instance.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version_id = ?", v)
-	if err != nil {
+	return s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
+		res, err := tx.ExecContext(tCtx, "DELETE FROM schema_migrations WHERE version_id = ?", v)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return up.ErrVersionNotFound
+		}
+
+		if _, err := tx.ExecContext(tCtx, "DELETE FROM schema_checksums WHERE version_id = ?", v); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// batchSize bounds the number of versions bound to a single statement.
+const batchSize = 500
+
+func (s *Sqlite3Store) InsertMany(ctx context.Context, versions []int64) error {
+	return s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
+		for batch := range slices.Chunk(versions, batchSize) {
+			query := "INSERT INTO schema_migrations (version_id) VALUES " + strings.Repeat("(?), ", len(batch)-1) + "(?)"
+			if _, err := tx.ExecContext(tCtx, query, args(batch)...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Sqlite3Store) RemoveMany(ctx context.Context, versions []int64) error {
+	return s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
+		for batch := range slices.Chunk(versions, batchSize) {
+			in := "(" + strings.Repeat("?, ", len(batch)-1) + "?)"
+			res, err := tx.ExecContext(tCtx, "DELETE FROM schema_migrations WHERE version_id IN "+in, args(batch)...)
+			if err != nil {
+				return err
+			}
+			rowsAffected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rowsAffected != int64(len(batch)) {
+				return up.ErrVersionNotFound
+			}
+
+			if _, err := tx.ExecContext(tCtx, "DELETE FROM schema_checksums WHERE version_id IN "+in, args(batch)...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Sqlite3Store) SetChecksum(ctx context.Context, v int64, checksum string) error {
+	if _, err := s.Conn().ExecContext(ctx, "INSERT OR REPLACE INTO schema_checksums (version_id, checksum) VALUES (?, ?)", v, checksum); err != nil {
 		return err
 	}
-	rowsAffected, err := res.RowsAffected()
+	return nil
+}
+
+// DumpSchema writes the statements that create the database's tables,
+// indexes, views and triggers, excluding the version store's own tables.
+func (s *Sqlite3Store) DumpSchema(ctx context.Context, w io.Writer) error {
+	rows, err := s.Conn().QueryContext(ctx, "SELECT sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' AND tbl_name NOT IN ('schema_lock', 'schema_migrations', 'schema_checksums') ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 WHEN 'view' THEN 2 ELSE 3 END, name")
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return up.ErrVersionNotFound
+	defer rows.Close()
+
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s;\n\n", stmt); err != nil {
+			return err
+		}
 	}
-	return nil
+	return rows.Err()
 }
 
 func (s *Sqlite3Store) withTx(ctx context.Context, fn func(context.Context, *sql.Tx) error) (err error) {
+	if s.tx != nil {
+		return fn(ctx, s.tx)
+	}
+
 	tx, err := s.instance.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -116,3 +346,16 @@ func (s *Sqlite3Store) withTx(ctx context.Context, fn func(context.Context, *sql
 
 	return fn(ctx, tx)
 }
+
+func args(versions []int64) []any {
+	a := make([]any, len(versions))
+	for i, v := range versions {
+		a[i] = v
+	}
+	return a
+}
+
+func isConstraintErr(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint
+}
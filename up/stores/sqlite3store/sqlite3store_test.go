@@ -1,10 +1,14 @@
 package sqlite3store_test
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jonathonwebb/x/up"
 	"github.com/jonathonwebb/x/up/stores/sqlite3store"
@@ -53,6 +57,21 @@ func TestSqlite3Store_Init(t *testing.T) {
 	})
 }
 
+func TestSqlite3Store_Initialized(t *testing.T) {
+	db := createTestDB(t)
+	store := sqlite3store.New(db)
+
+	if ok, err := store.Initialized(t.Context()); err != nil || ok {
+		t.Errorf("store.Initialized(ctx) = %v, %v, want false, no error", ok, err)
+	}
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+	if ok, err := store.Initialized(t.Context()); err != nil || !ok {
+		t.Errorf("store.Initialized(ctx) after Init = %v, %v, want true, no error", ok, err)
+	}
+}
+
 func TestSqlite3Store_Lock(t *testing.T) {
 	db := createTestDB(t)
 	store := sqlite3store.New(db)
@@ -73,6 +92,28 @@ func TestSqlite3Store_Lock(t *testing.T) {
 	}
 }
 
+func TestSqlite3Store_LockID(t *testing.T) {
+	db := createTestDB(t)
+	first := sqlite3store.New(db)
+	second := sqlite3store.New(db, sqlite3store.WithLockID(2))
+	if err := first.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	if err := first.Lock(t.Context()); err != nil {
+		t.Fatalf("first.Lock(ctx) = %v, want no error", err)
+	}
+	if err := second.Lock(t.Context()); err != nil {
+		t.Errorf("second.Lock(ctx) = %v, want no error", err)
+	}
+	if err := second.Release(t.Context()); err != nil {
+		t.Fatalf("second.Release(ctx) = %v, want no error", err)
+	}
+	if !lockExists(t, first) {
+		t.Error("expected first store's lock to survive second.Release()")
+	}
+}
+
 func TestSqlite3Store_Release(t *testing.T) {
 	db := createTestDB(t)
 	store := sqlite3store.New(db)
@@ -101,6 +142,115 @@ func TestSqlite3Store_Release(t *testing.T) {
 	}
 }
 
+func TestSqlite3Store_LockLease(t *testing.T) {
+	db := createTestDB(t)
+	first := sqlite3store.New(db)
+	second := sqlite3store.New(db)
+	if err := first.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	if err := first.LockLease(t.Context(), time.Hour); err != nil {
+		t.Fatalf("first.LockLease(ctx) = %v, want no error", err)
+	}
+	if err := second.LockLease(t.Context(), time.Hour); !errors.Is(err, up.ErrLocked) {
+		t.Errorf("second.LockLease(ctx) = %v, want ErrLocked", err)
+	}
+	if err := first.RenewLease(t.Context(), -time.Second); err != nil {
+		t.Fatalf("first.RenewLease(ctx) = %v, want no error", err)
+	}
+
+	// The first lease has expired, so the second store takes over.
+	if err := second.LockLease(t.Context(), time.Hour); err != nil {
+		t.Fatalf("second.LockLease(ctx) = %v, want no error", err)
+	}
+	if err := first.RenewLease(t.Context(), time.Hour); !errors.Is(err, up.ErrLeaseLost) {
+		t.Errorf("first.RenewLease(ctx) = %v, want ErrLeaseLost", err)
+	}
+	if err := first.Release(t.Context()); err != nil {
+		t.Fatalf("first.Release(ctx) = %v, want no error", err)
+	}
+	if !lockExists(t, second) {
+		t.Error("expected second store's lock to survive first.Release()")
+	}
+}
+
+func TestSqlite3Store_WithTx(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store := sqlite3store.New(db)
+
+	createTable := func(name string) func(context.Context, up.SQLConn) error {
+		return func(ctx context.Context, conn up.SQLConn) error {
+			_, err := conn.ExecContext(ctx, "CREATE TABLE "+name+" (id INTEGER)")
+			return err
+		}
+	}
+	migrator := &up.Migrator[up.SQLConn]{
+		Store: store,
+		Sources: []*up.Migration[up.SQLConn]{
+			{Version: 1, RunFunc: createTable("a")},
+			{Version: 2, RunFunc: createTable("b")},
+			{Version: 3, RunFunc: createTable("a")},
+		},
+		Atomic: true,
+	}
+
+	res, err := migrator.Run(t.Context(), up.RunTargetLatest)
+	if err == nil {
+		t.Fatal("migrator.Run(ctx) = nil, want error")
+	}
+	if res.Version != 0 || len(res.Migrations) != 0 {
+		t.Errorf("migrator.Run(ctx) result = %+v, want empty", res)
+	}
+	if got := currentVersions(t, store); len(got) != 0 {
+		t.Errorf("versions = %v, want none", got)
+	}
+
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('a', 'b')").Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Errorf("found %d migration tables, want 0", tables)
+	}
+	if lockExists(t, store) {
+		t.Error("expected lock to be released")
+	}
+}
+
+func TestSqlite3Store_DumpSchema(t *testing.T) {
+	db := createTestDB(t)
+	store := sqlite3store.New(db)
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE INDEX users_name ON users (name)",
+		"CREATE TABLE accounts (id INTEGER PRIMARY KEY)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b strings.Builder
+	if err := store.DumpSchema(t.Context(), &b); err != nil {
+		t.Fatalf("store.DumpSchema(ctx, w) = %v, want no error", err)
+	}
+
+	want := "CREATE TABLE accounts (id INTEGER PRIMARY KEY);\n\n" +
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);\n\n" +
+		"CREATE INDEX users_name ON users (name);\n\n"
+	if got := b.String(); got != want {
+		t.Errorf("store.DumpSchema(ctx, w) wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
+
 func TestSqlite3Store_Version(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -149,6 +299,63 @@ func TestSqlite3Store_Version(t *testing.T) {
 	}
 }
 
+func TestSqlite3Store_History(t *testing.T) {
+	db := createTestDB(t)
+	store := sqlite3store.New(db)
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	records, err := store.History(t.Context())
+	if err != nil {
+		t.Errorf("store.History(ctx) = %v, want no error", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("store.History(ctx) = %v, want no records", records)
+	}
+
+	for _, v := range []int64{3, 1, 2} {
+		if err := store.Insert(t.Context(), v); err != nil {
+			t.Fatalf("failed to insert version %d: %v", v, err)
+		}
+	}
+	if err := store.SetChecksum(t.Context(), 2, "abc"); err != nil {
+		t.Fatalf("failed to set checksum: %v", err)
+	}
+
+	records, err = store.History(t.Context())
+	if err != nil {
+		t.Fatalf("store.History(ctx) = %v, want no error", err)
+	}
+	var versions []int64
+	for _, r := range records {
+		versions = append(versions, r.Version)
+		if r.AppliedAt.IsZero() {
+			t.Errorf("record %d has zero AppliedAt", r.Version)
+		}
+	}
+	if want := []int64{1, 2, 3}; !slices.Equal(versions, want) {
+		t.Errorf("got versions %v, want %v", versions, want)
+	}
+	if got, want := records[1].Checksum, "abc"; got != want {
+		t.Errorf("record 2 checksum = %q, want %q", got, want)
+	}
+
+	if err := store.Remove(t.Context(), 2); err != nil {
+		t.Fatalf("failed to remove version: %v", err)
+	}
+	if err := store.Insert(t.Context(), 2); err != nil {
+		t.Fatalf("failed to insert version: %v", err)
+	}
+	records, err = store.History(t.Context())
+	if err != nil {
+		t.Fatalf("store.History(ctx) = %v, want no error", err)
+	}
+	if got := records[1].Checksum; got != "" {
+		t.Errorf("record 2 checksum = %q after remove, want empty", got)
+	}
+}
+
 func TestSqlite3Store_Insert(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -301,3 +508,55 @@ func currentVersions(t testing.TB, store *sqlite3store.Sqlite3Store) []int64 {
 	}
 	return versions
 }
+
+func TestSqlite3Store_Batch(t *testing.T) {
+	db := createTestDB(t)
+	store := sqlite3store.New(db)
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to initialize store: %v", err)
+	}
+
+	var versions []int64
+	for v := range int64(1200) {
+		versions = append(versions, v+1)
+	}
+	if err := store.InsertMany(t.Context(), versions); err != nil {
+		t.Fatalf("store.InsertMany(ctx, versions) = %v, want no error", err)
+	}
+	if got := currentVersions(t, store); !slices.Equal(got, versions) {
+		t.Errorf("got %d versions, want %d", len(got), len(versions))
+	}
+
+	if err := store.RemoveMany(t.Context(), []int64{1, 2, 9999}); !errors.Is(err, up.ErrVersionNotFound) {
+		t.Errorf("store.RemoveMany(ctx, missing) = %v, want ErrVersionNotFound", err)
+	}
+	if got := currentVersions(t, store); len(got) != len(versions) {
+		t.Errorf("got %d versions after failed RemoveMany, want %d", len(got), len(versions))
+	}
+
+	if err := store.RemoveMany(t.Context(), versions[:1100]); err != nil {
+		t.Fatalf("store.RemoveMany(ctx, versions) = %v, want no error", err)
+	}
+	if got := currentVersions(t, store); !slices.Equal(got, versions[1100:]) {
+		t.Errorf("got %d versions, want %d", len(got), len(versions[1100:]))
+	}
+}
+
+func TestSqlite3Store_ForceUnlock(t *testing.T) {
+	db := createTestDB(t)
+	holder := sqlite3store.New(db)
+	other := sqlite3store.New(db)
+	if err := holder.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+	if err := holder.Lock(t.Context()); err != nil {
+		t.Fatalf("holder.Lock(ctx) = %v, want no error", err)
+	}
+
+	if err := other.ForceUnlock(t.Context()); err != nil {
+		t.Fatalf("other.ForceUnlock(ctx) = %v, want no error", err)
+	}
+	if lockExists(t, holder) {
+		t.Error("expected lock to be removed after ForceUnlock()")
+	}
+}
@@ -0,0 +1,228 @@
+package sqlstore
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jonathonwebb/x/up"
+)
+
+// A Dialect describes how a [Store] talks to a particular database.
+//
+// The store writes its queries with ? placeholders and rewrites them with
+// Placeholder, so a dialect only supplies the statements that differ between
+// databases.
+type Dialect interface {
+	// Placeholder returns the bind parameter for the nth argument of a
+	// statement, counting from 1.
+	Placeholder(n int) string
+
+	// CreateTables returns the statements that create the schema_lock,
+	// schema_migrations and schema_checksums tables if they don't exist.
+	CreateTables() []string
+
+	// Lock acquires the lock identified by id on behalf of owner, returning
+	// [up.ErrLocked] if it is already held.
+	Lock(ctx context.Context, conn up.SQLConn, id int64, owner string) error
+
+	// Release releases the lock identified by id if it is held by owner.
+	Release(ctx context.Context, conn up.SQLConn, id int64, owner string) error
+}
+
+// A LeaseDialect is a Dialect whose locks can expire. A [Store] with a
+// LeaseDialect supports the lock leases of [up.LeaseStore].
+type LeaseDialect interface {
+	Dialect
+
+	// LockLease acquires the lock identified by id on behalf of owner until
+	// ttl from now, taking over an expired lock. It returns [up.ErrLocked]
+	// if the lock is held and has not expired.
+	LockLease(ctx context.Context, conn up.SQLConn, id int64, owner string, ttl time.Duration) error
+
+	// RenewLease extends the lease of a lock held by owner until ttl from
+	// now, returning [up.ErrLeaseLost] if owner no longer holds it.
+	RenewLease(ctx context.Context, conn up.SQLConn, id int64, owner string, ttl time.Duration) error
+}
+
+// An InitDialect is a Dialect that can tell whether the version store tables
+// exist without creating them. A [Store] with an InitDialect reports whether it
+// has been initialized for [up.InitChecker]. All built-in dialects are
+// InitDialects.
+type InitDialect interface {
+	Dialect
+
+	// InitializedQuery returns a query whose single integer result is
+	// positive if the schema_migrations table exists.
+	InitializedQuery() string
+}
+
+// Built-in dialects.
+var (
+	SQLite   Dialect      = sqliteDialect{}
+	Postgres Dialect      = postgresDialect{}
+	MySQL    Dialect      = mysqlDialect{}
+	LibSQL   LeaseDialect = libsqlDialect{}
+)
+
+const sqliteInitializedQuery = "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'"
+
+type sqliteDialect struct {
+	rowLock
+}
+
+func (sqliteDialect) Placeholder(int) string { return "?" }
+
+func (sqliteDialect) CreateTables() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS schema_lock (id INTEGER PRIMARY KEY, owner TEXT)",
+		"CREATE TABLE IF NOT EXISTS schema_migrations (version_id INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+		"CREATE TABLE IF NOT EXISTS schema_checksums (version_id INTEGER PRIMARY KEY, checksum TEXT NOT NULL)",
+	}
+}
+
+func (sqliteDialect) InitializedQuery() string { return sqliteInitializedQuery }
+
+func (d sqliteDialect) Lock(ctx context.Context, conn up.SQLConn, id int64, owner string) error {
+	return d.lock(ctx, conn, "INSERT INTO schema_lock (id, owner) VALUES (?, ?) ON CONFLICT DO NOTHING", id, owner)
+}
+
+func (d sqliteDialect) Release(ctx context.Context, conn up.SQLConn, id int64, owner string) error {
+	return d.release(ctx, conn, "DELETE FROM schema_lock WHERE id = ? AND owner = ?", id, owner)
+}
+
+type postgresDialect struct {
+	rowLock
+}
+
+func (postgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (postgresDialect) CreateTables() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS schema_lock (id BIGINT PRIMARY KEY, owner TEXT)",
+		"CREATE TABLE IF NOT EXISTS schema_migrations (version_id BIGINT PRIMARY KEY, applied_at TIMESTAMPTZ NOT NULL DEFAULT now())",
+		"CREATE TABLE IF NOT EXISTS schema_checksums (version_id BIGINT PRIMARY KEY, checksum TEXT NOT NULL)",
+	}
+}
+
+func (postgresDialect) InitializedQuery() string {
+	return "SELECT count(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'"
+}
+
+func (d postgresDialect) Lock(ctx context.Context, conn up.SQLConn, id int64, owner string) error {
+	return d.lock(ctx, conn, "INSERT INTO schema_lock (id, owner) VALUES ($1, $2) ON CONFLICT DO NOTHING", id, owner)
+}
+
+func (d postgresDialect) Release(ctx context.Context, conn up.SQLConn, id int64, owner string) error {
+	return d.release(ctx, conn, "DELETE FROM schema_lock WHERE id = $1 AND owner = $2", id, owner)
+}
+
+type mysqlDialect struct {
+	rowLock
+}
+
+func (mysqlDialect) Placeholder(int) string { return "?" }
+
+func (mysqlDialect) CreateTables() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS schema_lock (id BIGINT PRIMARY KEY, owner VARCHAR(64))",
+		"CREATE TABLE IF NOT EXISTS schema_migrations (version_id BIGINT PRIMARY KEY, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+		"CREATE TABLE IF NOT EXISTS schema_checksums (version_id BIGINT PRIMARY KEY, checksum VARCHAR(255) NOT NULL)",
+	}
+}
+
+func (mysqlDialect) InitializedQuery() string {
+	return "SELECT count(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'schema_migrations'"
+}
+
+func (d mysqlDialect) Lock(ctx context.Context, conn up.SQLConn, id int64, owner string) error {
+	return d.lock(ctx, conn, "INSERT IGNORE INTO schema_lock (id, owner) VALUES (?, ?)", id, owner)
+}
+
+func (d mysqlDialect) Release(ctx context.Context, conn up.SQLConn, id int64, owner string) error {
+	return d.release(ctx, conn, "DELETE FROM schema_lock WHERE id = ? AND owner = ?", id, owner)
+}
+
+// libsqlDialect supports libSQL and Turso databases, including embedded
+// replicas. Writes to a replica are forwarded to the primary, so each lock
+// operation is a single statement that the primary applies atomically, without
+// an interactive transaction. Lease expiry times are taken from the local
+// clock.
+type libsqlDialect struct {
+	rowLock
+}
+
+func (libsqlDialect) Placeholder(int) string { return "?" }
+
+func (libsqlDialect) CreateTables() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS schema_lock (id INTEGER PRIMARY KEY, owner TEXT, expires_at INTEGER)",
+		"CREATE TABLE IF NOT EXISTS schema_migrations (version_id INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+		"CREATE TABLE IF NOT EXISTS schema_checksums (version_id INTEGER PRIMARY KEY, checksum TEXT NOT NULL)",
+	}
+}
+
+func (libsqlDialect) InitializedQuery() string { return sqliteInitializedQuery }
+
+func (d libsqlDialect) Lock(ctx context.Context, conn up.SQLConn, id int64, owner string) error {
+	return d.lock(ctx, conn, "INSERT INTO schema_lock (id, owner) VALUES (?, ?) ON CONFLICT DO NOTHING", id, owner)
+}
+
+func (d libsqlDialect) Release(ctx context.Context, conn up.SQLConn, id int64, owner string) error {
+	return d.release(ctx, conn, "DELETE FROM schema_lock WHERE id = ? AND owner = ?", id, owner)
+}
+
+func (libsqlDialect) LockLease(ctx context.Context, conn up.SQLConn, id int64, owner string, ttl time.Duration) error {
+	now := time.Now()
+	res, err := conn.ExecContext(ctx, "INSERT INTO schema_lock (id, owner, expires_at) VALUES (?, ?, ?) ON CONFLICT (id) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at WHERE schema_lock.expires_at IS NOT NULL AND schema_lock.expires_at <= ?", id, owner, now.Add(ttl).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return up.ErrLocked
+	}
+	return nil
+}
+
+func (libsqlDialect) RenewLease(ctx context.Context, conn up.SQLConn, id int64, owner string, ttl time.Duration) error {
+	res, err := conn.ExecContext(ctx, "UPDATE schema_lock SET expires_at = ? WHERE id = ? AND owner = ?", time.Now().Add(ttl).UnixMilli(), id, owner)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return up.ErrLeaseLost
+	}
+	return nil
+}
+
+// rowLock implements locking with a row in the schema_lock table. The lock
+// statement must insert the row, or affect no rows if it already exists.
+type rowLock struct{}
+
+func (rowLock) lock(ctx context.Context, conn up.SQLConn, query string, id int64, owner string) error {
+	res, err := conn.ExecContext(ctx, query, id, owner)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return up.ErrLocked
+	}
+	return nil
+}
+
+func (rowLock) release(ctx context.Context, conn up.SQLConn, query string, id int64, owner string) error {
+	_, err := conn.ExecContext(ctx, query, id, owner)
+	return err
+}
@@ -0,0 +1,263 @@
+// Package sqlstore provides a database/sql implementation of the up.Store
+// interface that supports several databases through a [Dialect].
+//
+// The built-in [SQLite], [Postgres], [MySQL] and [LibSQL] dialects keep the
+// version store in the schema_lock, schema_migrations and schema_checksums
+// tables. Only [LibSQL] supports lock leases; with the other dialects, the
+// store's lease methods return an error. The database's driver must scan
+// timestamp columns into [time.Time]; with MySQL, open the database with
+// parseTime=true.
+package sqlstore
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jonathonwebb/x/up"
+)
+
+type Store struct {
+	instance *sql.DB
+	tx       *sql.Tx // set on stores bound to a transaction by WithTx
+	dialect  Dialect
+	owner    string
+	lockID   int64
+}
+
+// An Option configures a Store.
+type Option func(*Store)
+
+// WithLockID sets the id of the lock used as the version store lock.
+// Applications that share a database but migrate independently can use
+// different ids so their runs don't block each other. The default is 1.
+func WithLockID(id int64) Option {
+	return func(s *Store) {
+		s.lockID = id
+	}
+}
+
+var (
+	_ up.Store[up.SQLConn]   = (*Store)(nil)
+	_ up.ChecksumStore       = (*Store)(nil)
+	_ up.ForceUnlocker       = (*Store)(nil)
+	_ up.InitChecker         = (*Store)(nil)
+	_ up.LeaseStore          = (*Store)(nil)
+	_ up.TxStore[up.SQLConn] = (*Store)(nil)
+)
+
+func New(db *sql.DB, dialect Dialect, opts ...Option) *Store {
+	token := make([]byte, 16)
+	rand.Read(token)
+	s := &Store{
+		instance: db,
+		dialect:  dialect,
+		owner:    hex.EncodeToString(token),
+		lockID:   1,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Store) DB() *sql.DB {
+	return s.instance
+}
+
+func (s *Store) Conn() up.SQLConn {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.instance
+}
+
+// WithTx calls fn with a copy of the store bound to a new transaction.
+func (s *Store) WithTx(ctx context.Context, fn func(context.Context, up.Store[up.SQLConn]) error) error {
+	return s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
+		txStore := *s
+		txStore.tx = tx
+		return fn(tCtx, &txStore)
+	})
+}
+
+func (s *Store) Init(ctx context.Context) error {
+	return s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
+		for _, stmt := range s.dialect.CreateTables() {
+			if _, err := tx.ExecContext(tCtx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Initialized reports whether Init has created the version store tables. If
+// the store's dialect is not an [InitDialect], it reports true, as the migrator
+// assumes for stores that cannot tell.
+func (s *Store) Initialized(ctx context.Context) (bool, error) {
+	d, ok := s.dialect.(InitDialect)
+	if !ok {
+		return true, nil
+	}
+	var n int64
+	if err := s.Conn().QueryRowContext(ctx, d.InitializedQuery()).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *Store) Lock(ctx context.Context) error {
+	return s.dialect.Lock(ctx, s.Conn(), s.lockID, s.owner)
+}
+
+// errNoLease is returned by the lease methods of a store whose dialect is not
+// a [LeaseDialect].
+var errNoLease = fmt.Errorf("sqlstore: dialect does not support lock leases: %w", errors.ErrUnsupported)
+
+// LockLease acquires the lock until ttl from now, taking over an expired lock.
+// If the store's dialect is not a [LeaseDialect], it returns an error wrapping
+// [errors.ErrUnsupported] rather than taking a lock that never expires.
+func (s *Store) LockLease(ctx context.Context, ttl time.Duration) error {
+	d, ok := s.dialect.(LeaseDialect)
+	if !ok {
+		return errNoLease
+	}
+	return d.LockLease(ctx, s.Conn(), s.lockID, s.owner, ttl)
+}
+
+// RenewLease extends the lease of a lock held by this store until ttl from
+// now. If the store's dialect is not a [LeaseDialect], it returns an error
+// wrapping [errors.ErrUnsupported].
+func (s *Store) RenewLease(ctx context.Context, ttl time.Duration) error {
+	d, ok := s.dialect.(LeaseDialect)
+	if !ok {
+		return errNoLease
+	}
+	return d.RenewLease(ctx, s.Conn(), s.lockID, s.owner, ttl)
+}
+
+func (s *Store) Release(ctx context.Context) error {
+	return s.dialect.Release(ctx, s.Conn(), s.lockID, s.owner)
+}
+
+// ForceUnlock releases the lock regardless of which store holds it.
+func (s *Store) ForceUnlock(ctx context.Context) error {
+	_, err := s.Conn().ExecContext(ctx, s.rebind("DELETE FROM schema_lock WHERE id = ?"), s.lockID)
+	return err
+}
+
+func (s *Store) Version(ctx context.Context) (int64, error) {
+	row := s.Conn().QueryRowContext(ctx, "SELECT version_id FROM schema_migrations ORDER BY version_id DESC LIMIT 1")
+	var version int64
+	if err := row.Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *Store) History(ctx context.Context) ([]up.Record, error) {
+	rows, err := s.Conn().QueryContext(ctx, "SELECT m.version_id, m.applied_at, COALESCE(c.checksum, '') FROM schema_migrations m LEFT JOIN schema_checksums c ON c.version_id = m.version_id ORDER BY m.version_id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []up.Record
+	for rows.Next() {
+		var r up.Record
+		if err := rows.Scan(&r.Version, &r.AppliedAt, &r.Checksum); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (s *Store) Insert(ctx context.Context, v int64) error {
+	_, err := s.Conn().ExecContext(ctx, s.rebind("INSERT INTO schema_migrations (version_id) VALUES (?)"), v)
+	return err
+}
+
+func (s *Store) Remove(ctx context.Context, v int64) error {
+	return s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
+		res, err := tx.ExecContext(tCtx, s.rebind("DELETE FROM schema_migrations WHERE version_id = ?"), v)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return up.ErrVersionNotFound
+		}
+
+		if _, err := tx.ExecContext(tCtx, s.rebind("DELETE FROM schema_checksums WHERE version_id = ?"), v); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// SetChecksum records the checksum of version v, replacing any previous one.
+func (s *Store) SetChecksum(ctx context.Context, v int64, checksum string) error {
+	return s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(tCtx, s.rebind("DELETE FROM schema_checksums WHERE version_id = ?"), v); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(tCtx, s.rebind("INSERT INTO schema_checksums (version_id, checksum) VALUES (?, ?)"), v, checksum)
+		return err
+	})
+}
+
+// rebind replaces the ? placeholders in query with the dialect's.
+func (s *Store) rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString(s.dialect.Placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *Store) withTx(ctx context.Context, fn func(context.Context, *sql.Tx) error) (err error) {
+	if s.tx != nil {
+		return fn(ctx, s.tx)
+	}
+
+	tx, err := s.instance.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				err = errors.Join(err, rollbackErr)
+			}
+		} else {
+			if commitErr := tx.Commit(); commitErr != nil {
+				err = errors.Join(err, commitErr)
+			}
+		}
+	}()
+
+	return fn(ctx, tx)
+}
@@ -0,0 +1,256 @@
+package sqlstore_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jonathonwebb/x/up"
+	"github.com/jonathonwebb/x/up/stores/sqlstore"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestStore_Lock(t *testing.T) {
+	db := createTestDB(t)
+	first := sqlstore.New(db, sqlstore.SQLite)
+	second := sqlstore.New(db, sqlstore.SQLite)
+	other := sqlstore.New(db, sqlstore.SQLite, sqlstore.WithLockID(2))
+	if err := first.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	if err := first.Lock(t.Context()); err != nil {
+		t.Fatalf("first.Lock(ctx) = %v, want no error", err)
+	}
+	if err := second.Lock(t.Context()); !errors.Is(err, up.ErrLocked) {
+		t.Errorf("second.Lock(ctx) = %v, want ErrLocked", err)
+	}
+	if err := other.Lock(t.Context()); err != nil {
+		t.Errorf("other.Lock(ctx) = %v, want no error", err)
+	}
+
+	if err := second.Release(t.Context()); err != nil {
+		t.Fatalf("second.Release(ctx) = %v, want no error", err)
+	}
+	if err := second.Lock(t.Context()); !errors.Is(err, up.ErrLocked) {
+		t.Errorf("second.Lock(ctx) after foreign release = %v, want ErrLocked", err)
+	}
+
+	if err := first.Release(t.Context()); err != nil {
+		t.Fatalf("first.Release(ctx) = %v, want no error", err)
+	}
+	if err := second.Lock(t.Context()); err != nil {
+		t.Errorf("second.Lock(ctx) after release = %v, want no error", err)
+	}
+}
+
+func TestStore_Initialized(t *testing.T) {
+	for name, dialect := range map[string]sqlstore.Dialect{"sqlite": sqlstore.SQLite, "libsql": sqlstore.LibSQL} {
+		t.Run(name, func(t *testing.T) {
+			db := createTestDB(t)
+			store := sqlstore.New(db, dialect)
+
+			if ok, err := store.Initialized(t.Context()); err != nil || ok {
+				t.Errorf("store.Initialized(ctx) = %v, %v, want false, nil", ok, err)
+			}
+			if err := store.Init(t.Context()); err != nil {
+				t.Fatalf("failed to init: %v", err)
+			}
+			if ok, err := store.Initialized(t.Context()); err != nil || !ok {
+				t.Errorf("store.Initialized(ctx) after Init = %v, %v, want true, nil", ok, err)
+			}
+		})
+	}
+}
+
+func TestStore_Versions(t *testing.T) {
+	db := createTestDB(t)
+	store := sqlstore.New(db, sqlstore.SQLite)
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("store.Init(ctx) twice = %v, want no error", err)
+	}
+
+	if v, err := store.Version(t.Context()); err != nil || v != 0 {
+		t.Errorf("store.Version(ctx) = %d, %v, want 0, nil", v, err)
+	}
+
+	for _, v := range []int64{3, 1, 2} {
+		if err := store.Insert(t.Context(), v); err != nil {
+			t.Fatalf("store.Insert(ctx, %d) = %v, want no error", v, err)
+		}
+	}
+	if err := store.Insert(t.Context(), 2); err == nil {
+		t.Error("store.Insert(ctx, 2) twice = nil, want error")
+	}
+	if err := store.SetChecksum(t.Context(), 2, "old"); err != nil {
+		t.Fatalf("store.SetChecksum(ctx, 2) = %v, want no error", err)
+	}
+	if err := store.SetChecksum(t.Context(), 2, "new"); err != nil {
+		t.Fatalf("store.SetChecksum(ctx, 2) = %v, want no error", err)
+	}
+
+	if v, err := store.Version(t.Context()); err != nil || v != 3 {
+		t.Errorf("store.Version(ctx) = %d, %v, want 3, nil", v, err)
+	}
+
+	history, err := store.History(t.Context())
+	if err != nil {
+		t.Fatalf("store.History(ctx) = %v, want no error", err)
+	}
+	var versions []int64
+	for _, record := range history {
+		versions = append(versions, record.Version)
+		if record.AppliedAt.IsZero() {
+			t.Errorf("version %d has zero AppliedAt", record.Version)
+		}
+	}
+	if want := []int64{1, 2, 3}; !slices.Equal(want, versions) {
+		t.Errorf("history versions mismatch\nwant: %v\ngot:  %v", want, versions)
+	}
+	if history[1].Checksum != "new" {
+		t.Errorf("checksum: want %q, got %q", "new", history[1].Checksum)
+	}
+
+	if err := store.Remove(t.Context(), 2); err != nil {
+		t.Fatalf("store.Remove(ctx, 2) = %v, want no error", err)
+	}
+	if err := store.Remove(t.Context(), 2); !errors.Is(err, up.ErrVersionNotFound) {
+		t.Errorf("store.Remove(ctx, 2) twice = %v, want ErrVersionNotFound", err)
+	}
+}
+
+func TestStore_Migrator(t *testing.T) {
+	db := createTestDB(t)
+	store := sqlstore.New(db, sqlstore.SQLite)
+
+	var migrations []*up.Migration[up.SQLConn]
+	for _, v := range []int64{1, 2} {
+		migrations = append(migrations, &up.Migration[up.SQLConn]{
+			Version: v,
+			RunFunc: func(ctx context.Context, conn up.SQLConn) error {
+				_, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE TABLE t%d (id INTEGER)", v))
+				return err
+			},
+			RevertFunc: func(ctx context.Context, conn up.SQLConn) error {
+				_, err := conn.ExecContext(ctx, fmt.Sprintf("DROP TABLE t%d", v))
+				return err
+			},
+		})
+	}
+	migrator := &up.Migrator[up.SQLConn]{
+		Store:   store,
+		Sources: migrations,
+		Atomic:  true,
+	}
+
+	if _, err := migrator.Run(t.Context(), up.RunTargetLatest); err != nil {
+		t.Fatalf("migrator.Run(ctx) = %v, want no error", err)
+	}
+	if v, _ := store.Version(t.Context()); v != 2 {
+		t.Errorf("version after run: want 2, got %d", v)
+	}
+
+	if _, err := migrator.Revert(t.Context(), up.RevertTargetInitial); err != nil {
+		t.Fatalf("migrator.Revert(ctx) = %v, want no error", err)
+	}
+	if v, _ := store.Version(t.Context()); v != 0 {
+		t.Errorf("version after revert: want 0, got %d", v)
+	}
+}
+
+func TestDialect_Placeholder(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect sqlstore.Dialect
+		want    string
+	}{
+		{"sqlite", sqlstore.SQLite, "?"},
+		{"postgres", sqlstore.Postgres, "$3"},
+		{"mysql", sqlstore.MySQL, "?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.Placeholder(3); got != tt.want {
+				t.Errorf("Placeholder(3) = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func createTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file:"+t.Name()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestStore_LockLease(t *testing.T) {
+	// libSQL is SQLite-compatible, so the LibSQL dialect is exercised with the
+	// sqlite3 driver.
+	db := createTestDB(t)
+	first := sqlstore.New(db, sqlstore.LibSQL)
+	second := sqlstore.New(db, sqlstore.LibSQL)
+	if err := first.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	if err := first.LockLease(t.Context(), time.Hour); err != nil {
+		t.Fatalf("first.LockLease(ctx) = %v, want no error", err)
+	}
+	if err := second.LockLease(t.Context(), time.Hour); !errors.Is(err, up.ErrLocked) {
+		t.Errorf("second.LockLease(ctx) = %v, want ErrLocked", err)
+	}
+	if err := first.RenewLease(t.Context(), -time.Second); err != nil {
+		t.Fatalf("first.RenewLease(ctx) = %v, want no error", err)
+	}
+
+	if err := second.LockLease(t.Context(), time.Hour); err != nil {
+		t.Fatalf("second.LockLease(ctx) after expiry = %v, want no error", err)
+	}
+	if err := first.RenewLease(t.Context(), time.Hour); !errors.Is(err, up.ErrLeaseLost) {
+		t.Errorf("first.RenewLease(ctx) after takeover = %v, want ErrLeaseLost", err)
+	}
+
+	if err := first.Release(t.Context()); err != nil {
+		t.Fatalf("first.Release(ctx) = %v, want no error", err)
+	}
+	if err := first.Lock(t.Context()); !errors.Is(err, up.ErrLocked) {
+		t.Errorf("first.Lock(ctx) = %v, want ErrLocked", err)
+	}
+	if err := second.Release(t.Context()); err != nil {
+		t.Fatalf("second.Release(ctx) = %v, want no error", err)
+	}
+	if err := first.Lock(t.Context()); err != nil {
+		t.Errorf("first.Lock(ctx) after release = %v, want no error", err)
+	}
+}
+
+func TestStore_LockLease_Unsupported(t *testing.T) {
+	db := createTestDB(t)
+	store := sqlstore.New(db, sqlstore.SQLite)
+	if err := store.Init(t.Context()); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+
+	if err := store.LockLease(t.Context(), time.Hour); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("LockLease(ctx) = %v, want ErrUnsupported", err)
+	}
+	if err := store.RenewLease(t.Context(), time.Hour); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("RenewLease(ctx) = %v, want ErrUnsupported", err)
+	}
+	if err := store.Lock(t.Context()); err != nil {
+		t.Errorf("Lock(ctx) after unsupported LockLease = %v, want no error", err)
+	}
+}
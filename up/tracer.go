@@ -0,0 +1,24 @@
+package up
+
+import "context"
+
+// An Operation identifies the direction of a migrator operation.
+type Operation string
+
+const (
+	OperationRun    Operation = "run"
+	OperationRevert Operation = "revert"
+)
+
+// A Tracer observes migrator operations, such as to record spans and metrics.
+// Each start method returns the context to use for the rest of the operation
+// and a function that is called with the outcome when the operation ends.
+type Tracer interface {
+	// StartOperation is called when [Migrator.Run] or [Migrator.Revert]
+	// begins. The end function receives the operation's result.
+	StartOperation(ctx context.Context, op Operation, to int64) (context.Context, func(*Result, error))
+
+	// StartMigration is called before a single migration is applied or
+	// reverted.
+	StartMigration(ctx context.Context, op Operation, version int64, name string) (context.Context, func(error))
+}
@@ -0,0 +1,239 @@
+// Package upcmd provides a ready-made migration command line for up, built on
+// the cli package.
+//
+// An application mounts it by building its migrator and executing [Root]:
+//
+//	func main() {
+//		db, _ := sql.Open("sqlite3", "app.db")
+//		m := &up.Migrator[up.SQLConn]{
+//			Store:   sqlite3store.New(db),
+//			Sources: migrations,
+//		}
+//		cli.Run(upcmd.Root("migrate", m, "migrations"), nil, &upcmd.Options{})
+//	}
+package upcmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jonathonwebb/x/cli"
+	"github.com/jonathonwebb/x/up"
+)
+
+// Options holds the flags parsed by the commands returned by [Root].
+type Options struct {
+	Verbose bool  // log debug messages
+	To      int64 // target version of up and down
+	All     bool  // revert every migration with down
+}
+
+// Root returns a command named name with up, down, status, create and
+// force-unlock subcommands that operate on m. New migration files are created
+// in dir.
+func Root[T any](name string, m *up.Migrator[T], dir string) *cli.Command[*Options, any] {
+	return &cli.Command[*Options, any]{
+		Name:  name,
+		Usage: fmt.Sprintf("usage: %s [-v] <command> [args]", name),
+		Help: `Commands:
+  up            apply pending migrations
+  down          revert the latest migration
+  status        list migrations and whether they are applied
+  create NAME   create a new migration file
+  force-unlock  release a version store lock left by a failed process
+
+Flags:
+  -v            log debug messages`,
+		Flags: func(flags *flag.FlagSet, o *Options) {
+			flags.BoolVar(&o.Verbose, "v", false, "")
+		},
+		Subcommands: []*cli.Command[*Options, any]{
+			upCmd(name, m),
+			downCmd(name, m),
+			statusCmd(name, m),
+			createCmd(name, dir),
+			forceUnlockCmd(name, m),
+		},
+	}
+}
+
+// setup directs the migrator's log messages to env.
+func setup[T any](env *cli.Env[any], m *up.Migrator[T], o *Options) {
+	if m.LogFunc == nil {
+		m.LogFunc = func(s string) { env.Errorf("%s\n", s) }
+	}
+	if o.Verbose && m.DebugFunc == nil {
+		m.DebugFunc = func(s string) { env.Errorf("%s\n", s) }
+	}
+}
+
+func upCmd[T any](name string, m *up.Migrator[T]) *cli.Command[*Options, any] {
+	return &cli.Command[*Options, any]{
+		Name:  "up",
+		Usage: fmt.Sprintf("usage: %s up [-to version]", name),
+		Help: `Apply pending migrations up to and including the target version.
+
+Flags:
+  -to version   target version (default: latest)`,
+		Flags: func(flags *flag.FlagSet, o *Options) {
+			flags.Int64Var(&o.To, "to", up.RunTargetLatest, "")
+		},
+		Action: func(ctx context.Context, env *cli.Env[any], o *Options) cli.ExitStatus {
+			setup(env, m, o)
+			res, err := m.Run(ctx, o.To)
+			printResult(env, "applied", res)
+			if err != nil {
+				env.Errorf("%s: %v\n", name, err)
+				return cli.ExitFailure
+			}
+			return cli.ExitSuccess
+		},
+	}
+}
+
+func downCmd[T any](name string, m *up.Migrator[T]) *cli.Command[*Options, any] {
+	return &cli.Command[*Options, any]{
+		Name:  "down",
+		Usage: fmt.Sprintf("usage: %s down [-to version | -all]", name),
+		Help: `Revert migrations down to and excluding the target version. Without flags,
+only the latest applied migration is reverted.
+
+Flags:
+  -to version   target version
+  -all          revert every migration`,
+		Flags: func(flags *flag.FlagSet, o *Options) {
+			flags.Int64Var(&o.To, "to", -1, "")
+			flags.BoolVar(&o.All, "all", false, "")
+		},
+		Action: func(ctx context.Context, env *cli.Env[any], o *Options) cli.ExitStatus {
+			setup(env, m, o)
+			to := o.To
+			switch {
+			case o.All:
+				to = up.RevertTargetInitial
+			case to < 0:
+				statuses, err := m.Status(ctx)
+				if err != nil {
+					env.Errorf("%s: %v\n", name, err)
+					return cli.ExitFailure
+				}
+				to = previousVersion(statuses)
+			}
+
+			res, err := m.Revert(ctx, to)
+			printResult(env, "reverted", res)
+			if err != nil {
+				env.Errorf("%s: %v\n", name, err)
+				return cli.ExitFailure
+			}
+			return cli.ExitSuccess
+		},
+	}
+}
+
+// previousVersion returns the applied version before the latest one, or
+// [up.RevertTargetInitial] if at most one is applied.
+func previousVersion(statuses []up.MigrationStatus) int64 {
+	var applied []int64
+	for _, status := range statuses {
+		if status.Applied {
+			applied = append(applied, status.Version)
+		}
+	}
+	if len(applied) < 2 {
+		return up.RevertTargetInitial
+	}
+	return applied[len(applied)-2]
+}
+
+func statusCmd[T any](name string, m *up.Migrator[T]) *cli.Command[*Options, any] {
+	return &cli.Command[*Options, any]{
+		Name:  "status",
+		Usage: fmt.Sprintf("usage: %s status", name),
+		Help:  "List every migration and whether it is applied.",
+		Action: func(ctx context.Context, env *cli.Env[any], o *Options) cli.ExitStatus {
+			setup(env, m, o)
+			statuses, err := m.Status(ctx)
+			if err != nil {
+				env.Errorf("%s: %v\n", name, err)
+				return cli.ExitFailure
+			}
+
+			tw := tabwriter.NewWriter(env.Out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+			for _, status := range statuses {
+				applied := "pending"
+				if status.Applied {
+					applied = status.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+			}
+			if err := tw.Flush(); err != nil {
+				env.Errorf("%s: %v\n", name, err)
+				return cli.ExitFailure
+			}
+			return cli.ExitSuccess
+		},
+	}
+}
+
+func createCmd(name, dir string) *cli.Command[*Options, any] {
+	return &cli.Command[*Options, any]{
+		Name:  "create",
+		Usage: fmt.Sprintf("usage: %s create NAME", name),
+		Help: `Create a new migration file versioned with the current time. NAME's
+extension selects a SQL (.sql, the default) or Go (.go) file.`,
+		Action: func(ctx context.Context, env *cli.Env[any], o *Options) cli.ExitStatus {
+			if len(env.Args) != 1 {
+				env.Errorf("usage: %s create NAME\n", name)
+				return cli.ExitUsage
+			}
+			path, err := up.NewMigrationFile(dir, env.Args[0])
+			if err != nil {
+				env.Errorf("%s: %v\n", name, err)
+				return cli.ExitFailure
+			}
+			env.Printf("created %s\n", path)
+			return cli.ExitSuccess
+		},
+	}
+}
+
+func forceUnlockCmd[T any](name string, m *up.Migrator[T]) *cli.Command[*Options, any] {
+	return &cli.Command[*Options, any]{
+		Name:  "force-unlock",
+		Usage: fmt.Sprintf("usage: %s force-unlock", name),
+		Help: `Release the version store lock, even if another process holds it. Only use
+this after making sure no migration is running. The store must support forced
+unlocking.`,
+		Action: func(ctx context.Context, env *cli.Env[any], o *Options) cli.ExitStatus {
+			fu, ok := m.Store.(up.ForceUnlocker)
+			if !ok {
+				env.Errorf("%s: store does not support force unlock\n", name)
+				return cli.ExitFailure
+			}
+			if err := fu.ForceUnlock(ctx); err != nil {
+				env.Errorf("%s: failed to release version store lock: %v\n", name, err)
+				return cli.ExitFailure
+			}
+			env.Printf("released version store lock\n")
+			return cli.ExitSuccess
+		},
+	}
+}
+
+func printResult(env *cli.Env[any], verb string, res *up.Result) {
+	if res == nil {
+		return
+	}
+	for _, migration := range res.Migrations {
+		if migration.Skipped {
+			env.Printf("skipped %d %s\n", migration.Version, migration.Name)
+			continue
+		}
+		env.Printf("%s %d %s (%s)\n", verb, migration.Version, migration.Name, migration.Duration.Round(time.Millisecond))
+	}
+}
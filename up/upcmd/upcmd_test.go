@@ -0,0 +1,174 @@
+package upcmd_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jonathonwebb/x/cli"
+	"github.com/jonathonwebb/x/up"
+	"github.com/jonathonwebb/x/up/upcmd"
+	"github.com/jonathonwebb/x/up/uptest"
+)
+
+func migrations(versions ...int64) []*up.Migration[up.SQLConn] {
+	var ms []*up.Migration[up.SQLConn]
+	for _, v := range versions {
+		ms = append(ms, &up.Migration[up.SQLConn]{
+			Version:    v,
+			Name:       "m",
+			RunFunc:    func(context.Context, up.SQLConn) error { return nil },
+			RevertFunc: func(context.Context, up.SQLConn) error { return nil },
+		})
+	}
+	return ms
+}
+
+func execute(t *testing.T, m *up.Migrator[up.SQLConn], dir string, args ...string) (cli.ExitStatus, string, string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	env := &cli.Env[any]{
+		Out:  &out,
+		Err:  &errOut,
+		Args: append([]string{"migrate"}, args...),
+	}
+	status := upcmd.Root("migrate", m, dir).Execute(context.Background(), env, &upcmd.Options{})
+	return status, out.String(), errOut.String()
+}
+
+func TestRoot(t *testing.T) {
+	tests := []struct {
+		name         string
+		versions     []int64
+		args         []string
+		wantStatus   cli.ExitStatus
+		wantVersions []int64
+		wantOut      []string
+	}{
+		{
+			name:         "up",
+			args:         []string{"up"},
+			wantVersions: []int64{1, 2, 3},
+			wantOut:      []string{"applied 1 m", "applied 3 m"},
+		},
+		{
+			name:         "up_to",
+			args:         []string{"up", "-to", "2"},
+			wantVersions: []int64{1, 2},
+		},
+		{
+			name:         "down_one",
+			versions:     []int64{1, 2, 3},
+			args:         []string{"down"},
+			wantVersions: []int64{1, 2},
+			wantOut:      []string{"reverted 3 m"},
+		},
+		{
+			name:         "down_to",
+			versions:     []int64{1, 2, 3},
+			args:         []string{"down", "-to", "1"},
+			wantVersions: []int64{1},
+		},
+		{
+			name:     "down_all",
+			versions: []int64{1, 2, 3},
+			args:     []string{"down", "-all"},
+		},
+		{
+			name:         "status",
+			versions:     []int64{1},
+			args:         []string{"status"},
+			wantVersions: []int64{1},
+			wantOut:      []string{"VERSION", "2  ", "pending"},
+		},
+		{
+			name:       "unknown",
+			args:       []string{"sideways"},
+			wantStatus: cli.ExitUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &uptest.Store[up.SQLConn]{Versions: tt.versions}
+			m := &up.Migrator[up.SQLConn]{Store: store, Sources: migrations(1, 2, 3)}
+
+			status, out, errOut := execute(t, m, t.TempDir(), tt.args...)
+			if status != tt.wantStatus {
+				t.Fatalf("status: want %d, got %d (stderr: %q)", tt.wantStatus, status, errOut)
+			}
+			if tt.wantStatus == cli.ExitSuccess {
+				uptest.RequireApplied(t, store, tt.wantVersions...)
+				uptest.RequireUnlocked(t, store)
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(out, want) {
+					t.Errorf("output %q does not contain %q", out, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoot_Create(t *testing.T) {
+	dir := t.TempDir()
+	m := &up.Migrator[up.SQLConn]{Store: &uptest.Store[up.SQLConn]{}}
+
+	status, out, errOut := execute(t, m, dir, "create", "add_users")
+	if status != cli.ExitSuccess {
+		t.Fatalf("status: want %d, got %d (stderr: %q)", cli.ExitSuccess, status, errOut)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), "_add_users.sql") {
+		t.Errorf("unexpected files: %v", entries)
+	}
+	if !strings.Contains(out, entries[0].Name()) {
+		t.Errorf("output %q does not name the created file", out)
+	}
+
+	if status, _, _ := execute(t, m, dir, "create"); status != cli.ExitUsage {
+		t.Errorf("create without a name: want %d, got %d", cli.ExitUsage, status)
+	}
+}
+
+func TestRoot_ForceUnlock(t *testing.T) {
+	t.Run("supported", func(t *testing.T) {
+		store := &forceUnlockStore{&uptest.Store[up.SQLConn]{Locked: true}}
+		m := &up.Migrator[up.SQLConn]{Store: store}
+
+		if status, _, errOut := execute(t, m, t.TempDir(), "force-unlock"); status != cli.ExitSuccess {
+			t.Fatalf("status: want %d, got %d (stderr: %q)", cli.ExitSuccess, status, errOut)
+		}
+		uptest.RequireUnlocked(t, store.Store)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		store := &uptest.Store[up.SQLConn]{Locked: true}
+		m := &up.Migrator[up.SQLConn]{Store: store}
+
+		status, out, errOut := execute(t, m, t.TempDir(), "force-unlock")
+		if status != cli.ExitFailure {
+			t.Fatalf("status: want %d, got %d (stdout: %q)", cli.ExitFailure, status, out)
+		}
+		if !strings.Contains(errOut, "store does not support force unlock") {
+			t.Errorf("unexpected error output %q", errOut)
+		}
+		if !store.Locked {
+			t.Error("store should still be locked")
+		}
+	})
+}
+
+type forceUnlockStore struct {
+	*uptest.Store[up.SQLConn]
+}
+
+func (s *forceUnlockStore) ForceUnlock(ctx context.Context) error {
+	s.Locked = false
+	return nil
+}
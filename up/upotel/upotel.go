@@ -0,0 +1,145 @@
+// Package upotel reports up migrator operations to OpenTelemetry.
+//
+// A [Tracer] records a span for every Run and Revert, a child span for every
+// migration, and counters for applied and failed migrations:
+//
+//	t, err := upotel.New()
+//	if err != nil {
+//		return err
+//	}
+//	migrator.Tracer = t
+package upotel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jonathonwebb/x/up"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const scopeName = "github.com/jonathonwebb/x/up/upotel"
+
+// Attribute keys recorded on spans and metrics.
+const (
+	OperationKey = attribute.Key("up.operation")
+	TargetKey    = attribute.Key("up.target_version")
+	VersionKey   = attribute.Key("up.version")
+	NameKey      = attribute.Key("up.migration.name")
+)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+type Option func(*options)
+
+// WithTracerProvider sets the provider used to create spans. The global
+// provider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider sets the provider used to create metrics. The global
+// provider is used by default.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(o *options) {
+		o.meterProvider = mp
+	}
+}
+
+// A Tracer implements [up.Tracer] using OpenTelemetry.
+type Tracer struct {
+	tracer   trace.Tracer
+	applied  metric.Int64Counter
+	failed   metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+var _ up.Tracer = (*Tracer)(nil)
+
+func New(opts ...Option) (*Tracer, error) {
+	o := options{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	meter := o.meterProvider.Meter(scopeName)
+	applied, err := meter.Int64Counter("up.migrations.applied",
+		metric.WithDescription("Number of migrations applied or reverted successfully."),
+		metric.WithUnit("{migration}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create applied counter: %w", err)
+	}
+	failed, err := meter.Int64Counter("up.migrations.failed",
+		metric.WithDescription("Number of migrations that failed to apply or revert."),
+		metric.WithUnit("{migration}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create failed counter: %w", err)
+	}
+	duration, err := meter.Float64Histogram("up.migration.duration",
+		metric.WithDescription("Duration of each migration."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create duration histogram: %w", err)
+	}
+
+	return &Tracer{
+		tracer:   o.tracerProvider.Tracer(scopeName),
+		applied:  applied,
+		failed:   failed,
+		duration: duration,
+	}, nil
+}
+
+func (t *Tracer) StartOperation(ctx context.Context, op up.Operation, to int64) (context.Context, func(*up.Result, error)) {
+	ctx, span := t.tracer.Start(ctx, "up."+string(op), trace.WithAttributes(
+		OperationKey.String(string(op)),
+		TargetKey.Int64(to),
+	))
+	return ctx, func(res *up.Result, err error) {
+		if res != nil {
+			span.SetAttributes(VersionKey.Int64(res.Version))
+		}
+		end(span, err)
+	}
+}
+
+func (t *Tracer) StartMigration(ctx context.Context, op up.Operation, version int64, name string) (context.Context, func(error)) {
+	attrs := []attribute.KeyValue{
+		OperationKey.String(string(op)),
+		VersionKey.Int64(version),
+		NameKey.String(name),
+	}
+	ctx, span := t.tracer.Start(ctx, "up."+string(op)+".migration", trace.WithAttributes(attrs...))
+	start := time.Now()
+	return ctx, func(err error) {
+		set := metric.WithAttributes(attrs[0])
+		t.duration.Record(ctx, time.Since(start).Seconds(), set)
+		if err != nil {
+			t.failed.Add(ctx, 1, set)
+		} else {
+			t.applied.Add(ctx, 1, set)
+		}
+		end(span, err)
+	}
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
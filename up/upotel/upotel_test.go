@@ -0,0 +1,73 @@
+package upotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jonathonwebb/x/up"
+	"github.com/jonathonwebb/x/up/upotel"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	tracer, err := upotel.New(
+		upotel.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		upotel.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	ctx, endOp := tracer.StartOperation(context.Background(), up.OperationRun, up.RunTargetLatest)
+	_, endOk := tracer.StartMigration(ctx, up.OperationRun, 1, "first")
+	endOk(nil)
+	_, endErr := tracer.StartMigration(ctx, up.OperationRun, 2, "second")
+	endErr(errors.New("boom"))
+	endOp(&up.Result{Version: 1}, errors.New("boom"))
+
+	ended := spans.Ended()
+	if len(ended) != 3 {
+		t.Fatalf("want 3 spans, got %d", len(ended))
+	}
+	wantNames := []string{"up.run.migration", "up.run.migration", "up.run"}
+	wantCodes := []codes.Code{codes.Unset, codes.Error, codes.Error}
+	for i, span := range ended {
+		if span.Name() != wantNames[i] {
+			t.Errorf("span %d name = %q, want %q", i, span.Name(), wantNames[i])
+		}
+		if span.Status().Code != wantCodes[i] {
+			t.Errorf("span %d status = %v, want %v", i, span.Status().Code, wantCodes[i])
+		}
+	}
+	if parent := ended[0].Parent().SpanID(); parent != ended[2].SpanContext().SpanID() {
+		t.Errorf("migration span parent = %v, want operation span", parent)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error: %v", err)
+	}
+	counts := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok {
+				for _, dp := range sum.DataPoints {
+					counts[m.Name] += dp.Value
+				}
+			}
+		}
+	}
+	if counts["up.migrations.applied"] != 1 {
+		t.Errorf("applied = %d, want 1", counts["up.migrations.applied"])
+	}
+	if counts["up.migrations.failed"] != 1 {
+		t.Errorf("failed = %d, want 1", counts["up.migrations.failed"])
+	}
+}
@@ -0,0 +1,218 @@
+// Package uptest provides an in-memory up.Store and assertions for testing
+// code that uses the up package without a real database.
+//
+// The zero [Store] is ready to use. Each method counts its calls and can be
+// replaced by setting the matching Func field, for example to inject failures:
+//
+//	store := &uptest.Store[up.SQLConn]{}
+//	store.InsertFunc = func(ctx context.Context, v int64, s *uptest.Store[up.SQLConn]) error {
+//		if v == 3 {
+//			return errors.New("insert failed")
+//		}
+//		return uptest.DefaultInsert(ctx, v, s)
+//	}
+package uptest
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/jonathonwebb/x/up"
+)
+
+// Store is an in-memory [up.Store] that records the versions it is asked to
+// insert and remove.
+type Store[T any] struct {
+	Handle T // returned by Conn
+
+	Versions  []int64 // versions currently in the store
+	Applied   []int64 // every version inserted, in order
+	Reverted  []int64 // every version removed, in order
+	Locked    bool
+	Checksums map[int64]string
+
+	InitCalls    int
+	LockCalls    int
+	ReleaseCalls int
+	VersionCalls int
+	InsertCalls  int
+	RemoveCalls  int
+
+	InitFunc    func(context.Context, *Store[T]) error
+	LockFunc    func(context.Context, *Store[T]) error
+	ReleaseFunc func(context.Context, *Store[T]) error
+	VersionFunc func(context.Context, *Store[T]) (int64, error)
+	InsertFunc  func(context.Context, int64, *Store[T]) error
+	RemoveFunc  func(context.Context, int64, *Store[T]) error
+
+	mu sync.Mutex
+}
+
+var (
+	_ up.Store[up.SQLConn] = (*Store[up.SQLConn])(nil)
+	_ up.ChecksumStore     = (*Store[up.SQLConn])(nil)
+)
+
+// DefaultInit is the default Init behavior. It does nothing.
+func DefaultInit[T any](_ context.Context, _ *Store[T]) error {
+	return nil
+}
+
+// DefaultLock is the default Lock behavior. It returns [up.ErrLocked] if the
+// store is already locked.
+func DefaultLock[T any](_ context.Context, s *Store[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Locked {
+		return up.ErrLocked
+	}
+	s.Locked = true
+	return nil
+}
+
+// DefaultRelease is the default Release behavior. It unlocks the store.
+func DefaultRelease[T any](_ context.Context, s *Store[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Locked = false
+	return nil
+}
+
+// DefaultVersion is the default Version behavior. It returns the highest
+// version in Versions, or [up.ErrInitialVersion] if there is none.
+func DefaultVersion[T any](_ context.Context, s *Store[T]) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.Versions) == 0 {
+		return 0, up.ErrInitialVersion
+	}
+	return slices.Max(s.Versions), nil
+}
+
+// DefaultInsert is the default Insert behavior. It appends v to Versions and
+// Applied.
+func DefaultInsert[T any](_ context.Context, v int64, s *Store[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Versions = append(s.Versions, v)
+	s.Applied = append(s.Applied, v)
+	return nil
+}
+
+// DefaultRemove is the default Remove behavior. It deletes v from Versions and
+// appends it to Reverted, or returns [up.ErrVersionNotFound].
+func DefaultRemove[T any](_ context.Context, v int64, s *Store[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.Index(s.Versions, v)
+	if i < 0 {
+		return up.ErrVersionNotFound
+	}
+	s.Versions = slices.Delete(s.Versions, i, i+1)
+	s.Reverted = append(s.Reverted, v)
+	return nil
+}
+
+func (s *Store[T]) count(calls *int) {
+	s.mu.Lock()
+	*calls += 1
+	s.mu.Unlock()
+}
+
+func (s *Store[T]) Conn() T { return s.Handle }
+
+func (s *Store[T]) Init(ctx context.Context) error {
+	s.count(&s.InitCalls)
+	if s.InitFunc != nil {
+		return s.InitFunc(ctx, s)
+	}
+	return DefaultInit(ctx, s)
+}
+
+func (s *Store[T]) Lock(ctx context.Context) error {
+	s.count(&s.LockCalls)
+	if s.LockFunc != nil {
+		return s.LockFunc(ctx, s)
+	}
+	return DefaultLock(ctx, s)
+}
+
+func (s *Store[T]) Release(ctx context.Context) error {
+	s.count(&s.ReleaseCalls)
+	if s.ReleaseFunc != nil {
+		return s.ReleaseFunc(ctx, s)
+	}
+	return DefaultRelease(ctx, s)
+}
+
+func (s *Store[T]) Version(ctx context.Context) (int64, error) {
+	s.count(&s.VersionCalls)
+	if s.VersionFunc != nil {
+		return s.VersionFunc(ctx, s)
+	}
+	return DefaultVersion(ctx, s)
+}
+
+func (s *Store[T]) History(ctx context.Context) ([]up.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := make([]up.Record, len(s.Versions))
+	for i, v := range s.Versions {
+		records[i] = up.Record{Version: v, Checksum: s.Checksums[v]}
+	}
+	slices.SortFunc(records, func(a, b up.Record) int { return cmp.Compare(a.Version, b.Version) })
+	return records, nil
+}
+
+func (s *Store[T]) SetChecksum(ctx context.Context, v int64, checksum string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Checksums == nil {
+		s.Checksums = map[int64]string{}
+	}
+	s.Checksums[v] = checksum
+	return nil
+}
+
+func (s *Store[T]) Insert(ctx context.Context, v int64) error {
+	s.count(&s.InsertCalls)
+	if s.InsertFunc != nil {
+		return s.InsertFunc(ctx, v, s)
+	}
+	return DefaultInsert(ctx, v, s)
+}
+
+func (s *Store[T]) Remove(ctx context.Context, v int64) error {
+	s.count(&s.RemoveCalls)
+	if s.RemoveFunc != nil {
+		return s.RemoveFunc(ctx, v, s)
+	}
+	return DefaultRemove(ctx, v, s)
+}
+
+// RequireApplied fails the test immediately unless the store holds exactly
+// the given versions, in ascending order.
+func RequireApplied[T any](t testing.TB, s *Store[T], versions ...int64) {
+	t.Helper()
+	history, _ := s.History(context.Background())
+	got := make([]int64, len(history))
+	for i, record := range history {
+		got[i] = record.Version
+	}
+	if !slices.Equal(versions, got) {
+		t.Fatalf("applied versions mismatch\nwant: %v\ngot:  %v", versions, got)
+	}
+}
+
+// RequireUnlocked fails the test immediately if the store is locked.
+func RequireUnlocked[T any](t testing.TB, s *Store[T]) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Locked {
+		t.Fatal("store is locked, want unlocked")
+	}
+}
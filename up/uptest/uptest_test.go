@@ -0,0 +1,63 @@
+package uptest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jonathonwebb/x/up"
+	"github.com/jonathonwebb/x/up/uptest"
+)
+
+func TestStore(t *testing.T) {
+	store := &uptest.Store[up.SQLConn]{}
+	store.InsertFunc = func(ctx context.Context, v int64, s *uptest.Store[up.SQLConn]) error {
+		if v == 3 {
+			return errors.New("insert error")
+		}
+		return uptest.DefaultInsert(ctx, v, s)
+	}
+	migrator := &up.Migrator[up.SQLConn]{
+		Store: store,
+		Sources: []*up.Migration[up.SQLConn]{
+			{Version: 1, RunFunc: noop, RevertFunc: noop},
+			{Version: 2, RunFunc: noop, RevertFunc: noop},
+			{Version: 3, RunFunc: noop, RevertFunc: noop},
+		},
+	}
+
+	if _, err := migrator.Run(context.Background(), up.RunTargetLatest); err == nil {
+		t.Fatal("want insert error, got nil")
+	}
+	uptest.RequireApplied(t, store, 1, 2)
+	uptest.RequireUnlocked(t, store)
+	if store.InsertCalls != 3 {
+		t.Errorf("InsertCalls = %d, want 3", store.InsertCalls)
+	}
+
+	if _, err := migrator.Revert(context.Background(), up.RevertTargetInitial); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	uptest.RequireApplied(t, store)
+	if err := store.Remove(context.Background(), 1); !errors.Is(err, up.ErrVersionNotFound) {
+		t.Errorf("Remove(1) = %v, want %v", err, up.ErrVersionNotFound)
+	}
+}
+
+func TestDefaultVersion(t *testing.T) {
+	store := &uptest.Store[up.SQLConn]{}
+	if _, err := uptest.DefaultVersion(context.Background(), store); !errors.Is(err, up.ErrInitialVersion) {
+		t.Errorf("DefaultVersion() = %v, want %v", err, up.ErrInitialVersion)
+	}
+
+	store.Versions = []int64{1, 3, 2}
+	v, err := uptest.DefaultVersion(context.Background(), store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != 3 {
+		t.Errorf("DefaultVersion() = %d, want 3", v)
+	}
+}
+
+func noop(context.Context, up.SQLConn) error { return nil }
@@ -0,0 +1,76 @@
+package up
+
+import (
+	"context"
+	"fmt"
+)
+
+// A VerifyReport describes drift between a Migrator's sources and its version
+// store.
+type VerifyReport struct {
+	Version  int64   // current store version
+	Missing  []int64 // applied versions with no source migration
+	Pending  []int64 // unapplied source versions below the current version whose tags are selected
+	Modified []int64 // applied versions whose source checksum differs from the recorded checksum
+}
+
+// Drifted reports whether the report found any drift.
+func (r *VerifyReport) Drifted() bool {
+	return len(r.Missing) > 0 || len(r.Pending) > 0 || len(r.Modified) > 0
+}
+
+// Verify cross-checks the sources against the version store without applying
+// or reverting anything. Versions replaced by a snapshot migration are not
+// reported as missing or pending. Checksums are only compared for migrations
+// with a non-empty Checksum whose store record also has one.
+func (m *Migrator[T]) Verify(ctx context.Context) (*VerifyReport, error) {
+	if err := m.check(); err != nil {
+		return nil, fmt.Errorf("invalid sources: %w", err)
+	}
+
+	if err := m.Store.Init(ctx); err != nil {
+		return nil, fmt.Errorf("failed to init version store: %w", err)
+	}
+
+	history, err := m.Store.History(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version store history: %w", err)
+	}
+
+	sources := make(map[int64]*Migration[T], len(m.Sources))
+	var snapshot int64
+	for _, migration := range m.Sources {
+		sources[migration.Version] = migration
+		if migration.Snapshot {
+			snapshot = migration.Version
+		}
+	}
+
+	report := &VerifyReport{}
+	applied := make(map[int64]bool, len(history))
+	for _, record := range history {
+		applied[record.Version] = true
+		report.Version = max(report.Version, record.Version)
+
+		migration, ok := sources[record.Version]
+		if !ok {
+			if record.Version < snapshot {
+				continue // replaced by the snapshot
+			}
+			report.Missing = append(report.Missing, record.Version)
+			continue
+		}
+		if migration.Checksum != "" && record.Checksum != "" && migration.Checksum != record.Checksum {
+			report.Modified = append(report.Modified, record.Version)
+		}
+	}
+
+	for _, migration := range m.Sources {
+		if migration.Version < report.Version && migration.Version >= snapshot && !applied[migration.Version] && m.active(migration) {
+			report.Pending = append(report.Pending, migration.Version)
+		}
+	}
+
+	m.debug("verified %d sources against %d applied versions", len(m.Sources), len(history))
+	return report, nil
+}